@@ -15,6 +15,7 @@ import (
 
 	"github.com/patrykferenc/eecoin/cmd/node/nodetest"
 	"github.com/patrykferenc/eecoin/internal/blockchain/inmem/persistence"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	transactionhttp "github.com/patrykferenc/eecoin/internal/transaction/net/http"
 	"github.com/stretchr/testify/assert"
@@ -82,7 +83,7 @@ func TestShouldAcceptAndBroadcastMessage(t *testing.T) {
 
 	// that is the genesis address
 	senderAddr := hex.EncodeToString(publicMarshaled)
-	require.Equal(transaction.GENESIS_ADDRESS, senderAddr)
+	require.Equal(chaincfg.Active().GenesisAddress, senderAddr)
 
 	// and given receiver
 	privateReceiver, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader) // only used to generate a public key
@@ -6,23 +6,33 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"time"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/bolt"
+	blockchaincommand "github.com/patrykferenc/eecoin/internal/blockchain/command"
 	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem/persistence"
 	"github.com/patrykferenc/eecoin/internal/common/config"
 	"github.com/patrykferenc/eecoin/internal/common/event"
 	"github.com/patrykferenc/eecoin/internal/peer"
 	"github.com/patrykferenc/eecoin/internal/transaction"
+	transactionapplication "github.com/patrykferenc/eecoin/internal/transaction/application"
+	transactiondomain "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	transactionpersistence "github.com/patrykferenc/eecoin/internal/transaction/inmem/persistence"
 )
 
 type Container struct {
 	peerComponent        *peer.Component
 	blockChainComponent  *blockchain.Component
 	transactionComponent *transaction.Component
+	// miningPool is nil unless the pool is enabled
+	miningPool *application.Pool
+	mempool    *transactioninmem.PoolRepository
 
-	broker             *event.ChannelBroker
-	interruptionChanel chan bool
+	broker *event.ChannelBroker
 }
 
 func NewContainer(cfg *config.Config) (*Container, error) {
@@ -38,34 +48,175 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		return nil, err
 	}
 
-	var seenRepo *inmem.BlockChain
-	seenRepo, err = inmem.LoadPersistedBlockchain(cfg.Persistence.ChainFilePath)
-	if err != nil {
-		slog.Error("couldn't load persistent blockchain, creating new runtime chain", "error", err.Error())
-		seenRepo, err = inmem.NewBlockChain(broker)
+	var seenRepo blockchaincommand.BlockChainRepository
+	var unspentRepo transactiondomain.UnspentOutputRepository
+	var unspentUpdater transactionapplication.UnspentOutputUpdater
+	if cfg.Persistence.Bolt() {
+		store, err := openStore(cfg.Persistence)
 		if err != nil {
 			return nil, err
 		}
+		seenRepo, unspentRepo, unspentUpdater = store, store, store
+	} else {
+		chainRepo, err := inmem.LoadPersistedBlockchain(cfg.Persistence.ChainFilePath, cfg.Persistence.ChainFileBackups)
+		if errors.Is(err, persistence.LegacyChain) {
+			return nil, err
+		} else if errors.Is(err, os.ErrNotExist) {
+			slog.Info("no persisted blockchain, starting from genesis", "path", cfg.Persistence.ChainFilePath)
+		} else if err != nil {
+			slog.Error("couldn't load any persisted blockchain snapshot, starting from genesis", "error", err.Error())
+		}
+		if err != nil {
+			chainRepo, err = inmem.NewBlockChain(broker)
+			if err != nil {
+				return nil, err
+			}
+		}
+		unspent := transactioninmem.NewUnspentOutputRepository()
+		seenRepo, unspentRepo, unspentUpdater = chainRepo, unspent, transactionapplication.NewUnspentOutputEngine(unspent, chainRepo)
 	}
 
-	poolRepo := transactioninmem.NewPoolRepository()
-	tranasactionComponent := transaction.NewComponent(broker, poolRepo, peerComponent.Queries.GetPeers, seenRepo)
-	blockChainComponent := blockchain.NewComponent(cfg.Persistence.SelfKey, seenRepo, peerComponent.Queries.GetPeers, broker, poolRepo)
+	poolRepo, pending, err := newPoolRepository(cfg.Mempool)
+	if err != nil {
+		return nil, err
+	}
+	tranasactionComponent := transaction.NewComponent(broker, poolRepo, unspentRepo, unspentUpdater, poolConfig(cfg.Mempool), peerComponent.Queries.GetPeers)
+	interruptionChanel := make(chan bool)
+	blockChainComponent := blockchain.NewComponent(
+		cfg.Persistence.SelfKey,
+		seenRepo,
+		peerComponent.Queries.GetPeers,
+		peerComponent.Queries.GetHealthyPeers,
+		broker,
+		poolRepo,
+		unspentRepo,
+		cfg.Sync.HeadersFirst(),
+		cfg.Mining.Workers,
+		interruptionChanel,
+	)
 
 	if err := tranasactionComponent.Application.TransactionUpdater.UpdateFromBlockchain(); err != nil {
 		return nil, err
 	}
+	if err := restorePool(poolRepo, tranasactionComponent.Application.Pool, pending); err != nil {
+		return nil, err
+	}
+
+	var miningPool *application.Pool
+	if cfg.Pool.Enabled {
+		miningPool, err = application.NewPool(
+			blockChainComponent.Application.PoolTemplates,
+			seenRepo,
+			cfg.Persistence.SelfKey,
+			application.PoolConfig{ShareDifficulty: cfg.Pool.ShareDifficulty, Window: cfg.Pool.Window},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &Container{
 		peerComponent:        &peerComponent,
 		blockChainComponent:  &blockChainComponent,
 		transactionComponent: &tranasactionComponent,
+		mempool:              poolRepo,
+		miningPool:           miningPool,
 
-		broker:             broker,
-		interruptionChanel: make(chan bool),
+		broker: broker,
 	}, nil
 }
 
+// newPoolRepository opens the journal of the pool, unless it is disabled. The transactions replayed from it are
+// returned, to be restored once the unspent outputs are up to date.
+func newPoolRepository(cfg config.Mempool) (*transactioninmem.PoolRepository, []transactionpersistence.Entry, error) {
+	if cfg.JournalPath == "" {
+		return transactioninmem.NewPoolRepository(), nil, nil
+	}
+	if err := ensureBaseDir(cfg.JournalPath); err != nil {
+		return nil, nil, err
+	}
+	journal, pending, err := transactionpersistence.OpenJournal(cfg.JournalPath, cfg.MaxAge)
+	if err != nil {
+		return nil, nil, err
+	}
+	return transactioninmem.NewJournaledPoolRepository(journal), pending, nil
+}
+
+func poolConfig(cfg config.Mempool) transactiondomain.PoolConfig {
+	return transactiondomain.PoolConfig{
+		MaxSize:      cfg.MaxSize,
+		ReplaceByFee: cfg.ReplaceByFee,
+	}
+}
+
+// restorePool puts the transactions replayed from the journal back into the pool, as long as the policy of the pool
+// still admits them. The other ones are removed from the journal.
+func restorePool(repository *transactioninmem.PoolRepository, pool *transactiondomain.Pool, pending []transactionpersistence.Entry) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	added := make(map[transactiondomain.ID]time.Time, len(pending))
+	txs := make([]transactiondomain.Transaction, len(pending))
+	for i, entry := range pending {
+		txs[i] = entry.Transaction
+		added[entry.Transaction.ID()] = entry.Added
+	}
+	kept, rejected, err := pool.Revalidate(txs)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range kept {
+		if err := repository.Restore(tx, added[tx.ID()]); err != nil {
+			return err
+		}
+	}
+	ids := make([]transactiondomain.ID, len(rejected))
+	for i, tx := range rejected {
+		ids[i] = tx.ID()
+	}
+	if err := repository.Discard(ids...); err != nil {
+		return err
+	}
+
+	slog.Info("transaction pool restored from the journal", "restored", len(kept), "rejected", len(rejected))
+	return nil
+}
+
+// openStore opens the chain database. A new database takes over the chain from the chain file, if there is one.
+func openStore(cfg config.Persistence) (*bolt.Store, error) {
+	if err := ensureBaseDir(cfg.DatabasePath); err != nil {
+		return nil, err
+	}
+	store, err := bolt.Open(cfg.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := store.Height()
+	if err != nil || height > 0 {
+		return store, err
+	}
+	chain, err := persistence.LoadNewest(cfg.ChainFilePath, cfg.ChainFileBackups)
+	if errors.Is(err, persistence.LegacyChain) {
+		return nil, err
+	}
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("couldn't load chain file to take over into the database", "error", err)
+		}
+		return store, nil
+	}
+	for _, block := range chain.Blocks[1:] {
+		if err := store.PutBlock(block); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("chain file taken over into the database", "height", len(chain.Blocks)-1)
+	return store, nil
+}
+
 func getPeersFile(peersFilePath string) (io.ReadCloser, error) {
 	file, err := os.Open(peersFilePath)
 	if err != nil {
@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/x509"
 	"encoding/hex"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -11,18 +12,22 @@ import (
 	"github.com/patrykferenc/eecoin/internal/blockchain/inmem/persistence"
 	"github.com/patrykferenc/eecoin/internal/wallet/domain/wallet"
 
-	bc "github.com/patrykferenc/eecoin/internal/blockchain"
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	blockchaincommand "github.com/patrykferenc/eecoin/internal/blockchain/command"
 	blockchainHttp "github.com/patrykferenc/eecoin/internal/blockchain/net/http"
+	blockchainpool "github.com/patrykferenc/eecoin/internal/blockchain/net/pool"
+	blockchainquery "github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/patrykferenc/eecoin/internal/common/config"
 	"github.com/patrykferenc/eecoin/internal/common/event"
 	peercntr "github.com/patrykferenc/eecoin/internal/peer"
 	peercommand "github.com/patrykferenc/eecoin/internal/peer/command"
 	peerhttp "github.com/patrykferenc/eecoin/internal/peer/net/http"
+	transactioncommand "github.com/patrykferenc/eecoin/internal/transaction/command"
+	transactiondomain "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	transactionhttp "github.com/patrykferenc/eecoin/internal/transaction/net/http"
 )
 
@@ -54,6 +59,18 @@ func main() {
 		return
 	}
 
+	params, err := cfg.Chain.Params()
+	if err != nil {
+		slog.Error("Failed to select network", "error", err)
+		return
+	}
+	if _, err := blockchain.PowAlgorithmByName(params.PowAlgorithm); err != nil {
+		slog.Error("Failed to select proof-of-work algorithm", "error", err)
+		return
+	}
+	chaincfg.Use(params)
+	slog.Info("Following network", "network", params.Name, "pow", params.PowAlgorithm)
+
 	container, err := NewContainer(cfg)
 	if err != nil {
 		slog.Error("Failed to create container", "error", err)
@@ -62,9 +79,20 @@ func main() {
 	slog.Info("Context constructed")
 
 	go scheduleSave(cfg, container.peerComponent)
-	go schedulePersistChain(cfg, container.blockChainComponent.Queries.GetChain.Get())
+	if !cfg.Persistence.Bolt() {
+		go schedulePersistChain(cfg, container.blockChainComponent.Queries.GetChain)
+	}
 	go schedulePing(cfg, container.peerComponent)
-	go scheduleMining(container.blockChainComponent, container.interruptionChanel)
+	go scheduleExpirePool(cfg, container)
+	if cfg.Mining.Enabled && !params.MineOnDemand {
+		if err := container.blockChainComponent.Application.Mining.Start(); err != nil {
+			slog.Error("Failed to start mining", "error", err)
+		}
+	}
+
+	if container.miningPool != nil {
+		go servePoolTCP(cfg, container)
+	}
 
 	go pubSub(container)
 
@@ -99,16 +127,46 @@ func listenAndServe(container *Container) error {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	peerhttp.Route(r, container.peerComponent.Commands.AcceptPing)
-	blockchainHttp.Route(r, container.blockChainComponent.Commands.AddBlock, container.blockChainComponent.Queries.GetChain)
+	blockchainHttp.Route(
+		r,
+		container.blockChainComponent.Commands.AddBlock,
+		container.blockChainComponent.Queries.GetChain,
+		container.blockChainComponent.Queries.GetHeaders,
+		container.blockChainComponent.Queries.GetBlock,
+		container.blockChainComponent.Queries.GetSupply,
+	)
+	blockchainHttp.RouteProofs(
+		r,
+		container.blockChainComponent.Queries.GetTransactionProof,
+		container.blockChainComponent.Queries.GetUnspentProofs,
+	)
 	transactionhttp.Route(
 		r,
 		container.transactionComponent.Commands.AddTransactionHandler,
 		container.transactionComponent.Queries.GetUnspentOutputs,
 		container.transactionComponent.Queries.GetTransactionPool,
 	)
+	blockchainHttp.RouteMining(r, container.blockChainComponent.Application.Mining)
+	blockchainHttp.RouteMiningTemplates(r, container.blockChainComponent.Application.Templates)
+	if container.miningPool != nil {
+		blockchainpool.Route(r, container.miningPool)
+	}
+	if chaincfg.Active().MineOnDemand {
+		blockchainHttp.RouteGenerate(r, container.blockChainComponent.Commands.MineBlock)
+	}
+
+	addr := fmt.Sprintf(":%d", chaincfg.Active().Port)
+	slog.Info("Listening on " + addr)
+	return http.ListenAndServe(addr, r)
+}
 
-	slog.Info("Listening on :22137")
-	return http.ListenAndServe(":22137", r)
+func servePoolTCP(cfg *config.Config, container *Container) {
+	if cfg.Pool.TCPAddress == "" {
+		return
+	}
+	if err := blockchainpool.NewServer(container.miningPool).ListenAndServe(cfg.Pool.TCPAddress); err != nil {
+		slog.Error("Failed to serve the pool over TCP", "error", err)
+	}
 }
 
 func schedulePing(cfg *config.Config, peerComponent *peercntr.Component) {
@@ -121,15 +179,6 @@ func schedulePing(cfg *config.Config, peerComponent *peercntr.Component) {
 	}
 }
 
-func scheduleMining(blockchainComponent *bc.Component, interrupt chan bool) {
-	h := blockchainComponent.Commands.MineBlock
-	slog.Info("Mining started")
-	for {
-		h.Handle(blockchaincommand.MineBlock{InterruptChannel: interrupt})
-		slog.Info("Mining loop exited")
-	}
-}
-
 func scheduleSave(cfg *config.Config, peerComponent *peercntr.Component) {
 	if cfg.Peers.UpdateFileDuration == 0 {
 		return
@@ -147,7 +196,8 @@ func scheduleSave(cfg *config.Config, peerComponent *peercntr.Component) {
 	}
 }
 
-func schedulePersistChain(cfg *config.Config, chain blockchain.BlockChain) {
+// schedulePersistChain writes the chain as it is at every tick.
+func schedulePersistChain(cfg *config.Config, getChain blockchainquery.GetChain) {
 	if cfg.Persistence.UpdateFileDuration == 0 {
 		return
 	}
@@ -156,13 +206,37 @@ func schedulePersistChain(cfg *config.Config, chain blockchain.BlockChain) {
 
 	defer ticker.Stop()
 	for range ticker.C {
-		err := persistence.Persist(chain, cfg.Persistence.ChainFilePath)
+		chain, err := getChain.Get()
+		if err != nil {
+			slog.Error("Failed to read blockchain", "error", err)
+			continue
+		}
+		err = persistence.Persist(chain, cfg.Persistence.ChainFilePath, cfg.Persistence.ChainFileBackups)
 		if err != nil {
 			slog.Error("Failed to persist blockchain", "error", err)
 		}
 	}
 }
 
+// scheduleExpirePool drops the transactions which stayed in the pool for longer than the configured age.
+func scheduleExpirePool(cfg *config.Config, container *Container) {
+	if cfg.Mempool.MaxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Minute)
+
+	defer ticker.Stop()
+	for range ticker.C {
+		expired, err := container.mempool.Expire(time.Now().Add(-cfg.Mempool.MaxAge))
+		if err != nil {
+			slog.Error("Failed to expire transactions from the pool", "error", err)
+		} else if len(expired) > 0 {
+			slog.Info("Expired transactions from the pool", "count", len(expired))
+		}
+	}
+}
+
 func pubSub(cntr *Container) {
 	handlers := map[string]func(event.Event) error{
 		"x.block.added": func(e event.Event) error {
@@ -171,10 +245,9 @@ func pubSub(cntr *Container) {
 				slog.Error("Invalid event data")
 				return nil
 			}
-			cmd := blockchaincommand.AddBlock{ToAdd: data.Block}
-			err := cntr.blockChainComponent.Commands.AddBlock.Handle(cmd)
+			err := cntr.transactionComponent.Application.TransactionUpdater.ConnectBlock(data.Block)
 			if err != nil {
-				slog.Error("Failed to handle AddBlock command", "error", err)
+				slog.Error("Failed to update transactions after new block", "error", err)
 			}
 
 			err = cntr.blockChainComponent.Commands.Broadcast.Handle(blockchaincommand.BroadcastBlock{Block: data.Block})
@@ -182,7 +255,34 @@ func pubSub(cntr *Container) {
 				slog.Error("Failed to broadcast block", "error", err)
 			}
 
-			cntr.interruptionChanel <- true
+			cntr.blockChainComponent.Application.Mining.Interrupt()
+			return nil
+		},
+		"x.transaction.added": func(e event.Event) error {
+			data, ok := e.Data().(transactiondomain.Added)
+			if !ok {
+				slog.Error("Invalid event data")
+				return nil
+			}
+			// relay the transaction, so that a replacement reaches the pools holding what it replaces as well
+			err := cntr.transactionComponent.Commands.BroadcastTransactionHandler.Handle(transactioncommand.BroadcastTransaction{TransactionID: data.ID.String()})
+			if err != nil {
+				slog.Warn("Failed to relay transaction", "id", data.ID, "error", err)
+			}
+			return nil
+		},
+		"x.chain.reorganized": func(e event.Event) error {
+			data, ok := e.Data().(blockchain.ChainReorganizedEvent)
+			if !ok {
+				slog.Error("Invalid event data")
+				return nil
+			}
+			err := cntr.transactionComponent.Application.TransactionUpdater.Reorganize(data.Disconnected, data.Connected)
+			if err != nil {
+				slog.Error("Failed to update transactions after reorganization", "error", err)
+			}
+
+			cntr.blockChainComponent.Application.Mining.Interrupt()
 			return nil
 		},
 	}
@@ -191,11 +291,24 @@ func pubSub(cntr *Container) {
 }
 
 func sync(cntr *Container) {
-	// time.Sleep(5 * time.Second) // TODO#30
-	err := cntr.transactionComponent.Application.TransactionUpdater.UpdateFromRemote()
+	// find out which peers are reachable before asking them for their chains
+	cntr.peerComponent.Commands.SendPing.Handle(peercommand.SendPingCommand{})
+
+	err := cntr.blockChainComponent.Commands.SyncChain.Handle(blockchaincommand.SyncChain{})
 	if err != nil {
-		slog.Error("Failed to sync", "error", err)
-	} else {
-		slog.Info("Synced")
+		slog.Error("Failed to sync chain", "error", err)
+	}
+
+	updater := cntr.transactionComponent.Application.TransactionUpdater
+	if err := updater.UpdateFromBlockchain(); err != nil {
+		slog.Error("Failed to rebuild unspent outputs", "error", err)
+		return
 	}
+	if err := updater.UpdatePoolFromRemote(); err != nil {
+		slog.Error("Failed to sync transaction pool", "error", err)
+		return
+	}
+
+	cntr.blockChainComponent.Application.Mining.Interrupt()
+	slog.Info("Synced")
 }
@@ -3,14 +3,17 @@ package main
 import (
 	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
 
+	blockchainhttp "github.com/patrykferenc/eecoin/internal/blockchain/net/http"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
 	"github.com/patrykferenc/eecoin/internal/transaction/net/http"
-	"github.com/patrykferenc/eecoin/internal/transaction/query"
+	"github.com/patrykferenc/eecoin/internal/wallet/application"
 	"github.com/patrykferenc/eecoin/internal/wallet/domain/wallet"
 	"github.com/urfave/cli/v2"
 )
@@ -121,7 +124,19 @@ func setupCliCommands() []*cli.Command {
 		},
 		{
 			Name:  "balance",
-			Usage: "list balance",
+			Usage: "list balance, verified against the headers of the nodes",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "node",
+					Usage: "node to download the headers and the proofs from, can be given several times",
+					Value: cli.NewStringSlice(remote),
+				},
+				&cli.IntFlag{
+					Name:  "confirmations",
+					Usage: "blocks needed on top of an output, counting its own, before it counts into the balance",
+					Value: application.DefaultConfirmations,
+				},
+			},
 			Action: func(c *cli.Context) error {
 				if c.Args().Len() != 2 {
 					slog.Error("Two arguments needed : <config file path> <passphrase>")
@@ -130,28 +145,47 @@ func setupCliCommands() []*cli.Command {
 				configPath := c.Args().Get(0)
 				passphrase := c.Args().Get(1)
 
-				unspentRepo := http.NewUnspentOutputsRepository(remote)
-				balance := query.NewGetBalance(unspentRepo)
-				wl, _ := wallet.ReadWalletFromDirectoryEcdsa(configPath, &passphrase)
+				wl, err := wallet.ReadWalletFromDirectoryEcdsa(configPath, &passphrase)
+				if err != nil {
+					slog.Error("Cannot read wallet")
+					os.Exit(1)
+				}
 				selfPub := wl.MainId.Public
 				marshalled, err := x509.MarshalPKIXPublicKey(selfPub)
 				if err != nil {
 					fmt.Printf("Cannot marshal public key: %s\n", err)
 				}
 				selfAddr := hex.EncodeToString(marshalled)
-				b, err := balance.GetBalance(query.GetBalanceRequest{Address: selfAddr})
+
+				lightClient := application.NewLightClient(blockchainhttp.NewBlockClient(), c.StringSlice("node"), c.Int("confirmations"))
+				if err := lightClient.SyncHeaders(); err != nil {
+					return fmt.Errorf("cannot sync headers: %w", err)
+				}
+				b, err := lightClient.Balance(selfAddr)
 				if err != nil {
-					fmt.Printf("Cannot get balance: %s\n", err)
+					return fmt.Errorf("cannot get balance: %w", err)
 				}
-				fmt.Printf("Balance: %d\n", b)
+				fmt.Printf("Balance: %d\n", b.Confirmed)
+				fmt.Printf("Pending: %d (fewer than %d confirmations)\n", b.Pending, c.Int("confirmations"))
 				return nil
 			},
 		},
 		{
 			Name:  "transfer",
 			Usage: "transfer coins",
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "fee",
+					Usage: "amount left to the miner of the block including the transaction",
+					Value: 0,
+				},
+				&cli.BoolFlag{
+					Name:  "replaceable",
+					Usage: "let the transaction be replaced with bump-fee while it is pending",
+				},
+			},
 			Action: func(c *cli.Context) error {
-				if c.Args().Len() != 2 {
+				if c.Args().Len() != 4 {
 					slog.Error("Four arguments needed : <index from list beginning with 0> <integer amount> <config file path> <passphrase>")
 					os.Exit(1)
 				}
@@ -192,11 +226,99 @@ func setupCliCommands() []*cli.Command {
 				selfAddr := hex.EncodeToString(marshalled)
 
 				unspentRepo := http.NewUnspentOutputsRepository(remote)
-				tr, err := transaction.New(recieverAddr, selfAddr, amount, wl.MainId.Private(), unspentRepo)
+				newTransaction := transaction.NewWithFee
+				if c.Bool("replaceable") {
+					newTransaction = transaction.NewReplaceableWithFee
+				}
+				tr, err := newTransaction(recieverAddr, selfAddr, amount, c.Int("fee"), wl.MainId.Private(), unspentRepo)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Transaction created: %x\n", tr.ID())
+
+				body, err := json.Marshal(http.AsDTO(*tr))
+				if err != nil {
+					return err
+				}
+				if err := http.SendTransaction(body, remote); err != nil {
+					return fmt.Errorf("cannot send transaction: %w", err)
+				}
+				fmt.Printf("Transaction sent with fee %d\n", c.Int("fee"))
+
+				return nil
+			},
+		},
+		{
+			Name:      "bump-fee",
+			Usage:     "replace a pending transaction sent as replaceable with one paying a higher fee, taken out of its change",
+			ArgsUsage: "<txid> <config file path> <passphrase>",
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "by",
+					Usage: "how much more the replacement leaves to the miner",
+					Value: 1,
+				},
+				&cli.StringSliceFlag{
+					Name:  "node",
+					Usage: "node to download the headers and the proofs of the spent outputs from, can be given several times",
+					Value: cli.NewStringSlice(remote),
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() != 3 {
+					slog.Error("Three arguments needed : <txid> <config file path> <passphrase>")
+					os.Exit(1)
+				}
+				id, err := hex.DecodeString(c.Args().Get(0))
+				if err != nil {
+					slog.Error("Cannot parse txid, it has to be hex")
+					os.Exit(1)
+				}
+				configPath := c.Args().Get(1)
+				passphrase := c.Args().Get(2)
+				wl, err := wallet.ReadWalletFromDirectoryEcdsa(configPath, &passphrase)
+				if err != nil {
+					slog.Error("Cannot read wallet")
+					os.Exit(1)
+				}
+
+				selfPub := wl.MainId.Public
+				marshalled, err := x509.MarshalPKIXPublicKey(selfPub)
+				if err != nil {
+					fmt.Printf("Cannot marshal public key: %s\n", err)
+				}
+				selfAddr := hex.EncodeToString(marshalled)
+
+				pool := &http.TransactionPoolClient{}
+				pending, err := pool.GetTransaction(remote, transaction.ID(id))
+				if err != nil {
+					return fmt.Errorf("cannot get transaction: %w", err)
+				}
+
+				// the replacement signs for the outputs it spends, which have to be proven to be ours
+				lightClient := application.NewLightClient(blockchainhttp.NewBlockClient(), c.StringSlice("node"), 1)
+				if err := lightClient.SyncHeaders(); err != nil {
+					return fmt.Errorf("cannot sync headers: %w", err)
+				}
+				owned, err := lightClient.Unspent(selfAddr)
 				if err != nil {
+					return fmt.Errorf("cannot get unspent outputs: %w", err)
+				}
+
+				spendable := inmem.NewUnspentOutputRepository()
+				if err := spendable.Add(owned...); err != nil {
 					return err
 				}
-				fmt.Printf("Transaction created: %s\n", tr.ID())
+				replacement, err := transaction.BumpFee(pending, c.Int("by"), selfAddr, wl.MainId.Private(), spendable)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Replacement created: %x\n", replacement.ID())
+
+				if err := http.SendCanonicalTransaction(*replacement, remote); err != nil {
+					return fmt.Errorf("cannot send replacement: %w", err)
+				}
+				fmt.Printf("Replacement sent, fee raised by %d\n", c.Int("by"))
 
 				return nil
 			},
@@ -11,6 +11,7 @@ require (
 	github.com/teris-io/shortid v0.0.0-20220617161101-71ec9f2aa569
 	github.com/testcontainers/testcontainers-go v0.34.0
 	github.com/urfave/cli/v2 v2.27.5
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/crypto v0.30.0
 )
 
@@ -0,0 +1,165 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+)
+
+var MiningOnDemand = errors.New("blocks are mined on demand on this network")
+
+// Delays before the miner starts over after failing, doubling with every failure in a row.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// MiningStatus tells whether the node mines, what it works on and what it earned so far.
+// The blocks found and the rewards count the blocks the miner added to the chain since the node started, whose coinbase
+// pays the node.
+type MiningStatus struct {
+	Running     bool
+	Height      int
+	Difficulty  int
+	Hashrate    float64
+	BlocksFound int
+	Rewards     int
+}
+
+// Mining runs the miner in the background until stopped. The miner starts over on top of the current chain
+// whenever it gets interrupted through the interruption channel.
+type Mining struct {
+	mine      command.MineBlockHandler
+	selfAddr  string
+	interrupt chan bool
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	found   int
+	rewards int
+}
+
+func NewMining(mine command.MineBlockHandler, selfAddress string, interrupt chan bool) *Mining {
+	return &Mining{
+		mine:      mine,
+		selfAddr:  selfAddress,
+		interrupt: interrupt,
+	}
+}
+
+// Start the miner, unless it is already running.
+func (m *Mining) Start() error {
+	if chaincfg.Active().MineOnDemand {
+		return MiningOnDemand
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.cancel, m.done = cancel, done
+	go m.run(ctx, done)
+
+	slog.Info("Mining started")
+	return nil
+}
+
+// Stop the miner and wait until it stops.
+func (m *Mining) Stop() {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel, m.done = nil, nil
+	m.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+	cancel()
+	<-done
+	slog.Info("Mining stopped")
+}
+
+// Interrupt makes the running miner start over on top of the current chain. It does nothing when the miner is stopped.
+func (m *Mining) Interrupt() {
+	m.mu.Lock()
+	done := m.done
+	m.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+	select {
+	case m.interrupt <- true:
+	case <-done:
+	}
+}
+
+func (m *Mining) Status() MiningStatus {
+	progress := m.mine.Progress()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return MiningStatus{
+		Running:     m.done != nil,
+		Height:      progress.Height,
+		Difficulty:  progress.Difficulty,
+		Hashrate:    progress.Hashrate,
+		BlocksFound: m.found,
+		Rewards:     m.rewards,
+	}
+}
+
+// mined counts the block towards the status, as long as its coinbase pays the node.
+func (m *Mining) mined(block blockchain.Block) {
+	if len(block.Transactions) == 0 || !block.Transactions[0].IsCoinbase() {
+		return
+	}
+	rewards := 0
+	paid := false
+	for _, output := range block.Transactions[0].Outputs() {
+		if output.Address() == m.selfAddr {
+			rewards += output.Amount()
+			paid = true
+		}
+	}
+	if !paid {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.found++
+	m.rewards += rewards
+}
+
+// run starts the miner over until stopped. A miner failing, for instance because the chain cannot be read, is started
+// over only after a delay, so that it does not keep failing at once.
+func (m *Mining) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	delay := minRetryDelay
+	for ctx.Err() == nil {
+		err := m.mine.Handle(command.MineBlock{Context: ctx, InterruptChannel: m.interrupt, Mined: m.mined})
+		if err == nil {
+			delay = minRetryDelay
+			continue
+		}
+
+		slog.Error("Mining failed, starting over later", "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
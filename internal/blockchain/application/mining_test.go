@@ -0,0 +1,99 @@
+package application_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMining_shouldStartAndStop(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	mine := command.NewMineBlockHandler("self", repo, &mock.Publisher{}, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), 2)
+	interrupt := make(chan bool)
+	mining := application.NewMining(mine, "self", interrupt)
+
+	// when stopped
+	mining.Interrupt()
+	mining.Stop()
+
+	// then
+	assert.Equal(application.MiningStatus{}, mining.Status())
+
+	// when started
+	require.NoError(t, mining.Start())
+	require.NoError(t, mining.Start())
+
+	// then
+	require.Eventually(t, func() bool { return mining.Status().BlocksFound >= 2 }, 10*time.Second, 10*time.Millisecond)
+	assert.True(mining.Status().Running)
+	mining.Interrupt()
+
+	// when stopped again
+	mining.Stop()
+
+	// then
+	status := mining.Status()
+	chain := chainOf(t, repo)
+	assert.False(status.Running)
+	assert.Zero(status.Height)
+	assert.Zero(status.Hashrate)
+	assert.Equal(len(chain.Blocks)-1, status.BlocksFound)
+	rewards := 0
+	for _, block := range chain.Blocks[1:] {
+		rewards += block.Transactions[0].Outputs()[0].Amount()
+	}
+	assert.Equal(rewards, status.Rewards)
+
+	// and the chain no longer grows
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(chainOf(t, repo).Blocks, len(chain.Blocks))
+}
+
+func chainOf(t *testing.T, repo command.BlockChainRepository) blockchain.BlockChain {
+	t.Helper()
+	chain, err := repo.GetChain()
+	require.NoError(t, err)
+	return chain
+}
+
+func TestMining_shouldWaitBeforeStartingOverAfterFailing(t *testing.T) {
+	// given a miner which fails at once
+	mine := &failingMineBlock{}
+	mining := application.NewMining(mine, "self", make(chan bool))
+
+	// when
+	require.NoError(t, mining.Start())
+	time.Sleep(200 * time.Millisecond)
+	mining.Stop()
+
+	// then it was not started over right away
+	assert.Equal(t, int32(1), mine.calls.Load())
+	assert.False(t, mining.Status().Running)
+}
+
+type failingMineBlock struct {
+	calls atomic.Int32
+}
+
+func (f *failingMineBlock) Handle(command.MineBlock) error {
+	f.calls.Add(1)
+	return errors.New("chain not readable")
+}
+
+func (f *failingMineBlock) Progress() command.MiningProgress {
+	return command.MiningProgress{}
+}
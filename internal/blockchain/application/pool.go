@@ -0,0 +1,186 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// JobRefreshInterval is how long the workers of the pool get the same job, unless the chain tip moves on sooner.
+// The new jobs pay out according to the shares accepted in the meantime.
+const JobRefreshInterval = 10 * time.Second
+
+var (
+	WorkerNotValid = errors.New("worker address must be a public key")
+	ShareNotValid  = errors.New("share does not solve the challenge at the share difficulty")
+	ShareDuplicate = errors.New("share already submitted")
+)
+
+// PoolConfig tells how much work a share takes and how many of the last shares the rewards are split over.
+type PoolConfig struct {
+	ShareDifficulty int
+	Window          int
+}
+
+// Job is a block template handed out to the workers of the pool, which submit the solutions at the share difficulty.
+type Job struct {
+	Template        BlockTemplate
+	ShareDifficulty int
+}
+
+// Submitted is the outcome of an accepted share. The block is set when the share solved it as well.
+type Submitted struct {
+	Block *blockchain.Block
+}
+
+// WorkerStats tells how many shares a worker got accepted in total and how many of them are in the window.
+type WorkerStats struct {
+	Address  string
+	Accepted int
+	InWindow int
+}
+
+// Pool lets several workers mine together behind the node. Each job pays the workers in proportion to their shares
+// in the window at the time it was built (PPLNS), so the reward of a block found goes out through its coinbase.
+type Pool struct {
+	templates  *Templates
+	repository command.BlockChainRepository
+	selfAddr   string
+	config     PoolConfig
+
+	mu       sync.Mutex
+	window   *blockchain.ShareWindow
+	accepted map[string]int
+	seen     map[string]struct{}
+	job      *Job
+	jobTip   string
+	jobAt    time.Time
+}
+
+func NewPool(templates *Templates, repository command.BlockChainRepository, selfAddress string, config PoolConfig) (*Pool, error) {
+	if _, err := blockchain.NewChallenge(config.ShareDifficulty, 0); err != nil {
+		return nil, fmt.Errorf("share difficulty: %w", err)
+	}
+	window, err := blockchain.NewShareWindow(config.Window)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{
+		templates:  templates,
+		repository: repository,
+		selfAddr:   selfAddress,
+		config:     config,
+		window:     window,
+		accepted:   make(map[string]int),
+		seen:       make(map[string]struct{}),
+	}, nil
+}
+
+// Work returns the job the workers should mine on, building a new one when the chain tip moved on or the current
+// one got old.
+func (p *Pool) Work() (Job, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	last, err := p.repository.GetTip()
+	if err != nil {
+		return Job{}, err
+	}
+	tip := last.ContentHash
+	if p.job != nil && p.jobTip == tip && time.Now().Sub(p.jobAt) < JobRefreshInterval {
+		return *p.job, nil
+	}
+
+	split := p.window.Clone()
+	tmpl, err := p.templates.NewPaying(func(reward int) []*transaction.Output {
+		if outputs := split.Split(reward); len(outputs) > 0 {
+			return outputs
+		}
+		return command.PayTo(p.selfAddr)(reward)
+	})
+	if err != nil {
+		return Job{}, err
+	}
+
+	if tip != p.jobTip {
+		p.seen = make(map[string]struct{})
+	}
+	p.job = &Job{
+		Template:        tmpl,
+		ShareDifficulty: min(p.config.ShareDifficulty, tmpl.Challenge.Difficulty),
+	}
+	p.jobTip, p.jobAt = tip, time.Now()
+	return *p.job, nil
+}
+
+// Submit the share of the worker for the job. The share is accepted when it solves the challenge at the share
+// difficulty, and when it solves the block challenge as well, the block is added to the chain. The worker is the
+// address its cut of the rewards is paid to, so it has to be a public key.
+func (p *Pool) Submit(worker string, jobID string, nonce uint32, timestampMillis int64) (Submitted, error) {
+	if err := transaction.ValidateAddress(worker); err != nil {
+		return Submitted{}, fmt.Errorf("%w: %w", WorkerNotValid, err)
+	}
+	tmpl, err := p.templates.Get(jobID)
+	if err != nil {
+		return Submitted{}, err
+	}
+	if timestampMillis < tmpl.MinTimestampMillis {
+		return Submitted{}, fmt.Errorf("%w: timestamp before %d", ShareNotValid, tmpl.MinTimestampMillis)
+	}
+
+	solved := tmpl.Challenge
+	if err := solved.Try(tmpl.Previous, tmpl.Transactions, timestampMillis, nonce); err != nil {
+		return Submitted{}, fmt.Errorf("could not hash the share: %w", err)
+	}
+	share := solved
+	share.Difficulty = min(p.config.ShareDifficulty, tmpl.Challenge.Difficulty)
+	if !share.MatchesDifficulty() {
+		return Submitted{}, ShareNotValid
+	}
+
+	p.mu.Lock()
+	key := fmt.Sprintf("%s:%d:%d", jobID, nonce, timestampMillis)
+	if _, ok := p.seen[key]; ok {
+		p.mu.Unlock()
+		return Submitted{}, ShareDuplicate
+	}
+	p.seen[key] = struct{}{}
+	p.window.Add(blockchain.Share{Worker: worker, Difficulty: share.Difficulty})
+	p.accepted[worker]++
+	p.mu.Unlock()
+
+	if !solved.MatchesDifficulty() {
+		return Submitted{}, nil
+	}
+	block, err := p.templates.Submit(jobID, nonce, timestampMillis)
+	if err != nil {
+		slog.Warn("Block found by the pool was not added", "worker", worker, "error", err)
+		return Submitted{}, nil
+	}
+	slog.Info("Block found by the pool", "worker", worker, "index", block.Index, "hash", block.ContentHash)
+	return Submitted{Block: &block}, nil
+}
+
+// Workers lists the workers which got shares accepted, ordered by their address.
+func (p *Pool) Workers() []WorkerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	inWindow := make(map[string]int)
+	for _, share := range p.window.Shares() {
+		inWindow[share.Worker]++
+	}
+	stats := make([]WorkerStats, 0, len(p.accepted))
+	for worker, accepted := range p.accepted {
+		stats = append(stats, WorkerStats{Address: worker, Accepted: accepted, InWindow: inWindow[worker]})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Address < stats[j].Address })
+	return stats
+}
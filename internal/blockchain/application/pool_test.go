@@ -0,0 +1,118 @@
+package application_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_shouldSplitTheRewardByShares(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	publisher := &mock.Publisher{}
+	repo, err := inmem.NewBlockChain(publisher)
+	require.NoError(t, err)
+	templates := application.NewTemplates(repo, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), command.NewAddBlockHandler(repo, publisher), "operator")
+	pool, err := application.NewPool(templates, repo, "operator", application.PoolConfig{ShareDifficulty: 2, Window: 10})
+	require.NoError(t, err)
+
+	// and two workers, named after the addresses they are paid to
+	alice, bob := newAddress(t), newAddress(t)
+	if bob < alice {
+		alice, bob = bob, alice
+	}
+
+	// when
+	job, err := pool.Work()
+
+	// then nobody has shares yet, so the operator gets the reward
+	require.NoError(t, err)
+	assert.Equal(2, job.ShareDifficulty)
+	assert.Equal([]transaction.Output{*transaction.NewOutput(transaction.ActiveSubsidy().At(1), "operator")}, job.Template.Transactions[0].Outputs())
+	again, err := pool.Work()
+	require.NoError(t, err)
+	assert.Equal(job.Template.ID, again.Template.ID)
+
+	// when a share below the block difficulty comes in
+	timestamp := time.Now().UnixMilli()
+	shareNonce := findNonce(t, job, timestamp, func(c blockchain.Challenge) bool {
+		return isShare(c, job.ShareDifficulty) && !c.MatchesDifficulty()
+	})
+	submitted, err := pool.Submit(alice, job.Template.ID, shareNonce, timestamp)
+
+	// then
+	assert.NoError(err)
+	assert.Nil(submitted.Block)
+	_, err = pool.Submit(alice, job.Template.ID, shareNonce, timestamp)
+	assert.ErrorIs(err, application.ShareDuplicate)
+	_, err = pool.Submit("", job.Template.ID, shareNonce, timestamp)
+	assert.ErrorIs(err, application.WorkerNotValid)
+	_, err = pool.Submit("alice", job.Template.ID, shareNonce, timestamp)
+	assert.ErrorIs(err, application.WorkerNotValid)
+	_, err = pool.Submit(alice, "unknown", shareNonce, timestamp)
+	assert.ErrorIs(err, application.TemplateNotFound)
+	weakNonce := findNonce(t, job, timestamp, func(c blockchain.Challenge) bool { return !isShare(c, job.ShareDifficulty) })
+	_, err = pool.Submit(alice, job.Template.ID, weakNonce, timestamp)
+	assert.ErrorIs(err, application.ShareNotValid)
+
+	// when a share solves the block
+	blockNonce := findNonce(t, job, timestamp, func(c blockchain.Challenge) bool { return c.MatchesDifficulty() })
+	submitted, err = pool.Submit(bob, job.Template.ID, blockNonce, timestamp)
+
+	// then
+	assert.NoError(err)
+	require.NotNil(t, submitted.Block)
+	chain := chainOf(t, repo)
+	assert.Equal(*submitted.Block, chain.GetLast())
+	assert.Equal([]application.WorkerStats{{Address: alice, Accepted: 1, InWindow: 1}, {Address: bob, Accepted: 1, InWindow: 1}}, pool.Workers())
+
+	// and the next job splits the reward between the workers
+	next, err := pool.Work()
+	require.NoError(t, err)
+	reward := transaction.ActiveSubsidy().At(2)
+	assert.Equal([]transaction.Output{
+		*transaction.NewOutput(reward/2, alice),
+		*transaction.NewOutput(reward-reward/2, bob),
+	}, next.Template.Transactions[0].Outputs())
+}
+
+func newAddress(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return hex.EncodeToString(raw)
+}
+
+// findNonce tries the nonces of the job in turn until the challenge they give is accepted.
+func findNonce(t *testing.T, job application.Job, timestamp int64, accept func(blockchain.Challenge) bool) uint32 {
+	t.Helper()
+	for nonce := uint32(0); ; nonce++ {
+		challenge := job.Template.Challenge
+		require.NoError(t, challenge.Try(job.Template.Previous, job.Template.Transactions, timestamp, nonce))
+		if accept(challenge) {
+			return nonce
+		}
+	}
+}
+
+func isShare(challenge blockchain.Challenge, difficulty int) bool {
+	challenge.Difficulty = difficulty
+	return challenge.MatchesDifficulty()
+}
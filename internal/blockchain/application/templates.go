@@ -0,0 +1,158 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// MaxTemplates caps how many block templates are kept for the external miners at once.
+const MaxTemplates = 64
+
+var TemplateNotFound = errors.New("block template not found")
+
+// BlockTemplate is everything an external miner needs to solve the challenge of the next block.
+type BlockTemplate struct {
+	ID           string
+	Previous     blockchain.Block
+	Transactions []transaction.Transaction
+	Challenge    blockchain.Challenge
+	// MinTimestampMillis is the earliest the block can come, the time cap after its parent.
+	MinTimestampMillis int64
+}
+
+// Templates hands out block templates to the external miners and turns their solutions into blocks.
+// The templates are kept until the chain tip moves on, so that the miners can submit for any of them.
+type Templates struct {
+	repository     command.BlockChainRepository
+	poolRepository transaction.PoolRepository
+	unspent        transaction.UnspentOutputRepository
+	addBlock       command.AddBlockHandler
+	selfAddr       string
+
+	mu        sync.Mutex
+	tip       string
+	templates map[string]BlockTemplate
+	order     []string
+}
+
+func NewTemplates(repository command.BlockChainRepository, poolRepository transaction.PoolRepository, unspent transaction.UnspentOutputRepository, addBlock command.AddBlockHandler, selfAddress string) *Templates {
+	return &Templates{
+		repository:     repository,
+		poolRepository: poolRepository,
+		unspent:        unspent,
+		addBlock:       addBlock,
+		selfAddr:       selfAddress,
+		templates:      make(map[string]BlockTemplate),
+	}
+}
+
+// New builds a template on top of the chain tip, with the coinbase paying to the given address, the node's own
+// address when empty.
+func (t *Templates) New(payout string) (BlockTemplate, error) {
+	if payout == "" {
+		payout = t.selfAddr
+	}
+	return t.NewPaying(command.PayTo(payout))
+}
+
+// NewPaying builds a template on top of the chain tip, with the coinbase split by the payout.
+func (t *Templates) NewPaying(payout command.Payout) (BlockTemplate, error) {
+	previous, err := t.repository.GetTip()
+	if err != nil {
+		return BlockTemplate{}, fmt.Errorf("could not read the tip of the chain: %w", err)
+	}
+	challenge, err := command.ChallengeFor(t.repository, previous)
+	if err != nil {
+		return BlockTemplate{}, fmt.Errorf("could not create the challenge: %w", err)
+	}
+	transactions, err := command.BlockTransactions(t.poolRepository.GetAll(), t.unspent, previous.Index+1, payout)
+	if err != nil {
+		return BlockTemplate{}, fmt.Errorf("could not select the transactions: %w", err)
+	}
+	id, err := newTemplateID()
+	if err != nil {
+		return BlockTemplate{}, err
+	}
+
+	tmpl := BlockTemplate{
+		ID:                 id,
+		Previous:           previous,
+		Transactions:       transactions,
+		Challenge:          challenge,
+		MinTimestampMillis: previous.TimestampMilis + challenge.TimeCapMillis,
+	}
+	t.keep(tmpl)
+	return tmpl, nil
+}
+
+// Get the template with the given id, as long as it is kept.
+func (t *Templates) Get(id string) (BlockTemplate, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tmpl, ok := t.templates[id]
+	if !ok {
+		return BlockTemplate{}, TemplateNotFound
+	}
+	return tmpl, nil
+}
+
+// Submit rebuilds the block of the template with the nonce and the timestamp found by the miner, then adds it
+// to the chain, which announces it to the peers.
+func (t *Templates) Submit(id string, nonce uint32, timestampMillis int64) (blockchain.Block, error) {
+	tmpl, err := t.Get(id)
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+
+	challenge := tmpl.Challenge
+	if err := challenge.Try(tmpl.Previous, tmpl.Transactions, timestampMillis, nonce); err != nil {
+		return blockchain.Block{}, fmt.Errorf("could not hash the block: %w", err)
+	}
+	block, err := blockchain.NewBlockAfter(tmpl.Previous.Header, timestampMillis, tmpl.Transactions, challenge)
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+	if err := t.addBlock.Handle(command.AddBlock{ToAdd: block}); err != nil {
+		return blockchain.Block{}, err
+	}
+
+	t.mu.Lock()
+	delete(t.templates, id)
+	t.mu.Unlock()
+	slog.Info("Block submitted by an external miner", "index", block.Index, "hash", block.ContentHash)
+	return block, nil
+}
+
+// keep the template, dropping the templates of the previous tip and the oldest ones over the limit.
+func (t *Templates) keep(tmpl BlockTemplate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tmpl.Previous.ContentHash != t.tip {
+		t.tip = tmpl.Previous.ContentHash
+		t.templates = make(map[string]BlockTemplate)
+		t.order = nil
+	}
+	for len(t.order) >= MaxTemplates {
+		delete(t.templates, t.order[0])
+		t.order = t.order[1:]
+	}
+	t.templates[tmpl.ID] = tmpl
+	t.order = append(t.order, tmpl.ID)
+}
+
+func newTemplateID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not create the template id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
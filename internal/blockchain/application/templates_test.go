@@ -0,0 +1,92 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates_shouldAddTheSubmittedBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	publisher := &mock.Publisher{}
+	repo, err := inmem.NewBlockChain(publisher)
+	require.NoError(t, err)
+	templates := application.NewTemplates(repo, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), command.NewAddBlockHandler(repo, publisher), "self")
+
+	// when
+	tmpl, err := templates.New("miner")
+
+	// then
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetLast()
+	assert.Equal(genesis, tmpl.Previous)
+	assert.Equal(genesis.Challenge.Difficulty, tmpl.Challenge.Difficulty)
+	assert.Equal(genesis.TimestampMilis+tmpl.Challenge.TimeCapMillis, tmpl.MinTimestampMillis)
+	require.Len(t, tmpl.Transactions, 1)
+	assert.True(tmpl.Transactions[0].IsCoinbase())
+	assert.Equal("miner", tmpl.Transactions[0].Outputs()[0].Address())
+
+	// when the miner solves it
+	timestamp := time.Now().UnixMilli()
+	solved := tmpl.Challenge
+	require.NoError(t, solved.RollUntilMatchesDifficulty(tmpl.Previous, tmpl.Transactions, timestamp))
+	block, err := templates.Submit(tmpl.ID, solved.Nonce, timestamp)
+
+	// then
+	require.NoError(t, err)
+	chain = chainOf(t, repo)
+	assert.Equal(block, chain.GetLast())
+	assert.Equal(solved, block.Challenge)
+	assert.Equal(1, publisher.Called)
+
+	// and the template cannot be submitted twice
+	_, err = templates.Submit(tmpl.ID, solved.Nonce, timestamp)
+	assert.ErrorIs(err, application.TemplateNotFound)
+}
+
+func TestTemplates_shouldRejectUnsolvedBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	publisher := &mock.Publisher{}
+	repo, err := inmem.NewBlockChain(publisher)
+	require.NoError(t, err)
+	templates := application.NewTemplates(repo, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), command.NewAddBlockHandler(repo, publisher), "self")
+	tmpl, err := templates.New("")
+	require.NoError(t, err)
+
+	// and given a nonce which does not solve the challenge
+	timestamp := time.Now().UnixMilli()
+	nonce := uint32(0)
+	for tried := tmpl.Challenge; ; nonce++ {
+		require.NoError(t, tried.Try(tmpl.Previous, tmpl.Transactions, timestamp, nonce))
+		if !tried.MatchesDifficulty() {
+			break
+		}
+	}
+
+	// when
+	_, err = templates.Submit(tmpl.ID, nonce, timestamp)
+
+	// then
+	assert.ErrorIs(err, blockchain.BlockDidNotMatchDiff)
+	assert.Len(chainOf(t, repo).Blocks, 1)
+	assert.Zero(publisher.Called)
+
+	// when the template is unknown
+	_, err = templates.Submit("unknown", nonce, timestamp)
+
+	// then
+	assert.ErrorIs(err, application.TemplateNotFound)
+}
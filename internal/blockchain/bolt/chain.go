@@ -0,0 +1,101 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"go.etcd.io/bbolt"
+)
+
+// mainChain reads the main chain within a database transaction, one block or header at a time.
+type mainChain struct {
+	tx *bbolt.Tx
+}
+
+// height of the tip of the main chain.
+func (m mainChain) height() (int, error) {
+	last, _ := m.tx.Bucket(blocksBucket).Cursor().Last()
+	if last == nil {
+		return 0, blockchain.BlockNotFound
+	}
+	return int(binary.BigEndian.Uint64(last)), nil
+}
+
+func (m mainChain) tip() (blockchain.Block, error) {
+	height, err := m.height()
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+	return m.blockAt(height)
+}
+
+func (m mainChain) blockAt(index int) (blockchain.Block, error) {
+	stored := m.tx.Bucket(blocksBucket).Get(heightKey(index))
+	if stored == nil {
+		return blockchain.Block{}, fmt.Errorf("%w: %d", blockchain.BlockNotFound, index)
+	}
+	var block blockchain.Block
+	if err := canonical.Unmarshal(stored, &block); err != nil {
+		return blockchain.Block{}, fmt.Errorf("error reading block %d: %w", index, err)
+	}
+	return block, nil
+}
+
+// indexOf the block with the given hash, as long as it is on the main chain.
+func (m mainChain) indexOf(hash string) (int, bool) {
+	stored := m.tx.Bucket(hashesBucket).Get([]byte(hash))
+	if stored == nil {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint64(stored)), true
+}
+
+func (m mainChain) GetBlockByHash(hash string) (blockchain.Block, error) {
+	index, ok := m.indexOf(hash)
+	if !ok {
+		return blockchain.Block{}, blockchain.BlockNotFound
+	}
+	return m.blockAt(index)
+}
+
+// HeaderAt reads the header on its own, so that the transactions of the block are not decoded.
+func (m mainChain) HeaderAt(index int) (blockchain.Header, bool) {
+	headers, err := m.headers(index, 1)
+	if err != nil || len(headers) == 0 || headers[0].Index != index {
+		return blockchain.Header{}, false
+	}
+	return headers[0], true
+}
+
+// headers returns at most count headers, starting with the block with the given index.
+func (m mainChain) headers(from int, count int) ([]blockchain.Header, error) {
+	headers := make([]blockchain.Header, 0)
+	if from < 0 || count <= 0 {
+		return headers, nil
+	}
+	c := m.tx.Bucket(headersBucket).Cursor()
+	for k, v := c.Seek(heightKey(from)); k != nil && len(headers) < count; k, v = c.Next() {
+		var header blockchain.Header
+		if err := canonical.Unmarshal(v, &header); err != nil {
+			return nil, fmt.Errorf("error reading header %d: %w", binary.BigEndian.Uint64(k), err)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// all the blocks of the main chain, from the genesis to the tip.
+func (m mainChain) all() (*blockchain.BlockChain, error) {
+	chain := &blockchain.BlockChain{}
+	err := m.tx.Bucket(blocksBucket).ForEach(func(k, v []byte) error {
+		var block blockchain.Block
+		if err := canonical.Unmarshal(v, &block); err != nil {
+			return fmt.Errorf("error reading block %d: %w", binary.BigEndian.Uint64(k), err)
+		}
+		chain.Blocks = append(chain.Blocks, block)
+		return nil
+	})
+	return chain, err
+}
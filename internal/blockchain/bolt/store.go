@@ -0,0 +1,439 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"go.etcd.io/bbolt"
+)
+
+// schemaVersion is bumped whenever the layout of the buckets changes.
+const schemaVersion byte = 2
+
+var (
+	OtherNetwork       = errors.New("database holds the chain of another network")
+	UnsupportedVersion = errors.New("database schema version not supported")
+	UndoNotFound       = errors.New("undo data of the block not found")
+)
+
+var (
+	metaBucket    = []byte("meta")
+	blocksBucket  = []byte("blocks")  // height -> block on the main chain
+	headersBucket = []byte("headers") // height -> header of the block, to read the headers without the transactions
+	hashesBucket  = []byte("hashes")  // block hash -> height, for the blocks on the main chain
+	undoBucket    = []byte("undo")    // height -> how the block changed the unspent outputs
+	unspentBucket = []byte("unspent") // outpoint -> amount and address
+	addressBucket = []byte("address") // address and outpoint -> nothing, to find the outputs of an address
+
+	genesisKey = []byte("genesis")
+	versionKey = []byte("version")
+)
+
+// Store keeps the main chain and the unspent outputs in a bbolt database. Every block is written in the same database
+// transaction as the change it makes to the unspent outputs, so that the two never disagree, even after a crash.
+//
+// Opening the store only reads its metadata. The tip, the blocks and the headers are read from the database as they
+// are asked for and the unspent outputs are never rebuilt, so neither the startup nor adding a block depends on
+// the length of the chain. Side branches are only kept in memory, like with the in-memory repository, and find the
+// blocks of the main chain they build on in the database.
+type Store struct {
+	db   *bbolt.DB
+	side *blockchain.SideBranches
+	rw   sync.RWMutex
+}
+
+// Open the database at the given path, creating it with the genesis block of the active network when it is new.
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{metaBucket, blocksBucket, headersBucket, hashesBucket, undoBucket, unspentBucket, addressBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		meta := tx.Bucket(metaBucket)
+		genesis := blockchain.GenerateGenesisBlock()
+		stored := meta.Get(genesisKey)
+		if stored == nil {
+			slog.Info("creating chain database", "path", path, "genesis", genesis.ContentHash)
+			if err := meta.Put(versionKey, []byte{schemaVersion}); err != nil {
+				return err
+			}
+			if err := meta.Put(genesisKey, []byte(genesis.ContentHash)); err != nil {
+				return err
+			}
+			return connect(tx, genesis)
+		}
+
+		if version := meta.Get(versionKey); len(version) != 1 || version[0] != schemaVersion {
+			return fmt.Errorf("%w: %v", UnsupportedVersion, version)
+		}
+		if string(stored) != genesis.ContentHash {
+			return OtherNetwork
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, side: blockchain.NewSideBranches()}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Height of the main chain, read without loading the blocks.
+func (s *Store) Height() (int, error) {
+	height := 0
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		height, err = mainChain{tx}.height()
+		return err
+	})
+	return height, err
+}
+
+// GetChain reads every block of the main chain. The blocks were validated before they were written, so they are not
+// validated again.
+func (s *Store) GetChain() (blockchain.BlockChain, error) {
+	var chain *blockchain.BlockChain
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		chain, err = mainChain{tx}.all()
+		return err
+	})
+	if err != nil {
+		return blockchain.BlockChain{}, fmt.Errorf("could not read the chain from the database: %w", err)
+	}
+	return *chain, nil
+}
+
+func (s *Store) GetTip() (blockchain.Block, error) {
+	var tip blockchain.Block
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		tip, err = mainChain{tx}.tip()
+		return err
+	})
+	return tip, err
+}
+
+func (s *Store) GetBlock(hash string) (blockchain.Block, error) {
+	var block blockchain.Block
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		block, err = mainChain{tx}.GetBlockByHash(hash)
+		return err
+	})
+	return block, err
+}
+
+func (s *Store) GetHeaders(from int, count int) ([]blockchain.Header, error) {
+	var headers []blockchain.Header
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		headers, err = mainChain{tx}.headers(from, count)
+		return err
+	})
+	return headers, err
+}
+
+// UnspentAt disconnects the blocks of the main chain above the fork point of the branch ending with the block,
+// using their stored undo data, then connects the blocks of the branch.
+func (s *Store) UnspentAt(hash string) (transaction.UnspentOutputRepository, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	view := transaction.NewUnspentView(s)
+	var branch []blockchain.Block
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		main := mainChain{tx}
+		var fork int
+		var err error
+		fork, branch, err = s.forkOf(main, hash)
+		if err != nil {
+			return err
+		}
+		height, err := main.height()
+		if err != nil {
+			return err
+		}
+		for i := height; i >= fork; i-- {
+			undo, err := readUndo(tx, i)
+			if err != nil {
+				return err
+			}
+			view.Disconnect(undo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range branch {
+		view.Connect(block.Transactions)
+	}
+	return view, nil
+}
+
+// forkOf returns the height of the first block of the main chain which is not on the branch ending with the block
+// with the given hash, and the blocks of the branch from there on.
+func (s *Store) forkOf(main mainChain, hash string) (int, []blockchain.Block, error) {
+	if index, ok := main.indexOf(hash); ok {
+		return index + 1, nil, nil
+	}
+
+	tip, err := s.side.Get(hash)
+	if err != nil {
+		return 0, nil, err
+	}
+	branch, err := s.side.BranchTo(main, tip)
+	if err != nil {
+		return 0, nil, err
+	}
+	return branch[0].Index, branch, nil
+}
+
+// PutBlock validates the block against the tip of the chain, then stores it along with the unspent outputs it changes.
+func (s *Store) PutBlock(block blockchain.Block) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		main := mainChain{tx}
+		tip, err := main.tip()
+		if err != nil {
+			return err
+		}
+		if err := blockchain.ValidateAfter(main, tip, block); err != nil {
+			return err
+		}
+		if err := connect(tx, block); err != nil {
+			return fmt.Errorf("error storing block %d: %w", block.Index, err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) PutSideBlock(block blockchain.Block) error {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	return s.db.View(func(tx *bbolt.Tx) error {
+		return s.side.Add(mainChain{tx}, block)
+	})
+}
+
+// Reorganize disconnects the blocks after the fork point and connects the branch in a single database transaction,
+// as long as the branch is heavier than the blocks it replaces. Only the blocks above the fork point are read.
+func (s *Store) Reorganize(tip blockchain.Block) ([]blockchain.Block, []blockchain.Block, error) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	var disconnected, branch []blockchain.Block
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		main := mainChain{tx}
+		var err error
+		branch, err = s.side.BranchTo(main, tip)
+		if err != nil {
+			return err
+		}
+
+		fork := branch[0].Index
+		height, err := main.height()
+		if err != nil {
+			return err
+		}
+		replaced, err := main.headers(fork, height-fork+1)
+		if err != nil {
+			return err
+		}
+		if blockchain.GetCumulativeDifficulty(headersOf(branch)).Cmp(blockchain.GetCumulativeDifficulty(replaced)) <= 0 {
+			return blockchain.BranchNotHeavier
+		}
+
+		for i := height; i >= fork; i-- {
+			block, err := main.blockAt(i)
+			if err != nil {
+				return err
+			}
+			if err := disconnect(tx, block); err != nil {
+				return err
+			}
+			disconnected = append(disconnected, block)
+		}
+		slices.Reverse(disconnected)
+
+		parent, err := main.tip()
+		if err != nil {
+			return err
+		}
+		for _, block := range branch {
+			if err := blockchain.ValidateAfter(main, parent, block); err != nil {
+				return err
+			}
+			if err := connect(tx, block); err != nil {
+				return err
+			}
+			parent = block
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.side.Remove(branch...)
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		for _, block := range disconnected {
+			if err := s.side.Add(mainChain{tx}, block); err != nil {
+				slog.Warn("could not keep disconnected block on a side branch", "hash", block.ContentHash, "error", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("could not keep disconnected blocks on a side branch", "error", err)
+	}
+
+	return disconnected, branch, nil
+}
+
+// Update does nothing, the unspent outputs are updated in the same database transaction as the blocks.
+func (s *Store) Update() error {
+	return nil
+}
+
+func (s *Store) GetAll() ([]transaction.UnspentOutput, error) {
+	var outputs []transaction.UnspentOutput
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		outputs, err = unspentView{tx}.GetAll()
+		return err
+	})
+	return outputs, err
+}
+
+func (s *Store) GetByAddress(address string) ([]transaction.UnspentOutput, error) {
+	var outputs []transaction.UnspentOutput
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		outputs, err = unspentView{tx}.GetByAddress(address)
+		return err
+	})
+	return outputs, err
+}
+
+func (s *Store) GetByOutputIDAndIndex(outputID transaction.ID, outputIndex int) (transaction.UnspentOutput, error) {
+	var output transaction.UnspentOutput
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		output, err = unspentView{tx}.GetByOutputIDAndIndex(outputID, outputIndex)
+		return err
+	})
+	return output, err
+}
+
+// Set replaces all the unspent outputs with the given ones. The outputs are otherwise kept in step with the blocks,
+// so this is only meant for repairing the database.
+func (s *Store) Set(outputs []transaction.UnspentOutput) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return unspentView{tx}.Set(outputs)
+	})
+}
+
+// connect stores the block on top of the main chain, spends the outputs it spends and adds the ones it creates.
+// The undo data is stored next to the block, for when it gets disconnected.
+func connect(tx *bbolt.Tx, block blockchain.Block) error {
+	view := unspentView{tx}
+	undo, err := transaction.NewUndo(block.Transactions, view)
+	if err != nil {
+		return fmt.Errorf("error connecting block %d: %w", block.Index, err)
+	}
+	if err := view.remove(undo.Spent...); err != nil {
+		return err
+	}
+	if err := view.add(undo.Created...); err != nil {
+		return err
+	}
+
+	key := heightKey(block.Index)
+	if err := tx.Bucket(blocksBucket).Put(key, canonical.Marshal(block)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(headersBucket).Put(key, canonical.Marshal(block.Header)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(hashesBucket).Put([]byte(block.ContentHash), key); err != nil {
+		return err
+	}
+	return tx.Bucket(undoBucket).Put(key, canonical.Marshal(undoRecord(undo)))
+}
+
+// disconnect removes the block from the top of the main chain and reverts its change to the unspent outputs.
+func disconnect(tx *bbolt.Tx, block blockchain.Block) error {
+	undo, err := readUndo(tx, block.Index)
+	if err != nil {
+		return err
+	}
+
+	view := unspentView{tx}
+	if err := view.remove(undo.Created...); err != nil {
+		return err
+	}
+	if err := view.add(undo.Spent...); err != nil {
+		return err
+	}
+
+	key := heightKey(block.Index)
+	if err := tx.Bucket(blocksBucket).Delete(key); err != nil {
+		return err
+	}
+	if err := tx.Bucket(headersBucket).Delete(key); err != nil {
+		return err
+	}
+	if err := tx.Bucket(hashesBucket).Delete([]byte(block.ContentHash)); err != nil {
+		return err
+	}
+	return tx.Bucket(undoBucket).Delete(key)
+}
+
+func readUndo(tx *bbolt.Tx, index int) (transaction.Undo, error) {
+	stored := tx.Bucket(undoBucket).Get(heightKey(index))
+	if stored == nil {
+		return transaction.Undo{}, fmt.Errorf("%w: %d", UndoNotFound, index)
+	}
+	var undo undoRecord
+	if err := canonical.Unmarshal(stored, &undo); err != nil {
+		return transaction.Undo{}, fmt.Errorf("error reading undo data of block %d: %w", index, err)
+	}
+	return transaction.Undo(undo), nil
+}
+
+func headersOf(blocks []blockchain.Block) []blockchain.Header {
+	headers := make([]blockchain.Header, 0, len(blocks))
+	for _, block := range blocks {
+		headers = append(headers, block.Header)
+	}
+	return headers
+}
+
+func heightKey(index int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(index))
+}
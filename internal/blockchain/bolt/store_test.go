@@ -0,0 +1,283 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestStore_shouldKeepChainAndUnspentOutputsAcrossRestarts(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a new database
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	chain := chainOf(t, store)
+	genesis := chain.GetFirst()
+	genesisTx := genesis.Transactions[0]
+
+	// then it holds the genesis block and its output
+	assert.Equal([]blockchain.Block{genesis}, chainOf(t, store).Blocks)
+	assert.Equal([]transaction.UnspentOutput{
+		transaction.NewUnspentOutput(genesisTx.ID(), 0, chaincfg.Active().GenesisAmount, chaincfg.Active().GenesisAddress),
+	}, outputsOf(t, store, chaincfg.Active().GenesisAddress))
+
+	// when a block spending the genesis output is put and the database is opened again
+	spend, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(genesisTx.ID(), 0, "")},
+		[]*transaction.Output{transaction.NewOutput(chaincfg.Active().GenesisAmount, "bob")},
+	)
+	require.NoError(t, err)
+	block := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1), *spend)
+	require.NoError(t, store.PutBlock(block))
+	require.NoError(t, store.Close())
+	store, err = Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// then
+	height, err := store.Height()
+	assert.NoError(err)
+	assert.Equal(1, height)
+	assert.Equal([]blockchain.Block{genesis, block}, chainOf(t, store).Blocks)
+	assert.Empty(outputsOf(t, store, chaincfg.Active().GenesisAddress))
+	assert.Equal([]transaction.UnspentOutput{
+		transaction.NewUnspentOutput(spend.ID(), 0, chaincfg.Active().GenesisAmount, "bob"),
+	}, outputsOf(t, store, "bob"))
+	all, err := store.GetAll()
+	assert.NoError(err)
+	assert.ElementsMatch(transaction.UnspentOutputsFrom(append(genesis.Transactions, block.Transactions...)), all)
+}
+
+func TestStore_shouldNotStoreBlockSpendingMissingOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	store, err := Open(filepath.Join(t.TempDir(), "chain.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	chain := chainOf(t, store)
+	genesis := chain.GetFirst()
+	spend, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("missing", 0, "")},
+		[]*transaction.Output{transaction.NewOutput(1, "bob")},
+	)
+	require.NoError(t, err)
+	block := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1), *spend)
+
+	// when
+	err = store.PutBlock(block)
+
+	// then neither the block nor its outputs are stored
+	assert.Error(err)
+	assert.Equal([]blockchain.Block{genesis}, chainOf(t, store).Blocks)
+	height, err := store.Height()
+	assert.NoError(err)
+	assert.Zero(height)
+	assert.Empty(outputsOf(t, store, "alice"))
+}
+
+func TestStore_shouldReadOnlyTheBlocksAskedFor(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a block on top of the genesis
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	genesis := blockchain.GenerateGenesisBlock()
+	block := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1))
+	require.NoError(t, store.PutBlock(block))
+	require.NoError(t, store.Close())
+
+	// when the stored genesis block gets corrupted and the database is opened again
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(blocksBucket).Put(heightKey(0), []byte("corrupted"))
+	}))
+	require.NoError(t, db.Close())
+	store, err = Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// then the whole chain cannot be read
+	_, err = store.GetChain()
+	assert.Error(err)
+
+	// and the tip, its block and the headers still can
+	tip, err := store.GetTip()
+	assert.NoError(err)
+	assert.Equal(block, tip)
+	found, err := store.GetBlock(block.ContentHash)
+	assert.NoError(err)
+	assert.Equal(block, found)
+	headers, err := store.GetHeaders(0, 10)
+	assert.NoError(err)
+	assert.Equal([]blockchain.Header{genesis.Header, block.Header}, headers)
+	_, err = store.GetBlock("unknown")
+	assert.ErrorIs(err, blockchain.BlockNotFound)
+}
+
+func TestStore_shouldFollowChainThroughReorganization(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a block spending the genesis output
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	chain := chainOf(t, store)
+	genesis := chain.GetFirst()
+	genesisTx := genesis.Transactions[0]
+	spend, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(genesisTx.ID(), 0, "")},
+		[]*transaction.Output{transaction.NewOutput(chaincfg.Active().GenesisAmount, "bob")},
+	)
+	require.NoError(t, err)
+	mainBlock := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1), *spend)
+	require.NoError(t, store.PutBlock(mainBlock))
+
+	// when the chain switches to a heavier branch without that block
+	sideOne := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+200, coinbase(t, "carol", 1))
+	sideTwo := mineWith(t, []blockchain.Block{genesis, sideOne}, genesis.TimestampMilis+300, coinbase(t, "carol", 2))
+	require.NoError(t, store.PutSideBlock(sideOne))
+	require.NoError(t, store.PutSideBlock(sideTwo))
+	disconnected, connected, err := store.Reorganize(sideTwo)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+	store, err = Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// then the spent output is restored from the undo data
+	assert.Equal([]blockchain.Block{mainBlock}, disconnected)
+	assert.Equal([]blockchain.Block{sideOne, sideTwo}, connected)
+	assert.Equal([]blockchain.Block{genesis, sideOne, sideTwo}, chainOf(t, store).Blocks)
+	assert.Equal([]transaction.UnspentOutput{
+		transaction.NewUnspentOutput(genesisTx.ID(), 0, chaincfg.Active().GenesisAmount, chaincfg.Active().GenesisAddress),
+	}, outputsOf(t, store, chaincfg.Active().GenesisAddress))
+	assert.Empty(outputsOf(t, store, "bob"))
+	assert.Empty(outputsOf(t, store, "alice"))
+	assert.Len(outputsOf(t, store, "carol"), 2)
+}
+
+func TestStore_shouldServeUnspentOutputsAtAnyBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a block spending the genesis output
+	store, err := Open(filepath.Join(t.TempDir(), "chain.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	chain := chainOf(t, store)
+	genesis := chain.GetFirst()
+	genesisTx := genesis.Transactions[0]
+	genesisOutput := transaction.NewUnspentOutput(genesisTx.ID(), 0, chaincfg.Active().GenesisAmount, chaincfg.Active().GenesisAddress)
+	spend, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(genesisTx.ID(), 0, "")},
+		[]*transaction.Output{transaction.NewOutput(chaincfg.Active().GenesisAmount, "bob")},
+	)
+	require.NoError(t, err)
+	mainBlock := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1), *spend)
+	require.NoError(t, store.PutBlock(mainBlock))
+	// and a side branch without it
+	side := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+200, coinbase(t, "carol", 1))
+	require.NoError(t, store.PutSideBlock(side))
+
+	tt := []struct {
+		description string
+		hash        string
+		expected    []transaction.UnspentOutput
+	}{
+		{
+			description: "tip",
+			hash:        mainBlock.ContentHash,
+			expected:    transaction.UnspentOutputsFrom(append(genesis.Transactions, mainBlock.Transactions...)),
+		},
+		{
+			description: "below the tip",
+			hash:        genesis.ContentHash,
+			expected:    []transaction.UnspentOutput{genesisOutput},
+		},
+		{
+			description: "side branch",
+			hash:        side.ContentHash,
+			expected:    transaction.UnspentOutputsFrom(append(genesis.Transactions, side.Transactions...)),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			unspent, err := store.UnspentAt(tc.hash)
+
+			// then
+			require.NoError(t, err)
+			all, err := unspent.GetAll()
+			assert.NoError(err)
+			assert.ElementsMatch(tc.expected, all)
+		})
+	}
+
+	// and the outputs of the tip are left as they are
+	assert.Empty(outputsOf(t, store, chaincfg.Active().GenesisAddress))
+	// and an unknown block has none
+	_, err = store.UnspentAt("unknown")
+	assert.ErrorIs(err, blockchain.BlockNotFound)
+}
+
+func TestOpen_shouldRejectDatabaseOfAnotherNetwork(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, store.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(genesisKey, []byte(blockchain.GenerateGenesisBlockFor(chaincfg.RegTest).ContentHash))
+	}))
+	require.NoError(t, store.Close())
+
+	// when
+	_, err = Open(path)
+
+	// then
+	assert.ErrorIs(t, err, OtherNetwork)
+}
+
+func coinbase(t *testing.T, receiver string, height int) transaction.Transaction {
+	t.Helper()
+	tx, err := transaction.NewCoinbase(receiver, height)
+	require.NoError(t, err)
+	return *tx
+}
+
+func mineWith(t *testing.T, blocks []blockchain.Block, timestamp int64, transactions ...transaction.Transaction) blockchain.Block {
+	t.Helper()
+	chain := &blockchain.BlockChain{Blocks: blocks}
+	difficulty, err := blockchain.GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := blockchain.NewChallenge(difficulty, 2)
+	require.NoError(t, err)
+	require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	return block
+}
+
+func outputsOf(t *testing.T, store *Store, address string) []transaction.UnspentOutput {
+	t.Helper()
+	outputs, err := store.GetByAddress(address)
+	require.NoError(t, err)
+	return outputs
+}
+
+func chainOf(t *testing.T, repo *Store) blockchain.BlockChain {
+	t.Helper()
+	chain, err := repo.GetChain()
+	require.NoError(t, err)
+	return chain
+}
@@ -0,0 +1,177 @@
+package bolt
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"go.etcd.io/bbolt"
+)
+
+// unspentView reads and changes the unspent outputs within a database transaction.
+type unspentView struct {
+	tx *bbolt.Tx
+}
+
+func (v unspentView) GetAll() ([]transaction.UnspentOutput, error) {
+	outputs := make([]transaction.UnspentOutput, 0)
+	err := v.tx.Bucket(unspentBucket).ForEach(func(k, val []byte) error {
+		output, err := decodeOutput(k, val)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, output)
+		return nil
+	})
+	return outputs, err
+}
+
+func (v unspentView) GetByAddress(address string) ([]transaction.UnspentOutput, error) {
+	var outputs []transaction.UnspentOutput
+	prefix := addressPrefix(address)
+	unspent := v.tx.Bucket(unspentBucket)
+	c := v.tx.Bucket(addressBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		key := append([]byte{canonical.Version}, k[len(prefix):]...)
+		output, err := decodeOutput(key, unspent.Get(key))
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// GetByOutputIDAndIndex returns an empty output when the output is not unspent, like the in-memory repository.
+func (v unspentView) GetByOutputIDAndIndex(outputID transaction.ID, outputIndex int) (transaction.UnspentOutput, error) {
+	key := outpointKey(outputID, outputIndex)
+	val := v.tx.Bucket(unspentBucket).Get(key)
+	if val == nil {
+		return transaction.UnspentOutput{}, nil
+	}
+	return decodeOutput(key, val)
+}
+
+func (v unspentView) Set(outputs []transaction.UnspentOutput) error {
+	for _, name := range [][]byte{unspentBucket, addressBucket} {
+		if err := v.tx.DeleteBucket(name); err != nil {
+			return err
+		}
+		if _, err := v.tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	return v.add(outputs...)
+}
+
+func (v unspentView) add(outputs ...transaction.UnspentOutput) error {
+	unspent := v.tx.Bucket(unspentBucket)
+	addresses := v.tx.Bucket(addressBucket)
+	for _, output := range outputs {
+		key := outpointKey(output.OutputID(), output.OutputIndex())
+		e := canonical.NewEncoder()
+		e.Int64(int64(output.Amount()))
+		e.String(output.Address())
+		if err := unspent.Put(key, e.Encoded()); err != nil {
+			return err
+		}
+		if err := addresses.Put(addressKey(output.Address(), key), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v unspentView) remove(outputs ...transaction.UnspentOutput) error {
+	unspent := v.tx.Bucket(unspentBucket)
+	addresses := v.tx.Bucket(addressBucket)
+	for _, output := range outputs {
+		key := outpointKey(output.OutputID(), output.OutputIndex())
+		if err := unspent.Delete(key); err != nil {
+			return err
+		}
+		if err := addresses.Delete(addressKey(output.Address(), key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func outpointKey(id transaction.ID, index int) []byte {
+	e := canonical.NewEncoder()
+	e.String(string(id))
+	e.Uint32(uint32(index))
+	return e.Encoded()
+}
+
+func addressPrefix(address string) []byte {
+	e := canonical.NewEncoder()
+	e.String(address)
+	return e.Encoded()
+}
+
+// addressKey is the address followed by the outpoint, so that the outputs of an address are next to each other.
+func addressKey(address string, outpoint []byte) []byte {
+	return append(addressPrefix(address), outpoint[1:]...)
+}
+
+func decodeOutput(key, val []byte) (transaction.UnspentOutput, error) {
+	if val == nil {
+		return transaction.UnspentOutput{}, fmt.Errorf("output %x indexed but not stored", key)
+	}
+	k, err := canonical.NewDecoder(key)
+	if err != nil {
+		return transaction.UnspentOutput{}, err
+	}
+	id := transaction.ID(k.String())
+	index := int(k.Uint32())
+	if err := k.Finish(); err != nil {
+		return transaction.UnspentOutput{}, err
+	}
+
+	v, err := canonical.NewDecoder(val)
+	if err != nil {
+		return transaction.UnspentOutput{}, err
+	}
+	amount := int(v.Int64())
+	address := v.String()
+	if err := v.Finish(); err != nil {
+		return transaction.UnspentOutput{}, err
+	}
+	return transaction.NewUnspentOutput(id, index, amount, address), nil
+}
+
+// undoRecord is the undo data of a block, as it is stored.
+type undoRecord transaction.Undo
+
+func (u undoRecord) MarshalCanonical(e *canonical.Encoder) {
+	marshalOutputs(e, u.Spent)
+	marshalOutputs(e, u.Created)
+}
+
+func (u *undoRecord) UnmarshalCanonical(d *canonical.Decoder) {
+	u.Spent = unmarshalOutputs(d)
+	u.Created = unmarshalOutputs(d)
+}
+
+func marshalOutputs(e *canonical.Encoder, outputs []transaction.UnspentOutput) {
+	e.Length(len(outputs))
+	for _, output := range outputs {
+		e.String(string(output.OutputID()))
+		e.Uint32(uint32(output.OutputIndex()))
+		e.Int64(int64(output.Amount()))
+		e.String(output.Address())
+	}
+}
+
+func unmarshalOutputs(d *canonical.Decoder) []transaction.UnspentOutput {
+	outputs := make([]transaction.UnspentOutput, d.Length())
+	for i := range outputs {
+		id := transaction.ID(d.String())
+		index := int(d.Uint32())
+		amount := int(d.Int64())
+		outputs[i] = transaction.NewUnspentOutput(id, index, amount, d.String())
+	}
+	return outputs
+}
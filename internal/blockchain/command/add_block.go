@@ -1,10 +1,13 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	ev "github.com/patrykferenc/eecoin/internal/common/event"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
 type AddBlock struct {
@@ -15,30 +18,96 @@ type AddBlockHandler interface {
 	Handle(AddBlock) error
 }
 
-func NewAddBlockHandler(repo BlockChainRepository) AddBlockHandler {
+func NewAddBlockHandler(repo BlockChainRepository, publisher ev.Publisher) AddBlockHandler {
 	return &addBlockHandler{
-		repo: repo,
+		repo:      repo,
+		publisher: publisher,
 	}
 }
 
 type addBlockHandler struct {
-	repo BlockChainRepository
+	repo      BlockChainRepository
+	publisher ev.Publisher
 }
 
 type BlockChainRepository interface { // TODO#30 make not public, refactor to not return the blockchain as a whole (unsafe to read)
-	GetChain() blockchain.BlockChain
+	// GetChain returns every block of the main chain. It takes as long as the chain is, so the tip, the blocks and
+	// the headers are better read on their own.
+	GetChain() (blockchain.BlockChain, error)
+	// GetTip returns the last block of the main chain.
+	GetTip() (blockchain.Block, error)
+	// GetBlock returns the block of the main chain with the given hash.
+	GetBlock(hash string) (blockchain.Block, error)
+	// GetHeaders returns at most count headers of the main chain, starting with the block with the given index.
+	GetHeaders(from int, count int) ([]blockchain.Header, error)
+	// UnspentAt returns the outputs unspent once the block with the given hash is connected, which can be on a side
+	// branch. They are read through to the unspent outputs of the tip, so they only hold until the chain changes.
+	UnspentAt(hash string) (transaction.UnspentOutputRepository, error)
 	PutBlock(block blockchain.Block) error
+	// PutSideBlock stores a valid block which does not extend the tip of the chain.
+	PutSideBlock(block blockchain.Block) error
+	// Reorganize switches the chain to the branch ending with the given tip, if that branch is heavier.
+	// It returns the disconnected and the connected blocks.
+	Reorganize(tip blockchain.Block) ([]blockchain.Block, []blockchain.Block, error)
 }
 
 func (h *addBlockHandler) Handle(command AddBlock) error {
 	block := command.ToAdd
-	chain := h.repo.GetChain()
+	tip, err := h.repo.GetTip()
+	if err != nil {
+		return fmt.Errorf("could not add block to chain: %w", err)
+	}
 
-	err := chain.AddBlock(block)
+	unspent, err := h.repo.UnspentAt(block.PrevHash)
 	if err != nil {
-		slog.Warn("could not add block to chain in the handler", "error", err)
+		slog.Warn("could not find the parent of the block in the handler", "error", err)
+		return fmt.Errorf("could not add block to chain: %w", err)
+	}
+	if err := blockchain.ValidateTransactions(unspent, block); err != nil {
+		slog.Warn("block with invalid transactions rejected", "index", block.Index, "error", err)
 		return fmt.Errorf("could not add block to chain: %w", err)
 	}
 
+	if block.PrevHash == tip.ContentHash {
+		err := h.repo.PutBlock(block)
+		if err != nil {
+			slog.Warn("could not add block to chain in the handler", "error", err)
+			return fmt.Errorf("could not add block to chain: %w", err)
+		}
+
+		event, err := ev.New(blockchain.NewBlockAddedEvent{Block: block}, "x.block.added")
+		if err != nil {
+			return fmt.Errorf("could not create event: %w", err)
+		}
+		if err := h.publisher.Publish(event); err != nil {
+			return fmt.Errorf("could not publish event: %w", err)
+		}
+		return nil
+	}
+
+	if err := h.repo.PutSideBlock(block); err != nil {
+		slog.Warn("could not add block to a side branch in the handler", "error", err)
+		return fmt.Errorf("could not add block to chain: %w", err)
+	}
+
+	disconnected, connected, err := h.repo.Reorganize(block)
+	if errors.Is(err, blockchain.BranchNotHeavier) {
+		slog.Info("block kept on a side branch", "index", block.Index, "hash", block.ContentHash)
+		return nil
+	}
+	if err != nil {
+		slog.Warn("could not reorganize the chain", "error", err)
+		return fmt.Errorf("could not reorganize the chain: %w", err)
+	}
+	slog.Info("chain reorganized", "disconnected", len(disconnected), "connected", len(connected), "tip", block.ContentHash)
+
+	event, err := ev.New(blockchain.ChainReorganizedEvent{Disconnected: disconnected, Connected: connected}, "x.chain.reorganized")
+	if err != nil {
+		return fmt.Errorf("could not create event: %w", err)
+	}
+	if err := h.publisher.Publish(event); err != nil {
+		return fmt.Errorf("could not publish event: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,81 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBlock_shouldValidateTransactions(t *testing.T) {
+	// given
+	coinbase, err := transaction.NewCoinbase("miner", 1)
+	require.NoError(t, err)
+	wrongHeight, err := transaction.NewCoinbase("miner", 5)
+	require.NoError(t, err)
+	minted, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("not-existing", 0, "")},
+		[]*transaction.Output{transaction.NewOutput(1000, "thief")},
+	)
+	require.NoError(t, err)
+
+	tt := []struct {
+		description  string
+		transactions []transaction.Transaction
+		valid        bool
+	}{
+		{description: "valid coinbase", transactions: []transaction.Transaction{*coinbase}, valid: true},
+		{description: "no coinbase", transactions: []transaction.Transaction{}},
+		{description: "coinbase for another height", transactions: []transaction.Transaction{*wrongHeight}},
+		{description: "spending unknown output", transactions: []transaction.Transaction{*coinbase, *minted}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			repo, err := inmem.NewBlockChain(&mock.Publisher{})
+			require.NoError(t, err)
+			chain := chainOf(t, repo)
+			block := mineBlock(t, chain, tc.transactions, chain.GetLast().TimestampMilis+100)
+			publisher := &mock.Publisher{}
+			handler := command.NewAddBlockHandler(repo, publisher)
+
+			// when
+			err = handler.Handle(command.AddBlock{ToAdd: block})
+
+			// then
+			if tc.valid {
+				assert.NoError(t, err)
+				assert.Len(t, chainOf(t, repo).Blocks, 2)
+				assert.Equal(t, 1, publisher.Called)
+			} else {
+				assert.ErrorIs(t, err, blockchain.TransactionsNotValid)
+				assert.Len(t, chainOf(t, repo).Blocks, 1)
+				assert.Zero(t, publisher.Called)
+			}
+		})
+	}
+}
+
+func mineBlock(t *testing.T, chain blockchain.BlockChain, transactions []transaction.Transaction, timestamp int64) blockchain.Block {
+	t.Helper()
+	difficulty, err := blockchain.GetDifficulty(chain)
+	require.NoError(t, err)
+	challenge, err := blockchain.NewChallenge(difficulty, 2)
+	require.NoError(t, err)
+	require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	return block
+}
+
+func chainOf(t *testing.T, repo command.BlockChainRepository) blockchain.BlockChain {
+	t.Helper()
+	chain, err := repo.GetChain()
+	require.NoError(t, err)
+	return chain
+}
@@ -33,7 +33,7 @@ type peers interface {
 }
 
 func (h *broadcastBlockHandler) Handle(cmd BroadcastBlock) error {
-	_ = h.repository.GetChain() // TODO for lint xd
+	_, _ = h.repository.GetTip() // TODO for lint xd
 	peers, err := h.peers.Get()
 	if err != nil {
 		return err
@@ -1,19 +1,55 @@
 package command
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	ev "github.com/patrykferenc/eecoin/internal/common/event"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
-	"log/slog"
-	"time"
+)
+
+// templateCheckInterval is how often the miner checks whether the block it works on became outdated.
+const templateCheckInterval = 500 * time.Millisecond
+
+var (
+	miningInterrupted = errors.New("mining interrupted")
+	templateOutdated  = errors.New("block template outdated")
 )
 
 type MineBlock struct {
+	// Context stops the mining once cancelled.
+	Context          context.Context
 	InterruptChannel chan bool
+	// Blocks makes the handler return once it mined that many blocks. When zero, it mines until interrupted.
+	Blocks int
+	// Address receives the coinbase of the mined blocks, the node's own address when empty.
+	Address string
+	// Mined is called with every block mined, if set.
+	Mined func(block blockchain.Block)
 }
 
 type MineBlockHandler interface {
-	Handle(cmd MineBlock)
+	// Handle mines until cancelled, interrupted or done with the blocks asked for. It fails when it cannot build or
+	// solve a block template.
+	Handle(cmd MineBlock) error
+	// Progress tells what the miner works on, it is empty when not mining.
+	Progress() MiningProgress
+}
+
+// MiningProgress describes the block template being mined and how fast the hashes are computed.
+type MiningProgress struct {
+	Height     int
+	Difficulty int
+	Hashrate   float64 // hashes per second
 }
 
 type mineBlockHandler struct {
@@ -21,78 +57,296 @@ type mineBlockHandler struct {
 	repository     BlockChainRepository
 	publisher      ev.Publisher
 	poolRepository transaction.PoolRepository
+	unspent        transaction.UnspentOutputRepository
+	workers        int
+
+	hashes     atomic.Uint64
+	hashrate   atomic.Uint64 // float64 bits
+	meter      hashMeter
+	height     atomic.Int64
+	difficulty atomic.Int64
 }
 
-func NewMineBlockHandler(selfAddress string, repository BlockChainRepository, publisher ev.Publisher, poolRepository transaction.PoolRepository) MineBlockHandler {
+// NewMineBlockHandler creates a miner searching for the nonces with the given number of workers, or with one worker
+// per CPU when the number is not positive.
+func NewMineBlockHandler(selfAddress string, repository BlockChainRepository, publisher ev.Publisher, poolRepository transaction.PoolRepository, unspent transaction.UnspentOutputRepository, workers int) MineBlockHandler {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
 	return &mineBlockHandler{
 		repository:     repository,
 		publisher:      publisher,
 		poolRepository: poolRepository,
+		unspent:        unspent,
 		selfAddr:       selfAddress,
+		workers:        workers,
 	}
 }
 
-func (h *mineBlockHandler) Handle(cmd MineBlock) {
-	var chain = h.repository.GetChain()
-	var previousBlock = chain.GetLast()
-	var c, err = blockchain.NewChallenge(previousBlock.Challenge.Difficulty, previousBlock.Challenge.TimeCapMillis)
-	if err != nil {
-		slog.Error("Error creating challenge", "error", err)
+// blockTemplate is the block the miner works on: everything but the solved challenge.
+type blockTemplate struct {
+	previous     blockchain.Block
+	challenge    blockchain.Challenge
+	transactions []transaction.Transaction
+	timestamp    int64
+	pool         map[transaction.ID]struct{}
+}
+
+func (h *mineBlockHandler) Handle(cmd MineBlock) error {
+	ctx := cmd.Context
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	payout := cmd.Address
+	if payout == "" {
+		payout = h.selfAddr
+	}
+	h.meter.rate(h.hashes.Load())
+	defer func() {
+		h.hashrate.Store(0)
+		h.height.Store(0)
+		h.difficulty.Store(0)
+	}()
+
+	mined := 0
 	for {
-		currentTime := time.Now().UnixMilli()
-		var transactions = h.poolRepository.GetAll()
-		tx, err := transaction.NewCoinbase(h.selfAddr, 10)
-		transactions = append([]transaction.Transaction{*tx}, transactions...)
+		tmpl, err := h.newTemplate(payout)
 		if err != nil {
-			slog.Error("Error creating coinbase transaction", "error", err)
+			return fmt.Errorf("error creating block template: %w", err)
+		}
+		h.height.Store(int64(tmpl.previous.Index + 1))
+		h.difficulty.Store(int64(tmpl.challenge.Difficulty))
+
+		solved, err := h.solve(ctx, cmd.InterruptChannel, &tmpl)
+		switch {
+		case errors.Is(err, templateOutdated):
+			slog.Debug("Block template outdated, building a new one")
+			continue
+		case errors.Is(err, miningInterrupted):
+			slog.Info("Mining interrupted", "length", tmpl.previous.Index+1)
+			return nil
+		case ctx.Err() != nil:
+			slog.Info("Mining stopped", "length", tmpl.previous.Index+1)
+			return nil
+		case err != nil:
+			return fmt.Errorf("error solving challenge: %w", err)
+		case !solved:
+			slog.Debug("Nonces ran out, building a new block template")
 			continue
 		}
-		err = c.RollNonce(previousBlock, transactions, currentTime)
+
+		slog.Info("Block mined", "index", tmpl.previous.Index+1, "hash", tmpl.challenge.HashValue, "hashrate", h.Progress().Hashrate)
+		b, err := h.submit(tmpl)
 		if err != nil {
-			slog.Error("Error rolling nonce", "error", err)
+			slog.Warn("Mined block was not added, mining again", "error", err)
 			continue
 		}
-		if c.MatchesDifficulty() {
-			slog.Info("Block mined", "index", len(chain.Blocks), "hash", c.HashValue)
-			b, err := chain.NewBlock(currentTime, transactions, c)
-			if err != nil {
-				slog.Error("Error creating new block", "error", err)
-				continue
-			}
-			slog.Info("New block created", "index", len(chain.Blocks))
 
-			err = h.repository.PutBlock(b)
+		mined++
+		if cmd.Mined != nil {
+			cmd.Mined(b)
+		}
+		if cmd.Blocks > 0 && mined >= cmd.Blocks {
+			return nil
+		}
+	}
+}
+
+func (h *mineBlockHandler) Progress() MiningProgress {
+	return MiningProgress{
+		Height:     int(h.height.Load()),
+		Difficulty: int(h.difficulty.Load()),
+		Hashrate:   math.Float64frombits(h.hashrate.Load()),
+	}
+}
 
-			if err != nil {
-				slog.Error("Error adding new block", "error", err)
-				continue
-			}
+func (h *mineBlockHandler) newTemplate(payout string) (blockTemplate, error) {
+	last, err := h.repository.GetTip()
+	if err != nil {
+		return blockTemplate{}, err
+	}
+	challenge, err := ChallengeFor(h.repository, last)
+	if err != nil {
+		return blockTemplate{}, err
+	}
+	pool := h.poolRepository.GetAll()
+	transactions, err := BlockTransactions(pool, h.unspent, last.Index+1, PayTo(payout))
+	if err != nil {
+		return blockTemplate{}, err
+	}
 
-			event, err := ev.New(blockchain.NewBlockAddedEvent{Block: b}, "x.block.added")
-			if err != nil {
-				slog.Error("Error creating event", "error", err)
-				continue
-			}
+	ids := make(map[transaction.ID]struct{}, len(pool))
+	for _, tx := range pool {
+		ids[tx.ID()] = struct{}{}
+	}
+
+	// the block has to come at least the time cap after its parent
+	timestamp := max(time.Now().UnixMilli(), last.TimestampMilis+challenge.TimeCapMillis)
+
+	return blockTemplate{
+		previous:     last,
+		challenge:    challenge,
+		transactions: transactions,
+		timestamp:    timestamp,
+		pool:         ids,
+	}, nil
+}
 
-			err = h.publisher.Publish(event)
-			if err != nil {
-				slog.Error("Error publishing event", "error", err)
-				continue
+// solve searches for the nonce of the template in the background, while checking whether the template is still
+// up to date and measuring the hashrate.
+func (h *mineBlockHandler) solve(ctx context.Context, interrupt chan bool, tmpl *blockTemplate) (bool, error) {
+	search, stop := context.WithCancel(ctx)
+	defer stop()
+
+	type result struct {
+		solved bool
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		solved, err := tmpl.challenge.Solve(search, h.workers, tmpl.previous, tmpl.transactions, tmpl.timestamp, &h.hashes)
+		done <- result{solved: solved, err: err}
+	}()
+
+	ticker := time.NewTicker(templateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-done:
+			return r.solved, r.err
+		case <-interrupt:
+			stop()
+			<-done
+			return false, miningInterrupted
+		case <-ticker.C:
+			h.hashrate.Store(math.Float64bits(h.meter.rate(h.hashes.Load())))
+			if h.outdated(*tmpl) {
+				stop()
+				<-done
+				return false, templateOutdated
 			}
+		}
+	}
+}
 
-			chain = h.repository.GetChain()
-			previousBlock = chain.GetLast()
+// outdated tells whether the chain tip or the pool changed since the template was built.
+func (h *mineBlockHandler) outdated(tmpl blockTemplate) bool {
+	tip, err := h.repository.GetTip()
+	if err != nil || tip.ContentHash != tmpl.previous.ContentHash {
+		return true
+	}
+	pool := h.poolRepository.GetAll()
+	if len(pool) != len(tmpl.pool) {
+		return true
+	}
+	for _, tx := range pool {
+		if _, ok := tmpl.pool[tx.ID()]; !ok {
+			return true
 		}
-		select {
-		case <-cmd.InterruptChannel:
-			slog.Info("Mining interrupted. Updating chain", "length", len(chain.Blocks))
-			chain = h.repository.GetChain()
-			previousBlock = chain.GetLast()
-			slog.Info("Chain updated", "length", len(chain.Blocks))
-			return
-		default:
-			//slog.Info("Mining loop, no update...")
+	}
+	return false
+}
+
+// submit turns the solved template into a block, puts it on the chain and announces it.
+func (h *mineBlockHandler) submit(tmpl blockTemplate) (blockchain.Block, error) {
+	b, err := blockchain.NewBlockAfter(tmpl.previous.Header, tmpl.timestamp, tmpl.transactions, tmpl.challenge)
+	if err != nil {
+		return blockchain.Block{}, err
+	}
+
+	// the pool can still hold transactions of the blocks whose outputs were not connected yet
+	if err := h.validate(b); err != nil {
+		return blockchain.Block{}, err
+	}
+
+	if err := h.repository.PutBlock(b); err != nil {
+		return blockchain.Block{}, err
+	}
+	slog.Info("New block created", "index", b.Index)
+
+	event, err := ev.New(blockchain.NewBlockAddedEvent{Block: b}, "x.block.added")
+	if err != nil {
+		slog.Error("Error creating event", "error", err)
+	} else if err := h.publisher.Publish(event); err != nil {
+		slog.Error("Error publishing event", "error", err)
+	}
+	return b, nil
+}
+
+// hashMeter works out the hashrate from the growing count of hashes.
+type hashMeter struct {
+	mu     sync.Mutex
+	hashes uint64
+	at     time.Time
+}
+
+func (m *hashMeter) rate(hashes uint64) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var rate float64
+	if elapsed := now.Sub(m.at).Seconds(); !m.at.IsZero() && elapsed > 0 {
+		rate = float64(hashes-m.hashes) / elapsed
+	}
+	m.hashes, m.at = hashes, now
+	return rate
+}
+
+// ChallengeFor the block following the tip, at the difficulty the chain expects. Only the headers reaching back to
+// the start of the adjustment interval are read.
+func ChallengeFor(repository BlockChainRepository, tip blockchain.Block) (blockchain.Challenge, error) {
+	from := max(min(tip.Index+1-chaincfg.Active().DifficultyAdjustmentInterval, tip.Index), 0)
+	headers, err := repository.GetHeaders(from, tip.Index-from+1)
+	if err != nil {
+		return blockchain.Challenge{}, err
+	}
+	if len(headers) == 0 || headers[len(headers)-1].ContentHash != tip.ContentHash {
+		return blockchain.Challenge{}, fmt.Errorf("%w: %s is not the tip of the chain anymore", blockchain.BlockNotFound, tip.ContentHash)
+	}
+	difficulty, err := blockchain.DifficultyAfter(headers)
+	if err != nil {
+		return blockchain.Challenge{}, err
+	}
+	return blockchain.NewChallenge(difficulty, tip.Challenge.TimeCapMillis)
+}
+
+func (h *mineBlockHandler) validate(block blockchain.Block) error {
+	unspent, err := h.repository.UnspentAt(block.PrevHash)
+	if err != nil {
+		return err
+	}
+	return blockchain.ValidateTransactions(unspent, block)
+}
+
+// Payout splits the reward of a block, the subsidy and the fees, between the outputs of its coinbase.
+type Payout func(reward int) []*transaction.Output
+
+// PayTo pays the whole reward to the address.
+func PayTo(address string) Payout {
+	return func(reward int) []*transaction.Output {
+		return []*transaction.Output{transaction.NewOutput(reward, address)}
+	}
+}
+
+// BlockTransactions picks the pool transactions paying the highest fee rate which fit in a block, and puts a coinbase
+// paying the subsidy and their fees out in front of them.
+func BlockTransactions(pool []transaction.Transaction, unspent transaction.UnspentOutputRepository, height int, payout Payout) ([]transaction.Transaction, error) {
+	params := chaincfg.Active()
+	subsidy := transaction.ActiveSubsidy().At(height)
+
+	// the coinbase takes up room as well, and how much depends on how the payout splits the fees
+	reserved := 0
+	for {
+		selected, fees := transaction.SelectTransactions(pool, unspent, params.MaxBlockSize-reserved, params.MaxBlockTransactions-1)
+		coinbase, err := transaction.NewCoinbaseWithOutputs(height, payout(subsidy+fees))
+		if err != nil {
+			return nil, err
+		}
+		if size := coinbase.Size(); size > reserved {
+			reserved = size
+			continue
 		}
+		return append([]transaction.Transaction{*coinbase}, selected...), nil
 	}
 }
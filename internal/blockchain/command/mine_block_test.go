@@ -0,0 +1,53 @@
+package command_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMineBlock_shouldMineUntilCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	handler := command.NewMineBlockHandler("self", repo, &mock.Publisher{}, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	mined := 0
+
+	// when
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Handle(command.MineBlock{
+			Context: ctx,
+			Mined: func(block blockchain.Block) {
+				mined++
+				if mined == 3 {
+					cancel()
+				}
+			},
+		})
+	}()
+
+	// then
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		cancel()
+		t.Fatal("mining did not stop")
+	}
+	chain := chainOf(t, repo)
+	assert.GreaterOrEqual(len(chain.Blocks), 4)
+	assert.Equal(len(chain.Blocks)-1, mined)
+	assert.Equal(command.MiningProgress{}, handler.Progress())
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+const maxSyncPeers = 8
+
+var NoPeersToSync = errors.New("no peers to sync with")
+
+type SyncChain struct{}
+
+// SyncChainHandler downloads the chains of several peers and switches to the heaviest valid one,
+// if it is heavier than the local chain.
+type SyncChainHandler interface {
+	Handle(SyncChain) error
+}
+
+type chainRetriever interface {
+	Get(peer string) (blockchain.BlockChain, error)
+}
+
+type syncChainHandler struct {
+	repo      BlockChainRepository
+	retriever chainRetriever
+	peers     peers
+}
+
+func NewSyncChainHandler(repo BlockChainRepository, retriever chainRetriever, peers peers) SyncChainHandler {
+	return &syncChainHandler{
+		repo:      repo,
+		retriever: retriever,
+		peers:     peers,
+	}
+}
+
+func (h *syncChainHandler) Handle(cmd SyncChain) error {
+	peers, err := h.peers.Get()
+	if err != nil {
+		return fmt.Errorf("could not get peers: %w", err)
+	}
+	if len(peers) == 0 {
+		return NoPeersToSync
+	}
+	if len(peers) > maxSyncPeers {
+		peers = peers[:maxSyncPeers]
+	}
+
+	candidates, err := h.heaviestFirst(peers)
+	if err != nil {
+		return err
+	}
+
+	// a heavier chain can still hold a block which is not valid, then the next heaviest one is tried
+	local, err := h.repo.GetChain()
+	if err != nil {
+		return fmt.Errorf("could not read the local chain: %w", err)
+	}
+	tried := make(map[string]struct{})
+	var errs []error
+	for _, candidate := range candidates {
+		if candidate.GetCumulativeDifficulty().Cmp(local.GetCumulativeDifficulty()) <= 0 {
+			break
+		}
+		tip := candidate.GetLast().ContentHash
+		if _, ok := tried[tip]; ok {
+			continue
+		}
+		tried[tip] = struct{}{}
+
+		err := h.adopt(local, candidate)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("could not adopt remote chain, trying the next heaviest", "length", len(candidate.Blocks), "error", err)
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("no heavier chain is valid: %w", errors.Join(errs...))
+	}
+
+	slog.Info("local chain is up to date", "length", len(local.Blocks))
+	return nil
+}
+
+// heaviestFirst downloads the chains of the peers and orders them by their cumulative difficulty, heaviest first.
+func (h *syncChainHandler) heaviestFirst(peers []string) ([]blockchain.BlockChain, error) {
+	type result struct {
+		chain blockchain.BlockChain
+		err   error
+	}
+	results := make(chan result, len(peers))
+
+	for _, peer := range peers {
+		go func(peer string) {
+			chain, err := h.retriever.Get(peer)
+			results <- result{chain: chain, err: err}
+		}(peer)
+	}
+
+	var chains []blockchain.BlockChain
+	for range peers {
+		r := <-results
+		if r.err != nil {
+			slog.Warn("could not get chain from peer", "error", r.err)
+			continue
+		}
+		chains = append(chains, r.chain)
+	}
+
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("could not get a valid chain from any of %d peers", len(peers))
+	}
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].GetCumulativeDifficulty().Cmp(chains[j].GetCumulativeDifficulty()) > 0
+	})
+	return chains, nil
+}
+
+func (h *syncChainHandler) adopt(local, remote blockchain.BlockChain) error {
+	fork := 0
+	for fork < len(local.Blocks) && fork < len(remote.Blocks) && local.Blocks[fork].ContentHash == remote.Blocks[fork].ContentHash {
+		fork++
+	}
+
+	return switchToBranch(h.repo, remote.Blocks[fork:])
+}
+
+// switchToBranch stores the blocks of a branch, which has to be connected to the local chain, and reorganizes the chain onto it.
+func switchToBranch(repo BlockChainRepository, branch []blockchain.Block) error {
+	unspent, err := repo.UnspentAt(branch[0].PrevHash)
+	if err != nil {
+		return fmt.Errorf("remote chain is not connected to the local one: %w", err)
+	}
+	if err := blockchain.ValidateTransactions(unspent, branch...); err != nil {
+		return fmt.Errorf("remote chain is not valid: %w", err)
+	}
+
+	for _, block := range branch {
+		if err := repo.PutSideBlock(block); err != nil && !errors.Is(err, blockchain.BlockAlreadyKnown) {
+			return fmt.Errorf("could not store block %d from remote chain: %w", block.Index, err)
+		}
+	}
+
+	disconnected, connected, err := repo.Reorganize(branch[len(branch)-1])
+	if err != nil {
+		return fmt.Errorf("could not switch to remote chain: %w", err)
+	}
+
+	slog.Info("chain synced", "disconnected", len(disconnected), "connected", len(connected), "length", branch[len(branch)-1].Index+1)
+	return nil
+}
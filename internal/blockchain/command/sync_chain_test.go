@@ -0,0 +1,155 @@
+package command_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncChain_shouldAdoptHeaviestChain(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a fresh node
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+
+	// and peers with chains of different weight
+	light := mineChain(t, genesis, 1, 100)
+	heavy := mineChain(t, genesis, 3, 200)
+	retriever := &mockChainRetriever{chains: map[string]blockchain.BlockChain{
+		"http://light": light,
+		"http://heavy": heavy,
+	}, errs: map[string]error{
+		"http://broken": errors.New("connection refused"),
+	}}
+	peers := mock.NewPeers([]string{"http://light", "http://broken", "http://heavy"})
+	handler := command.NewSyncChainHandler(repo, retriever, peers)
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.NoError(err)
+	assert.Equal(heavy.Blocks, chainOf(t, repo).Blocks)
+}
+
+func TestSyncChain_shouldFallBackToTheNextHeaviestValidChain(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a fresh node
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+
+	// and a peer with the heaviest chain, whose last coinbase mints more than it can
+	invalid := mineChainWith(t, genesis, 3, 200, func(height int) (*transaction.Transaction, error) {
+		if height == 3 {
+			return transaction.NewCoinbaseWithFees("miner", height, 1000)
+		}
+		return transaction.NewCoinbase("miner", height)
+	})
+	// and a peer with a lighter chain which is valid
+	valid := mineChain(t, genesis, 2, 100)
+	retriever := &mockChainRetriever{chains: map[string]blockchain.BlockChain{
+		"http://invalid": invalid,
+		"http://valid":   valid,
+	}}
+	handler := command.NewSyncChainHandler(repo, retriever, mock.NewPeers([]string{"http://invalid", "http://valid"}))
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.NoError(err)
+	assert.Equal(valid.Blocks, chainOf(t, repo).Blocks)
+}
+
+func TestSyncChain_shouldKeepLocalChainWhenItIsHeavier(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a node with a chain
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+	local := mineChain(t, genesis, 2, 100)
+	for _, block := range local.Blocks[1:] {
+		require.NoError(t, repo.PutBlock(block))
+	}
+
+	// and a peer with a lighter chain
+	retriever := &mockChainRetriever{chains: map[string]blockchain.BlockChain{
+		"http://light": mineChain(t, genesis, 1, 200),
+	}}
+	handler := command.NewSyncChainHandler(repo, retriever, mock.NewPeers([]string{"http://light"}))
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.NoError(err)
+	assert.Equal(local.Blocks, chainOf(t, repo).Blocks)
+}
+
+func TestSyncChain_shouldErrorWithoutPeers(t *testing.T) {
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	handler := command.NewSyncChainHandler(repo, &mockChainRetriever{}, mock.NewPeers(nil))
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.Equal(t, command.NoPeersToSync, err)
+}
+
+type mockChainRetriever struct {
+	chains map[string]blockchain.BlockChain
+	errs   map[string]error
+}
+
+func (m *mockChainRetriever) Get(peer string) (blockchain.BlockChain, error) {
+	if err, ok := m.errs[peer]; ok {
+		return blockchain.BlockChain{}, err
+	}
+	return m.chains[peer], nil
+}
+
+func mineChain(t *testing.T, genesis blockchain.Block, length int, timeOffset int64) blockchain.BlockChain {
+	t.Helper()
+	return mineChainWith(t, genesis, length, timeOffset, func(height int) (*transaction.Transaction, error) {
+		return transaction.NewCoinbase("miner", height)
+	})
+}
+
+func mineChainWith(t *testing.T, genesis blockchain.Block, length int, timeOffset int64, coinbaseAt func(height int) (*transaction.Transaction, error)) blockchain.BlockChain {
+	t.Helper()
+	chain, err := blockchain.ImportBlockchain([]blockchain.Block{genesis})
+	require.NoError(t, err)
+	for i := 1; i <= length; i++ {
+		coinbase, err := coinbaseAt(i)
+		require.NoError(t, err)
+		transactions := []transaction.Transaction{*coinbase}
+		timestamp := genesis.TimestampMilis + timeOffset + int64(i)*10
+		difficulty, err := blockchain.GetDifficulty(*chain)
+		require.NoError(t, err)
+		challenge, err := blockchain.NewChallenge(difficulty, 2)
+		require.NoError(t, err)
+		require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+		block, err := chain.NewBlock(timestamp, transactions, challenge)
+		require.NoError(t, err)
+		require.NoError(t, chain.AddBlock(block))
+	}
+	return *chain
+}
@@ -0,0 +1,201 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+const headersPageSize = 2000
+
+var BodyNotMatchingHeader = errors.New("block body does not match the announced header")
+
+type headersRetriever interface {
+	GetHeaders(peer string, from int, count int) ([]blockchain.Header, error)
+	GetBlock(peer string, hash string) (blockchain.Block, error)
+}
+
+type headersFirstSyncHandler struct {
+	repo      BlockChainRepository
+	retriever headersRetriever
+	peers     peers
+}
+
+// NewHeadersFirstSyncHandler syncs by downloading only the headers from several peers first.
+// Once the heaviest valid header chain is known, the missing bodies are fetched in parallel from the peers which announced it.
+func NewHeadersFirstSyncHandler(repo BlockChainRepository, retriever headersRetriever, peers peers) SyncChainHandler {
+	return &headersFirstSyncHandler{
+		repo:      repo,
+		retriever: retriever,
+		peers:     peers,
+	}
+}
+
+func (h *headersFirstSyncHandler) Handle(cmd SyncChain) error {
+	peers, err := h.peers.Get()
+	if err != nil {
+		return fmt.Errorf("could not get peers: %w", err)
+	}
+	if len(peers) == 0 {
+		return NoPeersToSync
+	}
+	if len(peers) > maxSyncPeers {
+		peers = peers[:maxSyncPeers]
+	}
+
+	tip, err := h.repo.GetTip()
+	if err != nil {
+		return fmt.Errorf("could not read the local chain: %w", err)
+	}
+	local, err := h.repo.GetHeaders(0, tip.Index+1)
+	if err != nil {
+		return fmt.Errorf("could not read the local chain: %w", err)
+	}
+	best, serving, err := h.heaviest(peers, local[0])
+	if err != nil {
+		return err
+	}
+
+	if blockchain.GetCumulativeDifficulty(best).Cmp(blockchain.GetCumulativeDifficulty(local)) <= 0 {
+		slog.Info("local chain is up to date", "length", len(local))
+		return nil
+	}
+
+	fork := 0
+	for fork < len(local) && fork < len(best) && local[fork].ContentHash == best[fork].ContentHash {
+		fork++
+	}
+
+	branch, err := h.bodies(best[fork:], serving)
+	if err != nil {
+		return err
+	}
+
+	return switchToBranch(h.repo, branch)
+}
+
+// heaviest returns the heaviest valid header chain together with the peers which announced it.
+func (h *headersFirstSyncHandler) heaviest(peers []string, genesis blockchain.Header) ([]blockchain.Header, []string, error) {
+	type result struct {
+		peer    string
+		headers []blockchain.Header
+		err     error
+	}
+	results := make(chan result, len(peers))
+
+	for _, peer := range peers {
+		go func(peer string) {
+			headers, err := h.downloadHeaders(peer, genesis)
+			results <- result{peer: peer, headers: headers, err: err}
+		}(peer)
+	}
+
+	var best []blockchain.Header
+	var serving []string
+	for range peers {
+		r := <-results
+		if r.err != nil {
+			slog.Warn("could not get headers from peer", "peer", r.peer, "error", r.err)
+			continue
+		}
+
+		heavier := blockchain.GetCumulativeDifficulty(r.headers).Cmp(blockchain.GetCumulativeDifficulty(best))
+		switch {
+		case best == nil || heavier > 0:
+			best, serving = r.headers, []string{r.peer}
+		case heavier == 0 && r.headers[len(r.headers)-1] == best[len(best)-1]:
+			serving = append(serving, r.peer)
+		}
+	}
+
+	if best == nil {
+		return nil, nil, fmt.Errorf("could not get valid headers from any of %d peers", len(peers))
+	}
+	return best, serving, nil
+}
+
+func (h *headersFirstSyncHandler) downloadHeaders(peer string, genesis blockchain.Header) ([]blockchain.Header, error) {
+	headers := make([]blockchain.Header, 0)
+	for {
+		page, err := h.retriever.GetHeaders(peer, len(headers), headersPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		if len(headers) == 0 {
+			if page[0] != genesis {
+				return nil, fmt.Errorf("%w: peer %s is on a different genesis", blockchain.HeadersNotValid, peer)
+			}
+			headers, page = append(headers, page[0]), page[1:]
+		}
+		if err := blockchain.ValidateHeaders(headers, page); err != nil {
+			return nil, fmt.Errorf("headers from %s: %w", peer, err)
+		}
+		headers = append(headers, page...)
+	}
+
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("peer %s sent no headers", peer)
+	}
+	return headers, nil
+}
+
+// bodies downloads the blocks for the headers, spreading the requests over the peers.
+// A block which could not be downloaded from one peer is requested from the next one.
+func (h *headersFirstSyncHandler) bodies(headers []blockchain.Header, peers []string) ([]blockchain.Block, error) {
+	blocks := make([]blockchain.Block, len(headers))
+	jobs := make(chan int, len(headers))
+	for i := range headers {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(headers))
+	for worker := range peers {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := range jobs {
+				block, err := h.body(headers[i], peers, worker)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				blocks[i] = block
+			}
+		}(worker)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (h *headersFirstSyncHandler) body(header blockchain.Header, peers []string, first int) (blockchain.Block, error) {
+	var lastErr error
+	for attempt := range peers {
+		peer := peers[(first+attempt)%len(peers)]
+
+		block, err := h.retriever.GetBlock(peer, header.ContentHash)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if block.Header != header {
+			lastErr = fmt.Errorf("%w: block %d from %s", BodyNotMatchingHeader, header.Index, peer)
+			continue
+		}
+		return block, nil
+	}
+	return blockchain.Block{}, fmt.Errorf("could not download block %d: %w", header.Index, lastErr)
+}
@@ -0,0 +1,119 @@
+package command_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersFirstSync_shouldAdoptHeaviestChain(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a fresh node
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+
+	// and peers announcing chains of different weight
+	light := mineChain(t, genesis, 1, 100)
+	heavy := mineChain(t, genesis, 3, 200)
+	retriever := &mockHeadersRetriever{chains: map[string]blockchain.BlockChain{
+		"http://light":      light,
+		"http://heavy":      heavy,
+		"http://also-heavy": heavy,
+	}, bodyErrs: map[string]error{
+		"http://heavy": errors.New("connection reset"),
+	}}
+	peers := mock.NewPeers([]string{"http://light", "http://heavy", "http://also-heavy"})
+	handler := command.NewHeadersFirstSyncHandler(repo, retriever, peers)
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.NoError(err)
+	assert.Equal(heavy.Blocks, chainOf(t, repo).Blocks)
+}
+
+func TestHeadersFirstSync_shouldRejectBodyNotMatchingHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a fresh node
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+
+	// and a peer serving a different block than it announced
+	announced := mineChain(t, genesis, 1, 100)
+	served := mineChain(t, genesis, 1, 200)
+	retriever := &mockHeadersRetriever{chains: map[string]blockchain.BlockChain{
+		"http://liar": announced,
+	}, substitutes: map[string]blockchain.Block{
+		announced.Blocks[1].ContentHash: served.Blocks[1],
+	}}
+	handler := command.NewHeadersFirstSyncHandler(repo, retriever, mock.NewPeers([]string{"http://liar"}))
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.ErrorIs(err, command.BodyNotMatchingHeader)
+	assert.Equal([]blockchain.Block{genesis}, chainOf(t, repo).Blocks)
+}
+
+func TestHeadersFirstSync_shouldIgnoreInvalidHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a node with a chain
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+	local := mineChain(t, genesis, 1, 100)
+	require.NoError(t, repo.PutBlock(local.Blocks[1]))
+
+	// and a peer announcing a longer chain with broken linkage
+	broken := mineChain(t, genesis, 3, 200)
+	broken.Blocks[2].PrevHash = "tampered"
+	retriever := &mockHeadersRetriever{chains: map[string]blockchain.BlockChain{
+		"http://broken": broken,
+	}}
+	handler := command.NewHeadersFirstSyncHandler(repo, retriever, mock.NewPeers([]string{"http://broken"}))
+
+	// when
+	err = handler.Handle(command.SyncChain{})
+
+	// then
+	assert.Error(err)
+	assert.Equal(local.Blocks, chainOf(t, repo).Blocks)
+}
+
+type mockHeadersRetriever struct {
+	chains      map[string]blockchain.BlockChain
+	substitutes map[string]blockchain.Block
+	bodyErrs    map[string]error
+}
+
+func (m *mockHeadersRetriever) GetHeaders(peer string, from int, count int) ([]blockchain.Header, error) {
+	chain := m.chains[peer]
+	return chain.GetHeaders(from, count), nil
+}
+
+func (m *mockHeadersRetriever) GetBlock(peer string, hash string) (blockchain.Block, error) {
+	if err, ok := m.bodyErrs[peer]; ok {
+		return blockchain.Block{}, err
+	}
+	if block, ok := m.substitutes[hash]; ok {
+		return block, nil
+	}
+	chain := m.chains[peer]
+	return chain.GetBlockByHash(hash)
+}
@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
 	"github.com/patrykferenc/eecoin/internal/blockchain/command"
 	"github.com/patrykferenc/eecoin/internal/blockchain/net/http"
 	"github.com/patrykferenc/eecoin/internal/blockchain/query"
@@ -10,33 +11,64 @@ import (
 )
 
 type Component struct {
-	Queries  Queries
-	Commands Commands
+	Queries     Queries
+	Commands    Commands
+	Application Application
 }
 
 type Queries struct {
-	GetChain query.GetChain
+	GetChain            query.GetChain
+	GetHeaders          query.GetHeaders
+	GetBlock            query.GetBlock
+	GetSupply           query.GetSupply
+	GetTransactionProof query.GetTransactionProof
+	GetUnspentProofs    query.GetUnspentProofs
 }
 
 type Commands struct {
 	AddBlock  command.AddBlockHandler
 	Broadcast command.BroadcastBlockHandler
 	MineBlock command.MineBlockHandler
+	SyncChain command.SyncChainHandler
 }
 
-func NewComponent(selfAddress string, repo command.BlockChainRepository, peers peersquery.GetPeers, publisher event.Publisher, repository transaction.PoolRepository) Component {
+type Application struct {
+	Mining    *application.Mining
+	Templates *application.Templates
+	// PoolTemplates keeps the jobs of the mining pool apart from the templates of the external miners, so that these
+	// cannot push out a job the workers still submit shares for.
+	PoolTemplates *application.Templates
+}
+
+func NewComponent(selfAddress string, repo command.BlockChainRepository, peers peersquery.GetPeers, healthyPeers peersquery.GetPeers, publisher event.Publisher, repository transaction.PoolRepository, unspent transaction.UnspentOutputRepository, headersFirstSync bool, miningWorkers int, interruptMining chan bool) Component {
 	broadcaster := http.NewBroadcaster()
 
 	broadcastHandler := command.NewBroadcastBlockHandler(repo, broadcaster, peers)
-	mineBlockHandler := command.NewMineBlockHandler(selfAddress, repo, publisher, repository)
+	mineBlockHandler := command.NewMineBlockHandler(selfAddress, repo, publisher, repository, unspent, miningWorkers)
+	addBlockHandler := command.NewAddBlockHandler(repo, publisher)
+	syncChainHandler := command.NewSyncChainHandler(repo, http.NewChainClient(), healthyPeers)
+	if headersFirstSync {
+		syncChainHandler = command.NewHeadersFirstSyncHandler(repo, http.NewBlockClient(), healthyPeers)
+	}
 	return Component{
 		Queries: Queries{
-			GetChain: query.NewGetChain(repo),
+			GetChain:            query.NewGetChain(repo),
+			GetHeaders:          query.NewGetHeaders(repo),
+			GetBlock:            query.NewGetBlock(repo),
+			GetSupply:           query.NewGetSupply(repo),
+			GetTransactionProof: query.NewGetTransactionProof(repo),
+			GetUnspentProofs:    query.NewGetUnspentProofs(repo, unspent),
 		},
 		Commands: Commands{
-			AddBlock:  command.NewAddBlockHandler(repo),
+			AddBlock:  addBlockHandler,
 			Broadcast: broadcastHandler,
 			MineBlock: mineBlockHandler,
+			SyncChain: syncChainHandler,
+		},
+		Application: Application{
+			Mining:        application.NewMining(mineBlockHandler, selfAddress, interruptMining),
+			Templates:     application.NewTemplates(repo, repository, unspent, addBlockHandler, selfAddress),
+			PoolTemplates: application.NewTemplates(repo, repository, unspent, addBlockHandler, selfAddress),
 		},
 	}
 }
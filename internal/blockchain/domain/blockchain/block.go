@@ -1,13 +1,14 @@
 package blockchain
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
-	"fmt"
 	"log/slog"
-	"time"
+	"math/big"
 
-	"github.com/gymshark/go-hasher"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
@@ -18,68 +19,96 @@ var (
 	BlockDidNotMatchDiff  = errors.New("block did not match difficulty")
 	BlockWasNotWithinTime = errors.New("block was not within time")
 	ChainNotValid         = errors.New("chain not valid")
-	GenesisBlockTimestamp = time.Date(2024, 11, 16, 20, 23, 0, 0, time.UTC).UnixMilli()
 )
 
-type Block struct {
+// Header holds everything that links the blocks together and proves the work done on them.
+type Header struct {
 	Index          int
 	TimestampMilis int64
 	ContentHash    string
 	PrevHash       string
-	Transactions   []transaction.Transaction
-	Challenge      Challenge
+	// MerkleRoot commits the header to the transactions of the block
+	MerkleRoot string
+	Challenge  Challenge
 }
 
-func (block Block) MarshalBinary() ([]byte, error) { // TODO#30 - maybe prettify this
-	var buffer bytes.Buffer
-	_, err := buffer.WriteString(fmt.Sprintf("%d%d%s", block.Index, block.TimestampMilis, block.PrevHash))
-	if err != nil {
-		return nil, fmt.Errorf("error writing to buffer: %w", err)
-	}
+// Block is a header together with its body, the transactions.
+type Block struct {
+	Header
+	Transactions []transaction.Transaction
+}
 
-	marshalledChallange, err := block.Challenge.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("error marshalling block: %w", err)
-	}
-	_, err = buffer.Write(marshalledChallange)
-	if err != nil {
-		return nil, fmt.Errorf("error writing to buffer: %w", err)
-	}
+func (block Block) MarshalCanonical(e *canonical.Encoder) {
+	block.Header.MarshalCanonical(e)
+	marshalTransactions(e, block.Transactions)
+}
 
-	for _, transaction := range block.Transactions {
-		marshalledTransaction, err := transaction.MarshalBinary()
-		if err != nil {
-			return nil, fmt.Errorf("error marshalling block: %w", err)
-		}
-		_, err = buffer.Write(marshalledTransaction)
-		if err != nil {
-			return nil, fmt.Errorf("error writing to buffer: %w", err)
-		}
+func (block *Block) UnmarshalCanonical(d *canonical.Decoder) {
+	block.Header.UnmarshalCanonical(d)
+	block.Transactions = make([]transaction.Transaction, d.Length())
+	for i := range block.Transactions {
+		block.Transactions[i].UnmarshalCanonical(d)
 	}
+}
+
+func (block Block) MarshalBinary() ([]byte, error) {
+	return canonical.Marshal(block), nil
+}
 
-	return buffer.Bytes(), nil
+func (block *Block) UnmarshalBinary(data []byte) error {
+	return canonical.Unmarshal(data, block)
+}
+
+func marshalTransactions(e *canonical.Encoder, transactions []transaction.Transaction) {
+	e.Length(len(transactions))
+	for _, tx := range transactions {
+		tx.MarshalCanonical(e)
+	}
 }
 
 type BlockChain struct {
 	Blocks []Block
 }
 
+// MarshalCanonical writes the blocks of the chain, starting with the genesis.
+func (chain BlockChain) MarshalCanonical(e *canonical.Encoder) {
+	e.Length(len(chain.Blocks))
+	for _, block := range chain.Blocks {
+		block.MarshalCanonical(e)
+	}
+}
+
+// UnmarshalCanonical reads the blocks of the chain, which still have to be validated by importing them.
+func (chain *BlockChain) UnmarshalCanonical(d *canonical.Decoder) {
+	chain.Blocks = make([]Block, d.Length())
+	for i := range chain.Blocks {
+		chain.Blocks[i].UnmarshalCanonical(d)
+	}
+}
+
 func (chain *BlockChain) NewBlock(timestamp int64, transactions []transaction.Transaction, solved Challenge) (Block, error) {
+	return NewBlockAfter(chain.GetLast().Header, timestamp, transactions, solved)
+}
+
+// NewBlockAfter builds the block following the one with the given header, from the challenge solved for it.
+func NewBlockAfter(previous Header, timestamp int64, transactions []transaction.Transaction, solved Challenge) (Block, error) {
 	if !solved.MatchesDifficulty() {
 		slog.Error("Block not valid", "reason", "difficulty not met")
 		return Block{}, BlockDidNotMatchDiff
 	}
-	if !blockCreatedAfterPreviousWithinTimeCap(timestamp, solved, chain.GetLast()) {
+	if !blockCreatedAfterPreviousWithinTimeCap(timestamp, solved, previous) {
 		slog.Error("Block not valid", "reason", "time cap not met")
 		return Block{}, BlockWasNotWithinTime
 	}
-	previousHash := chain.Blocks[len(chain.Blocks)-1].ContentHash
 	newBlock := &Block{
-		Index:          len(chain.Blocks),
-		TimestampMilis: timestamp,
-		PrevHash:       previousHash,
-		Transactions:   transactions,
-		Challenge:      solved,
+		Header: Header{
+			Index:          previous.Index + 1,
+			TimestampMilis: timestamp,
+			PrevHash:       previous.ContentHash,
+			MerkleRoot:     MerkleRoot(transactions),
+			Challenge:      solved,
+		},
+		Transactions: transactions,
 	}
 	contentHash, err := CalculateHash(*newBlock)
 	if err != nil {
@@ -90,16 +119,32 @@ func (chain *BlockChain) NewBlock(timestamp int64, transactions []transaction.Tr
 }
 
 func (chain *BlockChain) AddBlock(new Block) error {
-	if isValidBasedOnPrevious(new, chain.GetLast()) {
-		chain.Blocks = append(chain.Blocks, new)
-		return nil
+	if err := ValidateAfter(chain, chain.GetLast(), new); err != nil {
+		return err
 	}
-	return BlockNotValid
+	chain.Blocks = append(chain.Blocks, new)
+	return nil
+}
+
+// ValidateAfter checks the block against its parent, at the difficulty expected at its height. The ancestors of the
+// parent needed for retargeting are read from the main chain.
+func ValidateAfter(chain MainChain, parent Block, block Block) error {
+	difficulty, err := nextDifficulty(parent.Header, chain.HeaderAt)
+	if err != nil {
+		return err
+	}
+	if !isValidBasedOnPrevious(block, parent, difficulty) {
+		return BlockNotValid
+	}
+	return nil
 }
 
 func (chain *BlockChain) RemoveBlocksStartingWithIndex(index int) {
-	shortenedChain := chain.Blocks[:len(chain.Blocks)-index]
-	chain.Blocks = shortenedChain
+	if index < 0 || index >= len(chain.Blocks) {
+		return
+	}
+	// capacity is capped so that blocks appended later never overwrite copies of the chain still being read
+	chain.Blocks = chain.Blocks[:index:index]
 }
 
 func (chain *BlockChain) GetBlock(index int) (Block, error) {
@@ -117,6 +162,14 @@ func (chain *BlockChain) GetFirst() Block {
 	return chain.Blocks[0]
 }
 
+// HeaderAt returns the header of the block with the given index.
+func (chain *BlockChain) HeaderAt(index int) (Header, bool) {
+	if index < 0 || index >= len(chain.Blocks) {
+		return Header{}, false
+	}
+	return chain.Blocks[index].Header, true
+}
+
 func (chain *BlockChain) GetBlockByHash(hash string) (Block, error) {
 	for _, block := range chain.Blocks {
 		if block.ContentHash == hash {
@@ -137,10 +190,25 @@ func (chain *BlockChain) GetBlockByTransactionID(id transaction.ID) (Block, erro
 	return Block{}, BlockNotFound
 }
 
-func (chain *BlockChain) GetCumulativeDifficulty() int64 {
-	var sum int64 = 0
+// GetHeaders returns at most count headers, starting with the block with the given index.
+func (chain *BlockChain) GetHeaders(from int, count int) []Header {
+	if from < 0 || from >= len(chain.Blocks) || count <= 0 {
+		return []Header{}
+	}
+	to := min(from+count, len(chain.Blocks))
+
+	headers := make([]Header, 0, to-from)
+	for _, block := range chain.Blocks[from:to] {
+		headers = append(headers, block.Header)
+	}
+	return headers
+}
+
+// GetCumulativeDifficulty is the work of the chain, how many hashes it takes on average to mine all of its blocks.
+func (chain *BlockChain) GetCumulativeDifficulty() *big.Int {
+	sum := new(big.Int)
 	for _, block := range chain.Blocks {
-		sum += int64(intPow(block.Challenge.Difficulty, 2))
+		sum.Add(sum, work(block.Challenge.Difficulty))
 	}
 	return sum
 }
@@ -150,7 +218,8 @@ func ImportBlockchain(blocks []Block) (*BlockChain, error) {
 		return nil, ChainNotValid
 	}
 	for i := 1; i < len(blocks); i++ {
-		if !isValidBasedOnPrevious(blocks[i], blocks[i-1]) {
+		difficulty, err := GetDifficulty(BlockChain{Blocks: blocks[:i]})
+		if err != nil || !isValidBasedOnPrevious(blocks[i], blocks[i-1], difficulty) {
 			return nil, ChainNotValid
 		}
 	}
@@ -159,31 +228,45 @@ func ImportBlockchain(blocks []Block) (*BlockChain, error) {
 	}, nil
 }
 
+// GenerateGenesisBlock creates the first block of the network the node follows.
 func GenerateGenesisBlock() Block {
-	genesisTransaction, _ := transaction.NewGenesis() // todo add error handling
+	return GenerateGenesisBlockFor(chaincfg.Active())
+}
+
+// GenerateGenesisBlockFor creates the first block of the given network.
+func GenerateGenesisBlockFor(params chaincfg.ChainParams) Block {
+	genesisTransaction, _ := transaction.NewGenesisFor(params) // todo add error handling
+	transactions := []transaction.Transaction{
+		*genesisTransaction,
+	}
 	genesisBlock := &Block{
-		Index:          0,
-		TimestampMilis: GenesisBlockTimestamp,
-		Transactions: []transaction.Transaction{
-			*genesisTransaction,
-		},
-		Challenge: Challenge{
-			TimeCapMillis: 1,
-			Difficulty:    9,
+		Header: Header{
+			Index:          0,
+			TimestampMilis: params.GenesisTimestampMillis,
+			MerkleRoot:     MerkleRoot(transactions),
+			Challenge: Challenge{
+				TimeCapMillis: 1,
+				Difficulty:    params.GenesisDifficulty,
+			},
 		},
+		Transactions: transactions,
 	}
 	contentHash, _ := CalculateHash(*genesisBlock)
 	genesisBlock.ContentHash = contentHash
 	return *genesisBlock
 }
 
+// CalculateHash of the block is the hash of its header, which commits to the transactions by their Merkle root.
 func CalculateHash(block Block) (string, error) {
-	blockAsBytes, err := block.MarshalBinary()
-	if err != nil {
-		return "", fmt.Errorf("error calculating hash: %w", err)
-	}
-	structureHash := hasher.Sha256(blockAsBytes).Base64()
-	return structureHash, nil
+	return HashHeader(block.Header), nil
+}
+
+// HashHeader is the sha256 of the canonical encoding of the header, leaving out the content hash itself.
+func HashHeader(header Header) string {
+	e := canonical.NewEncoder()
+	header.marshalHashed(e)
+	hash := sha256.Sum256(e.Encoded())
+	return base64.StdEncoding.EncodeToString(hash[:])
 }
 
 func isValidGenesis(block Block) bool {
@@ -193,38 +276,24 @@ func isValidGenesis(block Block) bool {
 		block.Index == genesisBlock.Index &&
 		block.TimestampMilis == genesisBlock.TimestampMilis &&
 		block.ContentHash == genesisBlock.ContentHash &&
-		blockActualHash == genesisBlock.ContentHash {
+		blockActualHash == genesisBlock.ContentHash &&
+		block.MerkleRoot == MerkleRoot(block.Transactions) {
 		return true
 	}
 	return false
 }
 
-func isValidBasedOnPrevious(newBlock Block, previous Block) bool {
-	contentHash, _ := CalculateHash(newBlock)
-	if contentHash == newBlock.ContentHash {
-		return newBlock.Index == previous.Index+1 && newBlock.PrevHash == previous.ContentHash &&
-			Verify(previous, newBlock.TimestampMilis, newBlock.Challenge.Nonce, newBlock.Challenge.HashValue, newBlock.Transactions) &&
-			blockCreatedAfterPreviousWithinTimeCap(newBlock.TimestampMilis, newBlock.Challenge, previous)
-	}
-	return false
+func isValidBasedOnPrevious(newBlock Block, previous Block, difficulty int) bool {
+	return newBlock.MerkleRoot == MerkleRoot(newBlock.Transactions) &&
+		isValidHeaderBasedOnPrevious(newBlock.Header, previous.Header, difficulty)
 }
 
-func blockCreatedAfterPreviousWithinTimeCap(timestamp int64, solved Challenge, latest Block) bool {
+func blockCreatedAfterPreviousWithinTimeCap(timestamp int64, solved Challenge, latest Header) bool {
 	return timestamp-latest.TimestampMilis >= solved.TimeCapMillis
 }
 
-func intPow(n, m int) int {
-	if m == 0 {
-		return 1
-	}
-
-	if m == 1 {
-		return n
-	}
-
-	result := n
-	for i := 2; i <= m; i++ {
-		result *= n
-	}
-	return result
+// work of a block is 2^difficulty, as the difficulty counts the leading zero bits of its hash, the same as the
+// retargeting counts it.
+func work(difficulty int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(difficulty))
 }
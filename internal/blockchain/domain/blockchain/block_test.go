@@ -1,9 +1,11 @@
 package blockchain
 
 import (
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction/transactiontest"
 
@@ -18,12 +20,14 @@ func TestContentHash(t *testing.T) {
 	require.NoError(t, err, "NewTransaction should not return an error")
 
 	block := Block{
-		Index:          1,
-		TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
-		ContentHash:    "uWiPiJw53c/iAfcNJ5AaoQk/gXF/12cak2mvAtH9EnE=",
-		PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
-		Transactions:   []transaction.Transaction{*someTransaction},
-		Challenge:      Challenge{},
+		Header: Header{
+			Index:          1,
+			TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
+			ContentHash:    "st6hAr1N9MpV7JVAHCyEZGEYkorBvaNyw3H3mrjK9eQ=",
+			PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
+			Challenge:      Challenge{},
+		},
+		Transactions: []transaction.Transaction{*someTransaction},
 	}
 	result, _ := CalculateHash(block)
 	assert.Equal(t, result, block.ContentHash)
@@ -43,12 +47,14 @@ func TestImportBlock_shouldError(t *testing.T) {
 			description: "Non genesis block",
 			chain: []Block{
 				{
-					Index:          -1,
-					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
-					ContentHash:    "2137",
-					PrevHash:       "2137",
-					Transactions:   make([]transaction.Transaction, 0),
-					Challenge:      Challenge{},
+					Header: Header{
+						Index:          -1,
+						TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
+						ContentHash:    "2137",
+						PrevHash:       "2137",
+						Challenge:      Challenge{},
+					},
+					Transactions: make([]transaction.Transaction, 0),
 				},
 			},
 			expectedErr: ChainNotValid,
@@ -83,6 +89,30 @@ func TestImportBlock_shouldWork(t *testing.T) {
 	assertThat.Equal(genesisChain.GetFirst(), genesisBlock)
 }
 
+func TestGenerateGenesisBlock_shouldDifferBetweenNetworks(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	mainNet := GenerateGenesisBlockFor(chaincfg.MainNet)
+	testNet := GenerateGenesisBlockFor(chaincfg.TestNet)
+	regTest := GenerateGenesisBlockFor(chaincfg.RegTest)
+
+	// then
+	assertThat.Equal(GenerateGenesisBlock(), mainNet)
+	assertThat.NotEqual(mainNet.ContentHash, testNet.ContentHash)
+	assertThat.NotEqual(mainNet.ContentHash, regTest.ContentHash)
+	assertThat.NotEqual(testNet.ContentHash, regTest.ContentHash)
+	assertThat.NotEqual(mainNet.Transactions[0].ID(), testNet.Transactions[0].ID())
+	assertThat.Equal(chaincfg.RegTest.GenesisDifficulty, regTest.Challenge.Difficulty)
+
+	// when
+	_, err := ImportBlockchain([]Block{testNet})
+
+	// then
+	assertThat.Equal(ChainNotValid, err)
+}
+
 func TestNewBlock(t *testing.T) {
 	t.Parallel()
 	assertThat := assert.New(t)
@@ -122,7 +152,7 @@ func TestAddBlock_shouldWork(t *testing.T) {
 	transactions := make([]transaction.Transaction, 0)
 
 	// and given
-	solvedChallenge, err := NewChallenge(2, 2)
+	solvedChallenge, err := NewChallenge(genesis.Challenge.Difficulty, 2)
 	assertThat.Nil(err)
 	err = solvedChallenge.RollUntilMatchesDifficulty(genesis, transactions, timestamp)
 	assertThat.Nil(err)
@@ -154,51 +184,64 @@ func TestBlockChain_GetCumulativeDifficulty(t *testing.T) {
 	chainTwo, err := ImportBlockchain([]Block{genesis})
 	assertThat.Nil(err)
 
-	// and given
-	timestamp := time.Date(2025, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 120).UnixMilli()
-	transactions := make([]transaction.Transaction, 0)
+	// and having added one block to the first chain and two to the second one
+	assertThat.Nil(chainOne.AddBlock(mineOn(t, chainOne.Blocks, genesis.TimestampMilis+100)))
+	assertThat.Nil(chainTwo.AddBlock(mineOn(t, chainTwo.Blocks, genesis.TimestampMilis+200)))
+	assertThat.Nil(chainTwo.AddBlock(mineOn(t, chainTwo.Blocks, genesis.TimestampMilis+300)))
 
-	// and given
-	solvedChallengeOne, err := NewChallenge(2, 2)
-	assertThat.Nil(err)
-	err = solvedChallengeOne.RollUntilMatchesDifficulty(genesis, transactions, timestamp)
-	assertThat.Nil(err)
+	// then the work of each block is 2^difficulty
+	assertThat.Equal(1, chainTwo.GetCumulativeDifficulty().Cmp(chainOne.GetCumulativeDifficulty()))
+	assertThat.Equal(big.NewInt(3<<genesis.Challenge.Difficulty), chainTwo.GetCumulativeDifficulty())
+}
 
-	// and given
-	solvedChallengeTwo, err := NewChallenge(3, 2)
-	assertThat.Nil(err)
-	err = solvedChallengeTwo.RollUntilMatchesDifficulty(genesis, transactions, timestamp)
-	assertThat.Nil(err)
+func TestGetCumulativeDifficulty_shouldCountTheWorkNotTheBlocks(t *testing.T) {
+	t.Parallel()
 
-	// and given new block
-	newBlockOne, err := chainOne.NewBlock(timestamp, transactions, solvedChallengeOne)
-	assertThat.Nil(err)
+	// given a block at a difficulty higher by two and three blocks at the lower one
+	withDifficulty := func(difficulties ...int) []Header {
+		headers := make([]Header, len(difficulties))
+		for i, difficulty := range difficulties {
+			headers[i].Challenge.Difficulty = difficulty
+		}
+		return headers
+	}
+	harder, easier := withDifficulty(12), withDifficulty(10, 10, 10)
 
-	newBlockTwo, err := chainTwo.NewBlock(timestamp, transactions, solvedChallengeTwo)
-	assertThat.Nil(err)
+	// then the harder block took more work to mine
+	assert.Equal(t, big.NewInt(4096), GetCumulativeDifficulty(harder))
+	assert.Equal(t, 1, GetCumulativeDifficulty(harder).Cmp(GetCumulativeDifficulty(easier)))
+}
 
-	// and having added separate blocks to two chains
-	err = chainOne.AddBlock(newBlockOne)
-	assertThat.Nil(err)
+func TestAddBlock_shouldRequireExpectedDifficulty(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
 
-	err = chainTwo.AddBlock(newBlockTwo)
-	assertThat.Nil(err)
+	// given
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	timestamp := genesis.TimestampMilis + 100
+	transactions := make([]transaction.Transaction, 0)
 
-	// when calculating third block to one of chains
-	solvedChallengeThree, err := NewChallenge(3, 2)
-	assertThat.Nil(err)
+	// and a block solved at a lower difficulty than expected
+	easy, err := NewChallenge(2, 2)
+	require.NoError(t, err)
+	require.NoError(t, easy.RollUntilMatchesDifficulty(genesis, transactions, timestamp))
+	block, err := chain.NewBlock(timestamp, transactions, easy)
+	require.NoError(t, err)
 
-	err = solvedChallengeThree.RollUntilMatchesDifficulty(chainTwo.GetLast(), transactions, timestamp+200)
-	assertThat.Nil(err)
+	// when
+	err = chain.AddBlock(block)
 
-	newBlockThree, err := chainTwo.NewBlock(timestamp+200, transactions, solvedChallengeThree)
-	assertThat.Nil(err)
+	// then
+	assertThat.Equal(BlockNotValid, err)
+	assertThat.Equal([]Block{genesis}, chain.Blocks)
 
-	err = chainTwo.AddBlock(newBlockThree)
-	assertThat.Nil(err)
+	// when importing a chain with that block
+	_, err = ImportBlockchain([]Block{genesis, block})
 
-	assertThat.Greater(chainTwo.GetCumulativeDifficulty(), chainOne.GetCumulativeDifficulty())
-	assertThat.Equal(int64(81+9+9), chainTwo.GetCumulativeDifficulty())
+	// then
+	assertThat.Equal(ChainNotValid, err)
 }
 
 func TestNewBlockWithAddBlock_shouldNotWork(t *testing.T) {
@@ -217,56 +260,66 @@ func TestNewBlockWithAddBlock_shouldNotWork(t *testing.T) {
 		{
 			description: "Invalid index",
 			block: Block{
-				Index:          12,
-				TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
-				ContentHash:    "2137",
-				PrevHash:       genesis.ContentHash,
-				Transactions:   make([]transaction.Transaction, 0),
-				Challenge:      Challenge{},
+				Header: Header{
+					Index:          12,
+					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
+					ContentHash:    "2137",
+					PrevHash:       genesis.ContentHash,
+					Challenge:      Challenge{},
+				},
+				Transactions: make([]transaction.Transaction, 0),
 			},
 		},
 		{
 			description: "Invalid prev hash",
 			block: Block{
-				Index:          1,
-				TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
-				ContentHash:    "2137",
-				PrevHash:       "2136",
-				Transactions:   make([]transaction.Transaction, 0),
-				Challenge:      Challenge{},
+				Header: Header{
+					Index:          1,
+					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).UnixMilli(),
+					ContentHash:    "2137",
+					PrevHash:       "2136",
+					Challenge:      Challenge{},
+				},
+				Transactions: make([]transaction.Transaction, 0),
 			},
 		},
 		{
 			description: "Invalid content hash",
 			block: Block{
-				Index:          1,
-				TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
-				ContentHash:    "2137",
-				PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
-				Transactions:   make([]transaction.Transaction, 0),
-				Challenge:      Challenge{},
+				Header: Header{
+					Index:          1,
+					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
+					ContentHash:    "2137",
+					PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
+					Challenge:      Challenge{},
+				},
+				Transactions: make([]transaction.Transaction, 0),
 			},
 		},
 		{
 			description: "Invalid challenge target hash",
 			block: Block{
-				Index:          1,
-				TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
-				ContentHash:    "/MNLyLMEHlB0Jj8gnyaWVezCredfngzK3sQAxELNe3o=",
-				PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
-				Transactions:   make([]transaction.Transaction, 0),
-				Challenge:      Challenge{},
+				Header: Header{
+					Index:          1,
+					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
+					ContentHash:    "/MNLyLMEHlB0Jj8gnyaWVezCredfngzK3sQAxELNe3o=",
+					PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
+					Challenge:      Challenge{},
+				},
+				Transactions: make([]transaction.Transaction, 0),
 			},
 		},
 		{
 			description: "Invalid challenge timestamp",
 			block: Block{
-				Index:          1,
-				TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
-				ContentHash:    "/MNLyLMEHlB0Jj8gnyaWVezCredfngzK3sQAxELNe3o=",
-				PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
-				Transactions:   make([]transaction.Transaction, 0),
-				Challenge:      Challenge{},
+				Header: Header{
+					Index:          1,
+					TimestampMilis: time.Date(2023, 2, 3, 12, 0, 0, 0, time.UTC).Add(time.Millisecond * 1).UnixMilli(),
+					ContentHash:    "/MNLyLMEHlB0Jj8gnyaWVezCredfngzK3sQAxELNe3o=",
+					PrevHash:       "D6bHWTk7daQ0dXVoxGG1XhtVIAwmLgoexNnv53wi3yc=",
+					Challenge:      Challenge{},
+				},
+				Transactions: make([]transaction.Transaction, 0),
 			},
 		},
 	}
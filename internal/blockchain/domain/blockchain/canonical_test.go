@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockCanonicalEncoding_golden(t *testing.T) {
+	t.Parallel()
+
+	// given
+	tx, err := transaction.NewFrom(nil, []*transaction.Output{transaction.NewOutput(5, "a")})
+	require.NoError(t, err)
+	block := Block{
+		Header: Header{
+			Index:          1,
+			TimestampMilis: 1000,
+			ContentHash:    "c",
+			PrevHash:       "p",
+			MerkleRoot:     "m",
+			Challenge:      Challenge{Difficulty: 2, Nonce: 0x0a0b0c0d, HashValue: "h", TimeCapMillis: 3},
+		},
+		Transactions: []transaction.Transaction{*tx},
+	}
+
+	// when
+	encoded, err := block.MarshalBinary()
+	hash, hashErr := CalculateHash(block)
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, hashErr)
+	assert.Equal(t, ""+
+		"01"+ // version
+		"0000000000000001"+ // index
+		"00000000000003e8"+ // timestamp
+		"00000001"+"70"+ // previous hash
+		"00000001"+"6d"+ // Merkle root
+		"0000000000000002"+"0a0b0c0d"+"00000001"+"68"+"0000000000000003"+ // challenge
+		"00000001"+"63"+ // content hash
+		"00000001"+"00000000"+"00000001"+"0000000000000005"+"00000001"+"61", // transactions
+		hex.EncodeToString(encoded))
+	assert.Equal(t, "hsdwOwual8i8Q2g9F5UUvhZ5kJknHmQimglFE1KILUs=", hash)
+}
+
+func TestBlockCanonicalEncoding_shouldRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// given
+	genesis := GenerateGenesisBlockFor(chaincfg.RegTest)
+	encoded, err := genesis.MarshalBinary()
+	require.NoError(t, err)
+
+	// when
+	var decoded Block
+	err = decoded.UnmarshalBinary(encoded)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, genesis, decoded)
+}
+
+func TestGenesisBlock_golden(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		params      chaincfg.ChainParams
+		contentHash string
+	}{
+		{params: chaincfg.MainNet, contentHash: "C28xaSjwlGOt3gySMFr4Qx6GVuKKqhFXkEPShDo/j8U="},
+		{params: chaincfg.TestNet, contentHash: "H+xj41RHHqxra38YwBxnxGxswO66A8l4WRPiHCNCw44="},
+		{params: chaincfg.RegTest, contentHash: "L47OtuWpzh0iv3YgKAExrthrcDEKQbld9E3SUXIJpMY="},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.params.Name, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			genesis := GenerateGenesisBlockFor(tc.params)
+
+			// then
+			assert.Equal(t, tc.contentHash, genesis.ContentHash)
+		})
+	}
+}
+
+func TestTargetPreimage_golden(t *testing.T) {
+	t.Parallel()
+
+	// given
+	previous := Block{Header: Header{Index: 1, ContentHash: "c"}}
+
+	// when
+	preimage, err := targetPreimage(previous, nil, 1000)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"01"+ // version
+		"0000000000000002"+ // next index
+		"00000001"+"63"+ // previous hash
+		"00000000000003e8"+ // timestamp
+		"0000002c"+hex.EncodeToString([]byte("47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=")), // Merkle root of no transactions
+		hex.EncodeToString(preimage))
+}
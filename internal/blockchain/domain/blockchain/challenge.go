@@ -2,17 +2,22 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
-	"encoding/gob"
 	"errors"
 	"math/bits"
 	"math/rand/v2"
 
-	"github.com/gymshark/go-hasher"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	t "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
+const (
+	MinDifficulty = 2
+	MaxDifficulty = 256
+)
+
 var NotValidDifficulty = errors.New("difficulty not valid, it must be between 2 and 256")
 
 type Challenge struct {
@@ -22,18 +27,37 @@ type Challenge struct {
 	TimeCapMillis int64
 }
 
+func (c Challenge) MarshalCanonical(e *canonical.Encoder) {
+	e.Int64(int64(c.Difficulty))
+	e.Uint32(c.Nonce)
+	e.String(c.HashValue)
+	e.Int64(c.TimeCapMillis)
+}
+
+func (c *Challenge) UnmarshalCanonical(d *canonical.Decoder) {
+	c.Difficulty = int(d.Int64())
+	c.Nonce = d.Uint32()
+	c.HashValue = d.String()
+	c.TimeCapMillis = d.Int64()
+}
+
 func (c Challenge) MarshalBinary() ([]byte, error) {
-	return []byte{byte(c.Difficulty), byte(c.Nonce), byte(c.TimeCapMillis)}, nil
+	return canonical.Marshal(c), nil
 }
 
 func (c *Challenge) RollNonce(previousBlock Block, transactionData []t.Transaction, currentTimestampMillis int64) error {
-	newNonce := rand.Uint32()
-	targetHash, err := calculateTargetHash(previousBlock, transactionData, currentTimestampMillis, newNonce)
+	return c.Try(previousBlock, transactionData, currentTimestampMillis, rand.Uint32())
+}
+
+// Try sets the nonce of the challenge along with the hash it gives for the block with the given transactions
+// and timestamp. The challenge is solved when the hash matches the difficulty.
+func (c *Challenge) Try(previousBlock Block, transactionData []t.Transaction, currentTimestampMillis int64, nonce uint32) error {
+	targetHash, err := calculateTargetHash(previousBlock, transactionData, currentTimestampMillis, nonce)
 	if err != nil {
 		return err
 	}
 	c.HashValue = targetHash
-	c.Nonce = newNonce
+	c.Nonce = nonce
 	return nil
 }
 
@@ -42,15 +66,31 @@ func (c *Challenge) MatchesDifficulty() bool {
 	if err != nil {
 		return false
 	}
-	if len(byteVal) < c.Difficulty/8 || len(byteVal) == 0 {
+	return hashMatchesDifficulty(byteVal, c.Difficulty)
+}
+
+func hashMatchesDifficulty(hash []byte, difficulty int) bool {
+	if len(hash) <= difficulty/8 || len(hash) == 0 {
 		return false
 	}
-	for i := 0; i <= c.Difficulty/8; i++ {
-		if bits.LeadingZeros8(byteVal[i]) < c.Difficulty-(8*i) {
+	for i := 0; i < difficulty/8; i++ {
+		if hash[i] != 0 {
 			return false
 		}
 	}
-	return true
+	return bits.LeadingZeros8(hash[difficulty/8]) >= difficulty%8
+}
+
+// Target is the highest hash matching the difficulty, when the hashes are read as big-endian numbers.
+func Target(difficulty int) []byte {
+	target := bytes.Repeat([]byte{0xff}, sha256.Size)
+	for i := 0; i < difficulty/8 && i < len(target); i++ {
+		target[i] = 0
+	}
+	if difficulty/8 < len(target) {
+		target[difficulty/8] = 0xff >> (difficulty % 8)
+	}
+	return target
 }
 
 func (c *Challenge) RollUntilMatchesDifficulty(previousBlock Block, transactionData []t.Transaction, currentTimestampMillis int64) error {
@@ -74,7 +114,7 @@ func (c *Challenge) RollUntilMatchesDifficultyCapped(maxIterations int, previous
 }
 
 func NewChallenge(difficulty int, timeCapMillis int64) (Challenge, error) {
-	if difficulty >= 2 && difficulty <= 256 {
+	if difficulty >= MinDifficulty && difficulty <= MaxDifficulty {
 		return Challenge{
 			Difficulty:    difficulty,
 			TimeCapMillis: timeCapMillis,
@@ -83,6 +123,7 @@ func NewChallenge(difficulty int, timeCapMillis int64) (Challenge, error) {
 	return Challenge{}, NotValidDifficulty
 }
 
+// Verify tells whether the hash of the block is the one the proof-of-work algorithm of the network gives.
 func Verify(previous Block, latestBlockTimestamp int64, latestSolvedChallengeNonce uint32, latestSolvedChallengeHashValue string, latestBlockData []t.Transaction) bool {
 	validHash, err := calculateTargetHash(previous, latestBlockData, latestBlockTimestamp, latestSolvedChallengeNonce)
 	if err != nil || validHash != latestSolvedChallengeHashValue {
@@ -91,33 +132,50 @@ func Verify(previous Block, latestBlockTimestamp int64, latestSolvedChallengeNon
 	return true
 }
 
-func calculateTargetHash(previousBlock Block, transactions []t.Transaction, currentTimestampMillis int64, nonce uint32) (string, error) {
-	var allBytes []byte
-	nextIndex := previousBlock.Index + 1
-	previousHash := previousBlock.ContentHash
-
-	nonceByteBuffer := make([]byte, 4)
-	binary.LittleEndian.PutUint32(nonceByteBuffer, nonce)
-
-	nextIndexByteBuffer := make([]byte, 8)
-	binary.LittleEndian.PutUint32(nextIndexByteBuffer, uint32(nextIndex))
-
-	currentTimestampMillisBuffer := make([]byte, 8)
-	binary.LittleEndian.PutUint64(nextIndexByteBuffer, uint64(currentTimestampMillis))
+// VerifyHeader tells whether the hash of the header is the one the proof-of-work algorithm of the network gives.
+// The header commits to the transactions by their Merkle root, so it is verified without the body of the block.
+func VerifyHeader(previous Header, header Header) bool {
+	pow, err := activePow()
+	if err != nil {
+		return false
+	}
+	rest := headerPreimage(previous, header.MerkleRoot, header.TimestampMilis)
+	return powHash(pow, rest, header.Challenge.Nonce) == header.Challenge.HashValue
+}
 
-	previousHashByteBuffer := []byte(previousHash)
+func calculateTargetHash(previousBlock Block, transactions []t.Transaction, currentTimestampMillis int64, nonce uint32) (string, error) {
+	pow, err := activePow()
+	if err != nil {
+		return "", err
+	}
+	return calculateTargetHashWith(pow, previousBlock, transactions, currentTimestampMillis, nonce)
+}
 
-	var previousBlockDataBuffer bytes.Buffer
-	enc := gob.NewEncoder(&previousBlockDataBuffer)
-	if err := enc.Encode(transactions); err != nil {
+func calculateTargetHashWith(pow PowAlgorithm, previousBlock Block, transactions []t.Transaction, currentTimestampMillis int64, nonce uint32) (string, error) {
+	rest, err := targetPreimage(previousBlock, transactions, currentTimestampMillis)
+	if err != nil {
 		return "", err
 	}
+	return powHash(pow, rest, nonce), nil
+}
+
+func powHash(pow PowAlgorithm, rest []byte, nonce uint32) string {
+	preimage := make([]byte, 4, 4+len(rest))
+	binary.BigEndian.PutUint32(preimage, nonce)
+	return base64.StdEncoding.EncodeToString(pow.Hash(append(preimage, rest...)))
+}
 
-	allBytes = append(allBytes, nonceByteBuffer...)
-	allBytes = append(allBytes, nextIndexByteBuffer...)
-	allBytes = append(allBytes, previousHashByteBuffer...)
-	allBytes = append(allBytes, currentTimestampMillisBuffer...)
-	allBytes = append(allBytes, previousBlockDataBuffer.Bytes()...)
+// targetPreimage is everything the target hash is calculated from, apart from the nonce which goes in front of it:
+// the canonical encoding of the index, the previous hash, the timestamp and the Merkle root of the next block.
+func targetPreimage(previousBlock Block, transactions []t.Transaction, currentTimestampMillis int64) ([]byte, error) {
+	return headerPreimage(previousBlock.Header, MerkleRoot(transactions), currentTimestampMillis), nil
+}
 
-	return hasher.Sha256(allBytes).Base64(), nil
+func headerPreimage(previous Header, merkleRoot string, timestamp int64) []byte {
+	e := canonical.NewEncoder()
+	e.Int64(int64(previous.Index + 1))
+	e.String(previous.ContentHash)
+	e.Int64(timestamp)
+	e.String(merkleRoot)
+	return e.Encoded()
 }
@@ -2,6 +2,7 @@ package blockchain
 
 import (
 	"encoding/base64"
+	"fmt"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	"testing"
 
@@ -106,6 +107,18 @@ func TestChallengeMatchesDifficulty_shouldMatch(t *testing.T) {
 	assertThat.True(challenge.MatchesDifficulty())
 }
 
+func TestChallengeMatchesDifficulty_shouldMatchAboveOneByte(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	challangeHashStr := base64.StdEncoding.EncodeToString([]byte{0, 0x0f, 0xff})
+
+	// then
+	assertThat.True((&Challenge{Difficulty: 12, HashValue: challangeHashStr}).MatchesDifficulty())
+	assertThat.False((&Challenge{Difficulty: 13, HashValue: challangeHashStr}).MatchesDifficulty())
+}
+
 func TestChallengeMatchesDifficulty_shouldNotMatch(t *testing.T) {
 	t.Parallel()
 	assertThat := assert.New(t)
@@ -159,3 +172,51 @@ func mustNewChallenge(difficulty int) Challenge {
 	}
 	return challenge
 }
+
+func TestTry_shouldReproduceTheSolvedChallenge(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	previous := GenerateGenesisBlock()
+	solved, err := NewChallenge(8, 60)
+	assertThat.NoError(err)
+	assertThat.NoError(solved.RollUntilMatchesDifficulty(previous, nil, 120))
+
+	// when
+	tried, err := NewChallenge(8, 60)
+	assertThat.NoError(err)
+	err = tried.Try(previous, nil, 120, solved.Nonce)
+
+	// then
+	assertThat.NoError(err)
+	assertThat.Equal(solved, tried)
+	assertThat.True(tried.MatchesDifficulty())
+}
+
+func TestTarget(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		difficulty int
+		prefix     []byte
+	}{
+		{difficulty: 2, prefix: []byte{0x3f, 0xff}},
+		{difficulty: 8, prefix: []byte{0x00, 0xff}},
+		{difficulty: 9, prefix: []byte{0x00, 0x7f, 0xff}},
+		{difficulty: 20, prefix: []byte{0x00, 0x00, 0x0f, 0xff}},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("difficulty %d", tc.difficulty), func(t *testing.T) {
+			// when
+			target := Target(tc.difficulty)
+
+			// then
+			assert.Len(t, target, 32)
+			assert.Equal(t, tc.prefix, target[:len(tc.prefix)])
+			assert.True(t, hashMatchesDifficulty(target, tc.difficulty))
+			assert.False(t, hashMatchesDifficulty(target, tc.difficulty+1))
+		})
+	}
+}
@@ -1,43 +1,72 @@
 package blockchain
 
-const (
-	BlockGenerationDefaultIntervalMillis int64 = 100 * 60
-	DifficultyAdjustmentInterval               = 10
+import (
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 )
 
+// GetDifficulty is the difficulty the next block of the chain has to be mined at.
 func GetDifficulty(chain BlockChain) (int, error) {
-	latest := chain.GetLast()
-	if latest.Index != 0 && latest.Index%DifficultyAdjustmentInterval == 0 {
-		calculatedDifficulty, err := getAdjustedDifficulty(chain)
-		return calculatedDifficulty, err
-	}
-	return latest.Challenge.Difficulty, nil
+	return nextDifficulty(chain.GetLast().Header, chain.HeaderAt)
+}
+
+// DifficultyAfter is the difficulty of the block following the last of the headers.
+// The headers have to reach back to the start of the adjustment interval.
+func DifficultyAfter(headers []Header) (int, error) {
+	last := headers[len(headers)-1]
+	return nextDifficulty(last, func(index int) (Header, bool) {
+		i := len(headers) - 1 - (last.Index - index)
+		if i < 0 || i >= len(headers) {
+			return Header{}, false
+		}
+		return headers[i], true
+	})
 }
 
-func getAdjustedDifficulty(chain BlockChain) (int, error) {
-	previouslyAdjusted, err := chain.GetBlock(len(chain.Blocks) - DifficultyAdjustmentInterval)
-	if err != nil {
-		return 0, err
+// nextDifficulty retargets the difficulty at the start of every adjustment interval, based on how long the blocks
+// of the previous interval took to mine. Within an interval the blocks keep the difficulty of their parent.
+func nextDifficulty(parent Header, headerAt func(index int) (Header, bool)) (int, error) {
+	params := chaincfg.Active()
+	height := parent.Index + 1
+	if params.NoRetargeting || params.DifficultyAdjustmentInterval <= 0 || height%params.DifficultyAdjustmentInterval != 0 {
+		return parent.Challenge.Difficulty, nil
 	}
 
-	expectedTime := BlockGenerationDefaultIntervalMillis * DifficultyAdjustmentInterval
-	actualTime := chain.GetLast().TimestampMilis - previouslyAdjusted.TimestampMilis
+	// the timestamp of the genesis block says nothing about when the chain started being mined
+	start := max(height-params.DifficultyAdjustmentInterval, 1)
+	if start >= parent.Index {
+		return parent.Challenge.Difficulty, nil
+	}
+	first, ok := headerAt(start)
+	if !ok {
+		return 0, fmt.Errorf("%w: header %d is needed to retarget", BlockNotFound, start)
+	}
 
-	lower := ifThen(previouslyAdjusted.Challenge.Difficulty-1 <= 0, previouslyAdjusted.Challenge.Difficulty, previouslyAdjusted.Challenge.Difficulty-1)
-	higher := ifThen(previouslyAdjusted.Challenge.Difficulty+1 >= 256, previouslyAdjusted.Challenge.Difficulty, previouslyAdjusted.Challenge.Difficulty+1)
+	expected := params.TargetBlockTimeMillis * int64(parent.Index-start)
+	actual := parent.TimestampMilis - first.TimestampMilis
+	return retarget(parent.Challenge.Difficulty, expected, actual), nil
+}
 
-	switch {
-	case actualTime < expectedTime/2:
-		return higher, nil
-	case actualTime > expectedTime*2:
-		return lower, nil
+// retarget scales the work needed to mine a block by how much faster or slower than expected the blocks came.
+// The difficulty counts the leading zero bits of the hash, so the work is 2^difficulty and the scale is rounded
+// to the nearest power of two. A single retarget changes the work at most four times either way.
+func retarget(difficulty int, expected, actual int64) int {
+	if expected <= 0 {
+		return difficulty
 	}
-	return chain.GetLast().Challenge.Difficulty, nil
-}
+	actual = min(max(actual, 0), 4*expected)
 
-func ifThen[T any](cond bool, vtrue, vfalse T) T {
-	if cond {
-		return vtrue
+	// how long the blocks took, in 1/1024ths of the expected time
+	switch ratio := actual * 1024 / expected; {
+	case ratio < 362: // 2^-1.5
+		difficulty += 2
+	case ratio < 724: // 2^-0.5
+		difficulty++
+	case ratio > 2896: // 2^1.5
+		difficulty -= 2
+	case ratio > 1448: // 2^0.5
+		difficulty--
 	}
-	return vfalse
+	return min(max(difficulty, MinDifficulty), MaxDifficulty)
 }
@@ -2,38 +2,102 @@ package blockchain
 
 import (
 	"testing"
+	"time"
 
-	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestGetDifficultyLowered(t *testing.T) {
-	chain := computeChain(10, 3, BlockGenerationDefaultIntervalMillis*BlockGenerationDefaultIntervalMillis/20+1, t)
-	result, err := GetDifficulty(*chain)
-	assert.NoError(t, err)
-	assert.Less(t, result, 3)
-}
+func TestGetDifficulty(t *testing.T) {
+	t.Parallel()
+	target := chaincfg.Active().TargetBlockTimeMillis
+
+	tt := []struct {
+		description string
+		length      int
+		difficulty  int
+		interval    int64
+		expected    int
+	}{
+		{description: "within the adjustment interval", length: 5, difficulty: 9, interval: target / 10, expected: 9},
+		{description: "on target", length: 10, difficulty: 9, interval: target, expected: 9},
+		{description: "slightly faster than target", length: 10, difficulty: 9, interval: target * 3 / 4, expected: 9},
+		{description: "slightly slower than target", length: 10, difficulty: 9, interval: target * 4 / 3, expected: 9},
+		{description: "twice as fast", length: 10, difficulty: 9, interval: target / 2, expected: 10},
+		{description: "six times as fast", length: 10, difficulty: 9, interval: target / 6, expected: 11},
+		{description: "sixty times as fast is capped", length: 10, difficulty: 9, interval: target / 60, expected: 11},
+		{description: "twice as slow", length: 10, difficulty: 9, interval: target * 2, expected: 8},
+		{description: "ten times as slow is capped", length: 10, difficulty: 9, interval: target * 10, expected: 7},
+		{description: "not below the minimum", length: 10, difficulty: MinDifficulty, interval: target * 10, expected: MinDifficulty},
+		{description: "not above the maximum", length: 10, difficulty: MaxDifficulty, interval: target / 60, expected: MaxDifficulty},
+		{description: "second interval", length: 20, difficulty: 12, interval: target / 2, expected: 13},
+		{description: "within the second interval", length: 15, difficulty: 12, interval: target / 2, expected: 12},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			chain := simulatedChain(tc.length, tc.difficulty, tc.interval)
 
-func TestGetDifficultyUpped(t *testing.T) {
-	chain := computeChain(10, 3, BlockGenerationDefaultIntervalMillis*DifficultyAdjustmentInterval/20-1, t)
-	result, err := GetDifficulty(*chain)
-	assert.NoError(t, err)
-	assert.Greater(t, result, 3)
+			// when
+			difficulty, err := GetDifficulty(chain)
+
+			// then
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, difficulty)
+		})
+	}
 }
 
-func computeChain(elements, fixedDifficulty int, fixedTimecapOfChallenges int64, t *testing.T) *BlockChain {
-	genesisBlock := GenerateGenesisBlock()
-	timestamp := genesisBlock.TimestampMilis
-	chain, err := ImportBlockchain([]Block{genesisBlock})
+func TestAddBlock_shouldFollowRetargeting(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a chain mined much faster than the target up to the adjustment
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
 	require.NoError(t, err)
-	transactions := make([]transaction.Transaction, 0)
+	for i := int64(1); i < int64(chaincfg.Active().DifficultyAdjustmentInterval); i++ {
+		require.NoError(t, chain.AddBlock(mineOn(t, chain.Blocks, genesis.TimestampMilis+i*100)))
+	}
+	difficulty, err := GetDifficulty(*chain)
+	require.NoError(t, err)
+	require.Equal(t, genesis.Challenge.Difficulty+2, difficulty)
+
+	// when a block keeps the old difficulty
+	timestamp := chain.GetLast().TimestampMilis + 100
+	stale := genesis.Challenge
+	require.NoError(t, stale.RollUntilMatchesDifficulty(chain.GetLast(), nil, timestamp))
+	staleBlock, err := chain.NewBlock(timestamp, nil, stale)
+	require.NoError(t, err)
+
+	// then
+	assertThat.Equal(BlockNotValid, chain.AddBlock(staleBlock))
+
+	// when the block is mined at the retargeted difficulty
+	retargeted := mineOn(t, chain.Blocks, timestamp)
+
+	// then
+	assertThat.NoError(chain.AddBlock(retargeted))
+	assertThat.Equal(difficulty, retargeted.Challenge.Difficulty)
+	_, err = ImportBlockchain(chain.Blocks)
+	assertThat.NoError(err)
+}
 
-	for i := 1; i < elements+1; i++ {
-		challenge, _ := NewChallenge(fixedDifficulty, fixedTimecapOfChallenges)
-		_ = challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp+int64(i)*fixedTimecapOfChallenges)
-		block, _ := chain.NewBlock(timestamp+int64(i)*fixedTimecapOfChallenges, transactions, challenge)
-		_ = chain.AddBlock(block)
+// simulatedChain builds the headers of a chain without mining it, with the blocks coming at the given interval.
+// The first block comes a day after the genesis block, like on a chain started long after its genesis.
+func simulatedChain(length, difficulty int, interval int64) BlockChain {
+	genesis := GenerateGenesisBlock()
+	blocks := []Block{genesis}
+	timestamp := genesis.TimestampMilis + (24 * time.Hour).Milliseconds()
+	for i := 1; i < length; i++ {
+		blocks = append(blocks, Block{Header: Header{
+			Index:          i,
+			TimestampMilis: timestamp,
+			Challenge:      Challenge{Difficulty: difficulty},
+		}})
+		timestamp += interval
 	}
-	return chain
+	return BlockChain{Blocks: blocks}
 }
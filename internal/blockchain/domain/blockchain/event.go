@@ -3,3 +3,10 @@ package blockchain
 type NewBlockAddedEvent struct {
 	Block Block
 }
+
+// ChainReorganizedEvent is emitted when the node switches its main chain to a heavier branch.
+// Disconnected blocks are ordered from the lowest one, same as the connected ones.
+type ChainReorganizedEvent struct {
+	Disconnected []Block
+	Connected    []Block
+}
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	BlockAlreadyKnown  = errors.New("block already known")
+	ParentNotFound     = errors.New("parent block not found")
+	BranchNotConnected = errors.New("branch does not connect to the chain")
+	BranchNotHeavier   = errors.New("branch is not heavier than the chain")
+)
+
+// MainChain is what the side branches read of the main chain, so that it does not have to be held in memory.
+type MainChain interface {
+	GetBlockByHash(hash string) (Block, error)
+	HeaderAt(index int) (Header, bool)
+}
+
+// SideBranches keeps valid blocks that do not extend the tip of the main chain,
+// so the node can switch to them once they accumulate more work.
+type SideBranches struct {
+	blocks map[string]Block
+	rw     sync.RWMutex
+}
+
+func NewSideBranches() *SideBranches {
+	return &SideBranches{
+		blocks: make(map[string]Block),
+	}
+}
+
+// Add validates the block against its parent, found either on the main chain or on one of
+// the side branches, and stores it.
+func (s *SideBranches) Add(chain MainChain, block Block) error {
+	if _, err := chain.GetBlockByHash(block.ContentHash); err == nil {
+		return BlockAlreadyKnown
+	}
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if _, ok := s.blocks[block.ContentHash]; ok {
+		return BlockAlreadyKnown
+	}
+
+	parent, err := chain.GetBlockByHash(block.PrevHash)
+	if err != nil {
+		var ok bool
+		parent, ok = s.blocks[block.PrevHash]
+		if !ok {
+			return ParentNotFound
+		}
+	}
+
+	difficulty, err := nextDifficulty(parent.Header, func(index int) (Header, bool) {
+		return s.ancestorAt(chain, parent, index)
+	})
+	if err != nil || !isValidBasedOnPrevious(block, parent, difficulty) {
+		return BlockNotValid
+	}
+
+	s.blocks[block.ContentHash] = block
+	return nil
+}
+
+// ancestorAt finds the header at the given height on the branch of the block, walking back through the side blocks
+// until it reaches the main chain. The caller holds the lock.
+func (s *SideBranches) ancestorAt(chain MainChain, block Block, index int) (Header, bool) {
+	for block.Index > index {
+		parent, ok := s.blocks[block.PrevHash]
+		if !ok {
+			// the rest of the branch is on the main chain
+			if _, err := chain.GetBlockByHash(block.PrevHash); err != nil {
+				return Header{}, false
+			}
+			return chain.HeaderAt(index)
+		}
+		block = parent
+	}
+	return block.Header, block.Index == index
+}
+
+func (s *SideBranches) Get(hash string) (Block, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	block, ok := s.blocks[hash]
+	if !ok {
+		return Block{}, BlockNotFound
+	}
+	return block, nil
+}
+
+func (s *SideBranches) Remove(blocks ...Block) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for _, block := range blocks {
+		delete(s.blocks, block.ContentHash)
+	}
+}
+
+// BranchTo walks back from the given tip through the side blocks until it reaches the main chain.
+// The returned blocks are ordered from the fork point towards the tip.
+func (s *SideBranches) BranchTo(chain MainChain, tip Block) ([]Block, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	branch := []Block{tip}
+	for {
+		first := branch[0]
+		if _, err := chain.GetBlockByHash(first.PrevHash); err == nil {
+			return branch, nil
+		}
+		parent, ok := s.blocks[first.PrevHash]
+		if !ok {
+			return nil, BranchNotConnected
+		}
+		branch = append([]Block{parent}, branch...)
+	}
+}
+
+// Reorganize replaces the blocks after the fork point with the given branch, as long as the branch is valid
+// and has a higher cumulative difficulty than the current chain. The first block of the branch must point
+// to a block on the chain. The blocks disconnected from the main chain are returned, starting from the lowest one.
+func (chain *BlockChain) Reorganize(branch []Block) ([]Block, error) {
+	if len(branch) == 0 {
+		return nil, BranchNotConnected
+	}
+
+	fork, err := chain.GetBlockByHash(branch[0].PrevHash)
+	if err != nil {
+		return nil, BranchNotConnected
+	}
+
+	candidate := &BlockChain{
+		Blocks: append([]Block{}, chain.Blocks[:fork.Index+1]...),
+	}
+	for _, block := range branch {
+		if err := candidate.AddBlock(block); err != nil {
+			return nil, err
+		}
+	}
+
+	if candidate.GetCumulativeDifficulty().Cmp(chain.GetCumulativeDifficulty()) <= 0 {
+		return nil, BranchNotHeavier
+	}
+
+	disconnected := append([]Block{}, chain.Blocks[fork.Index+1:]...)
+	chain.RemoveBlocksStartingWithIndex(fork.Index + 1)
+	chain.Blocks = append(chain.Blocks, branch...)
+
+	return disconnected, nil
+}
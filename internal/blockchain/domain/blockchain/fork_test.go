@@ -0,0 +1,195 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorganize_shouldSwitchToHeavierBranch(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a chain with one block
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	mainBlock := mineOn(t, chain.Blocks, genesis.TimestampMilis+100)
+	require.NoError(t, chain.AddBlock(mainBlock))
+
+	// and a competing branch with more work
+	sideOne := mineOn(t, []Block{genesis}, genesis.TimestampMilis+200)
+	sideTwo := mineOn(t, []Block{genesis, sideOne}, genesis.TimestampMilis+300)
+	side := NewSideBranches()
+	require.NoError(t, side.Add(chain, sideOne))
+	require.NoError(t, side.Add(chain, sideTwo))
+
+	// when
+	branch, err := side.BranchTo(chain, sideTwo)
+	require.NoError(t, err)
+	disconnected, err := chain.Reorganize(branch)
+
+	// then
+	assertThat.NoError(err)
+	assertThat.Equal([]Block{mainBlock}, disconnected)
+	assertThat.Equal([]Block{genesis, sideOne, sideTwo}, chain.Blocks)
+}
+
+func TestReorganize_shouldKeepChainWhenBranchIsNotHeavier(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a chain with two blocks
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	require.NoError(t, chain.AddBlock(mineOn(t, chain.Blocks, genesis.TimestampMilis+100)))
+	require.NoError(t, chain.AddBlock(mineOn(t, chain.Blocks, genesis.TimestampMilis+200)))
+	before := append([]Block{}, chain.Blocks...)
+
+	// and a competing branch with the same work
+	sideOne := mineOn(t, []Block{genesis}, genesis.TimestampMilis+300)
+	sideTwo := mineOn(t, []Block{genesis, sideOne}, genesis.TimestampMilis+400)
+
+	// when
+	disconnected, err := chain.Reorganize([]Block{sideOne, sideTwo})
+
+	// then
+	assertThat.Equal(BranchNotHeavier, err)
+	assertThat.Nil(disconnected)
+	assertThat.Equal(before, chain.Blocks)
+}
+
+func TestReorganize_shouldNotAcceptInvalidBranch(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	// and a branch with a tampered block
+	sideOne := mineOn(t, []Block{genesis}, genesis.TimestampMilis+100)
+	sideOne.ContentHash = "tampered"
+
+	// when
+	_, err = chain.Reorganize([]Block{sideOne})
+
+	// then
+	assertThat.Error(err)
+	assertThat.Equal([]Block{genesis}, chain.Blocks)
+}
+
+func TestSideBranches_Add_shouldError(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	sideOne := mineOn(t, []Block{genesis}, genesis.TimestampMilis+100)
+	orphan := mineOn(t, []Block{genesis, sideOne}, genesis.TimestampMilis+200)
+	side := NewSideBranches()
+
+	// when adding a block without a known parent
+	err = side.Add(chain, orphan)
+	// then
+	assertThat.Equal(ParentNotFound, err)
+
+	// when adding a block already on the chain
+	err = side.Add(chain, genesis)
+	// then
+	assertThat.Equal(BlockAlreadyKnown, err)
+
+	// when adding a block twice
+	assertThat.NoError(side.Add(chain, sideOne))
+	err = side.Add(chain, sideOne)
+	// then
+	assertThat.Equal(BlockAlreadyKnown, err)
+}
+
+func TestSideBranches_Add_shouldRetargetAlongTheBranch(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a chain just before the adjustment
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	interval := chaincfg.Active().DifficultyAdjustmentInterval
+	for i := 1; i < interval; i++ {
+		require.NoError(t, chain.AddBlock(mineOn(t, chain.Blocks, genesis.TimestampMilis+int64(i)*100)))
+	}
+
+	// and a side branch forking halfway, mined slower than the main chain
+	fork := interval / 2
+	branch := append([]Block{}, chain.Blocks[:fork+1]...)
+	side := NewSideBranches()
+	for i := fork + 1; i < interval; i++ {
+		block := mineOn(t, branch, branch[len(branch)-1].TimestampMilis+chaincfg.Active().TargetBlockTimeMillis*2)
+		require.NoError(t, side.Add(chain, block))
+		branch = append(branch, block)
+	}
+	expected, err := GetDifficulty(BlockChain{Blocks: branch})
+	require.NoError(t, err)
+	mainExpected, err := GetDifficulty(*chain)
+	require.NoError(t, err)
+	require.NotEqual(t, mainExpected, expected)
+
+	// when a block follows the main chain's difficulty
+	timestamp := branch[len(branch)-1].TimestampMilis + 100
+	wrong, err := NewChallenge(mainExpected, 2)
+	require.NoError(t, err)
+	require.NoError(t, wrong.RollUntilMatchesDifficulty(branch[len(branch)-1], nil, timestamp))
+	wrongBlock, err := (&BlockChain{Blocks: branch}).NewBlock(timestamp, nil, wrong)
+	require.NoError(t, err)
+
+	// then
+	assertThat.Equal(BlockNotValid, side.Add(chain, wrongBlock))
+
+	// when the block follows the branch's difficulty
+	block := mineOn(t, branch, timestamp)
+
+	// then
+	assertThat.NoError(side.Add(chain, block))
+	assertThat.Equal(expected, block.Challenge.Difficulty)
+}
+
+func TestRemoveBlocksStartingWithIndex(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	first := mineOn(t, chain.Blocks, genesis.TimestampMilis+100)
+	require.NoError(t, chain.AddBlock(first))
+	require.NoError(t, chain.AddBlock(mineOn(t, chain.Blocks, genesis.TimestampMilis+200)))
+
+	// when
+	chain.RemoveBlocksStartingWithIndex(2)
+
+	// then
+	assertThat.Equal([]Block{genesis, first}, chain.Blocks)
+}
+
+func mineOn(t *testing.T, blocks []Block, timestamp int64) Block {
+	t.Helper()
+	chain := &BlockChain{Blocks: blocks}
+	transactions := make([]transaction.Transaction, 0)
+
+	difficulty, err := GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := NewChallenge(difficulty, 2)
+	require.NoError(t, err)
+	require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	return block
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+)
+
+var HeadersNotValid = errors.New("headers are not valid")
+
+// ValidateHeaders checks that the headers form a chain on top of the ancestors and that each of them
+// carries a challenge solved at the difficulty expected at its height. The ancestors have to reach back
+// to the start of the adjustment interval. The headers are checked by the same rules as the blocks, apart from
+// the Merkle root, which is only matched against the transactions once the bodies are known.
+func ValidateHeaders(ancestors []Header, headers []Header) error {
+	if len(ancestors) == 0 {
+		return fmt.Errorf("%w: no headers to build on", HeadersNotValid)
+	}
+
+	all := append(slices.Clip(ancestors), headers...)
+	for i := len(ancestors); i < len(all); i++ {
+		previous, header := all[i-1], all[i]
+		difficulty, err := DifficultyAfter(all[:i])
+		if err != nil {
+			return fmt.Errorf("%w: %w", HeadersNotValid, err)
+		}
+		if !isValidHeaderBasedOnPrevious(header, previous, difficulty) {
+			return fmt.Errorf("%w: header %d does not follow header %d", HeadersNotValid, header.Index, previous.Index)
+		}
+	}
+	return nil
+}
+
+// MarshalCanonical writes the part of the header the content hash is calculated from, followed by the content hash.
+func (h Header) MarshalCanonical(e *canonical.Encoder) {
+	h.marshalHashed(e)
+	e.String(h.ContentHash)
+}
+
+func (h *Header) UnmarshalCanonical(d *canonical.Decoder) {
+	h.Index = int(d.Int64())
+	h.TimestampMilis = d.Int64()
+	h.PrevHash = d.String()
+	h.MerkleRoot = d.String()
+	h.Challenge.UnmarshalCanonical(d)
+	h.ContentHash = d.String()
+}
+
+func (h Header) marshalHashed(e *canonical.Encoder) {
+	e.Int64(int64(h.Index))
+	e.Int64(h.TimestampMilis)
+	e.String(h.PrevHash)
+	e.String(h.MerkleRoot)
+	h.Challenge.MarshalCanonical(e)
+}
+
+// GetCumulativeDifficulty of the headers, calculated the same way as for the whole chain.
+func GetCumulativeDifficulty(headers []Header) *big.Int {
+	sum := new(big.Int)
+	for _, header := range headers {
+		sum.Add(sum, work(header.Challenge.Difficulty))
+	}
+	return sum
+}
+
+func isValidHeaderBasedOnPrevious(header Header, previous Header, difficulty int) bool {
+	return header.Index == previous.Index+1 && header.PrevHash == previous.ContentHash &&
+		header.ContentHash == HashHeader(header) &&
+		header.Challenge.Difficulty == difficulty && header.Challenge.MatchesDifficulty() &&
+		blockCreatedAfterPreviousWithinTimeCap(header.TimestampMilis, header.Challenge, previous) &&
+		VerifyHeader(previous, header)
+}
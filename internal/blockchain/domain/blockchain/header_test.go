@@ -0,0 +1,87 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHeaders(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	first := mineOn(t, chain.Blocks, genesis.TimestampMilis+100)
+	require.NoError(t, chain.AddBlock(first))
+
+	// then
+	assertThat.Equal([]Header{genesis.Header, first.Header}, chain.GetHeaders(0, 10))
+	assertThat.Equal([]Header{first.Header}, chain.GetHeaders(1, 1))
+	assertThat.Empty(chain.GetHeaders(2, 10))
+	assertThat.Empty(chain.GetHeaders(-1, 10))
+}
+
+func TestValidateHeaders(t *testing.T) {
+	t.Parallel()
+
+	genesis := GenerateGenesisBlock()
+	first := mineOn(t, []Block{genesis}, genesis.TimestampMilis+100)
+	second := mineOn(t, []Block{genesis, first}, genesis.TimestampMilis+200)
+
+	unsolved := second.Header
+	unsolved.Challenge.HashValue = "//////////////////////////////////////////8="
+	unsolved.Challenge.Difficulty = 16
+
+	easier := second.Header
+	easier.Challenge.Difficulty = MinDifficulty
+
+	tooEarly := second.Header
+	tooEarly.TimestampMilis = first.TimestampMilis
+
+	otherTransactions := second.Header
+	otherTransactions.MerkleRoot = genesis.MerkleRoot
+	otherTransactions.ContentHash = HashHeader(otherTransactions)
+
+	claimedHash := second.Header
+	claimedHash.Challenge.HashValue = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	claimedHash.ContentHash = HashHeader(claimedHash)
+
+	wrongContentHash := second.Header
+	wrongContentHash.ContentHash = first.ContentHash
+
+	tt := []struct {
+		description string
+		headers     []Header
+		valid       bool
+	}{
+		{description: "linked headers", headers: []Header{first.Header, second.Header}, valid: true},
+		{description: "no headers", headers: []Header{}, valid: true},
+		{description: "gap in headers", headers: []Header{second.Header}},
+		{description: "wrong order", headers: []Header{second.Header, first.Header}},
+		{description: "challenge not solved", headers: []Header{first.Header, unsolved}},
+		{description: "difficulty not expected", headers: []Header{first.Header, easier}},
+		{description: "created before time cap", headers: []Header{first.Header, tooEarly}},
+		{description: "Merkle root not solved for", headers: []Header{first.Header, otherTransactions}},
+		{description: "hash not given by the proof-of-work", headers: []Header{first.Header, claimedHash}},
+		{description: "content hash not of the header", headers: []Header{first.Header, wrongContentHash}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			err := ValidateHeaders([]Header{genesis.Header}, tc.headers)
+
+			// then
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, HeadersNotValid))
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+var TransactionNotInBlock = errors.New("transaction not in block")
+
+// The leaves and the inner nodes are hashed with different prefixes, so that an inner node cannot pass for a leaf.
+const (
+	merkleLeafPrefix byte = 0
+	merkleNodePrefix byte = 1
+)
+
+// MerkleStep is a sibling met on the way from a transaction up to the Merkle root.
+type MerkleStep struct {
+	Hash string
+	// Left tells whether the sibling is hashed before the node, rather than after it
+	Left bool
+}
+
+// MerkleProof shows that a transaction is in a block, given only the header of the block.
+type MerkleProof struct {
+	TransactionID transaction.ID
+	Steps         []MerkleStep
+}
+
+// MerkleRoot of the transactions. The leaves are the hashes of the transaction IDs, in the order of the block,
+// and every node above them hashes its two children. A node left without a pair is carried up as it is.
+func MerkleRoot(transactions []transaction.Transaction) string {
+	levels := merkleLevels(transactions)
+	return base64.StdEncoding.EncodeToString(levels[len(levels)-1][0])
+}
+
+// NewMerkleProof proves that the transaction with the given ID is one of the transactions.
+func NewMerkleProof(transactions []transaction.Transaction, id transaction.ID) (MerkleProof, error) {
+	index := -1
+	for i, tx := range transactions {
+		if tx.ID() == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return MerkleProof{}, TransactionNotInBlock
+	}
+
+	proof := MerkleProof{TransactionID: id, Steps: []MerkleStep{}}
+	levels := merkleLevels(transactions)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof.Steps = append(proof.Steps, MerkleStep{
+				Hash: base64.StdEncoding.EncodeToString(level[sibling]),
+				Left: sibling < index,
+			})
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof tells whether the proof leads from its transaction up to the Merkle root of the header.
+func VerifyMerkleProof(header Header, proof MerkleProof) bool {
+	node := merkleLeaf(proof.TransactionID)
+	for _, step := range proof.Steps {
+		sibling, err := base64.StdEncoding.DecodeString(step.Hash)
+		if err != nil || len(sibling) != sha256.Size {
+			return false
+		}
+		if step.Left {
+			node = merkleNode(sibling, node)
+		} else {
+			node = merkleNode(node, sibling)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(node) == header.MerkleRoot
+}
+
+// merkleLevels of the tree, starting with the leaves and ending with the root. With no transactions the root
+// is the hash of nothing.
+func merkleLevels(transactions []transaction.Transaction) [][][]byte {
+	if len(transactions) == 0 {
+		empty := sha256.Sum256(nil)
+		return [][][]byte{{empty[:]}}
+	}
+
+	level := make([][]byte, len(transactions))
+	for i, tx := range transactions {
+		level[i] = merkleLeaf(tx.ID())
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+func merkleLeaf(id transaction.ID) []byte {
+	hash := sha256.Sum256(append([]byte{merkleLeafPrefix}, id...))
+	return hash[:]
+}
+
+func merkleNode(left, right []byte) []byte {
+	preimage := make([]byte, 0, 1+len(left)+len(right))
+	preimage = append(preimage, merkleNodePrefix)
+	preimage = append(preimage, left...)
+	preimage = append(preimage, right...)
+	hash := sha256.Sum256(preimage)
+	return hash[:]
+}
@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactions(t *testing.T, count int) []transaction.Transaction {
+	transactions := make([]transaction.Transaction, count)
+	for i := range transactions {
+		tx, err := transaction.NewFrom(nil, []*transaction.Output{transaction.NewOutput(i+1, "address")})
+		require.NoError(t, err)
+		transactions[i] = *tx
+	}
+	return transactions
+}
+
+func TestMerkleRoot(t *testing.T) {
+	t.Parallel()
+	txs := newTransactions(t, 3)
+	leaf := func(i int) []byte {
+		hash := sha256.Sum256(append([]byte{0}, txs[i].ID()...))
+		return hash[:]
+	}
+	node := func(left, right []byte) []byte {
+		hash := sha256.Sum256(append(append([]byte{1}, left...), right...))
+		return hash[:]
+	}
+	empty := sha256.Sum256(nil)
+
+	tt := []struct {
+		description string
+		txs         []transaction.Transaction
+		expected    []byte
+	}{
+		{description: "no transactions", txs: nil, expected: empty[:]},
+		{description: "one transaction", txs: txs[:1], expected: leaf(0)},
+		{description: "two transactions", txs: txs[:2], expected: node(leaf(0), leaf(1))},
+		{description: "odd transaction carried up", txs: txs, expected: node(node(leaf(0), leaf(1)), leaf(2))},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			root := MerkleRoot(tc.txs)
+
+			// then
+			assert.Equal(t, base64.StdEncoding.EncodeToString(tc.expected), root)
+		})
+	}
+}
+
+func TestNewMerkleProof_shouldBeVerifiedForEveryTransaction(t *testing.T) {
+	t.Parallel()
+
+	for count := 1; count <= 9; count++ {
+		txs := newTransactions(t, count)
+		header := Header{MerkleRoot: MerkleRoot(txs)}
+
+		for i, tx := range txs {
+			// when
+			proof, err := NewMerkleProof(txs, tx.ID())
+
+			// then
+			require.NoError(t, err)
+			assert.True(t, VerifyMerkleProof(header, proof), fmt.Sprintf("transaction %d of %d", i, count))
+		}
+	}
+}
+
+func TestVerifyMerkleProof_shouldRejectTamperedProof(t *testing.T) {
+	t.Parallel()
+
+	// given
+	txs := newTransactions(t, 5)
+	header := Header{MerkleRoot: MerkleRoot(txs)}
+	proof, err := NewMerkleProof(txs, txs[2].ID())
+	require.NoError(t, err)
+	other := newTransactions(t, 6)[5]
+
+	tt := []struct {
+		description string
+		tamper      func(MerkleProof) MerkleProof
+	}{
+		{description: "other transaction", tamper: func(p MerkleProof) MerkleProof {
+			p.TransactionID = other.ID()
+			return p
+		}},
+		{description: "swapped side", tamper: func(p MerkleProof) MerkleProof {
+			p.Steps = append([]MerkleStep{}, p.Steps...)
+			p.Steps[0].Left = !p.Steps[0].Left
+			return p
+		}},
+		{description: "missing step", tamper: func(p MerkleProof) MerkleProof {
+			p.Steps = p.Steps[1:]
+			return p
+		}},
+		{description: "malformed hash", tamper: func(p MerkleProof) MerkleProof {
+			p.Steps = append([]MerkleStep{}, p.Steps...)
+			p.Steps[0].Hash = "not base64"
+			return p
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Parallel()
+
+			// when
+			verified := VerifyMerkleProof(header, tc.tamper(proof))
+
+			// then
+			assert.False(t, verified)
+		})
+	}
+}
+
+func TestNewMerkleProof_shouldErrorWhenTransactionNotInBlock(t *testing.T) {
+	t.Parallel()
+
+	// given
+	txs := newTransactions(t, 3)
+
+	// when
+	_, err := NewMerkleProof(txs[:2], txs[2].ID())
+
+	// then
+	assert.ErrorIs(t, err, TransactionNotInBlock)
+}
+
+func TestImportBlockchain_shouldRejectBlockWithWrongMerkleRoot(t *testing.T) {
+	t.Parallel()
+
+	// given a block whose header does not commit to its transactions
+	genesis := GenerateGenesisBlock()
+	tampered := genesis
+	tampered.Transactions = newTransactions(t, 1)
+
+	// when
+	_, err := ImportBlockchain([]Block{tampered})
+
+	// then
+	assert.ErrorIs(t, err, ChainNotValid)
+}
@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	PowSha256       = "sha256"
+	PowDoubleSha256 = "double-sha256"
+	PowScrypt       = "scrypt"
+	PowArgon2id     = "argon2id"
+)
+
+var UnknownPowAlgorithm = errors.New("unknown proof-of-work algorithm")
+
+// powSalt is the same for every hash, the preimage is unique enough as it starts with the nonce.
+var powSalt = []byte("eecoin proof of work")
+
+// PowAlgorithm hashes the preimage of a block challenge: the nonce followed by everything the block commits to.
+// The challenge is solved when the hash has as many leading zero bits as the difficulty.
+type PowAlgorithm interface {
+	Name() string
+	Hash(preimage []byte) []byte
+}
+
+// PowAlgorithmByName finds the algorithm, the name being one of the Pow constants.
+func PowAlgorithmByName(name string) (PowAlgorithm, error) {
+	switch name {
+	case PowSha256:
+		return sha256Pow{}, nil
+	case PowDoubleSha256:
+		return doubleSha256Pow{}, nil
+	case PowScrypt:
+		return scryptPow{}, nil
+	case PowArgon2id:
+		return argon2idPow{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", UnknownPowAlgorithm, name)
+}
+
+// activePow is the algorithm the blocks of the network the node follows are mined with.
+func activePow() (PowAlgorithm, error) {
+	return PowAlgorithmByName(chaincfg.Active().PowAlgorithm)
+}
+
+type sha256Pow struct{}
+
+func (sha256Pow) Name() string { return PowSha256 }
+
+func (sha256Pow) Hash(preimage []byte) []byte {
+	hash := sha256.Sum256(preimage)
+	return hash[:]
+}
+
+type doubleSha256Pow struct{}
+
+func (doubleSha256Pow) Name() string { return PowDoubleSha256 }
+
+func (doubleSha256Pow) Hash(preimage []byte) []byte {
+	first := sha256.Sum256(preimage)
+	hash := sha256.Sum256(first[:])
+	return hash[:]
+}
+
+// scryptPow takes 128 KiB of memory per hash, like the scrypt coins mined with GPUs before ASICs came.
+type scryptPow struct{}
+
+func (scryptPow) Name() string { return PowScrypt }
+
+func (scryptPow) Hash(preimage []byte) []byte {
+	// the parameters are valid, so scrypt cannot fail
+	hash, _ := scrypt.Key(preimage, powSalt, 1024, 1, 1, sha256.Size)
+	return hash
+}
+
+// argon2idPow takes 4 MiB of memory per hash, which keeps the GPUs from running many of them side by side.
+type argon2idPow struct{}
+
+func (argon2idPow) Name() string { return PowArgon2id }
+
+func (argon2idPow) Hash(preimage []byte) []byte {
+	return argon2.IDKey(preimage, powSalt, 1, 4*1024, 1, sha256.Size)
+}
@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPowAlgorithmByName(t *testing.T) {
+	t.Parallel()
+	preimage := []byte("nonce and block")
+	sha := sha256.Sum256(preimage)
+	hashes := make(map[string]string)
+
+	for _, name := range []string{PowSha256, PowDoubleSha256, PowScrypt, PowArgon2id} {
+		// when
+		pow, err := PowAlgorithmByName(name)
+
+		// then
+		require.NoError(t, err, name)
+		assert.Equal(t, name, pow.Name())
+		hash := pow.Hash(preimage)
+		assert.Len(t, hash, 32, name)
+		assert.Equal(t, hash, pow.Hash(preimage), name)
+		assert.NotContains(t, hashes, string(hash), name)
+		hashes[string(hash)] = name
+		if name == PowSha256 {
+			assert.Equal(t, sha[:], hash)
+		}
+	}
+
+	// when
+	_, err := PowAlgorithmByName("md5")
+
+	// then
+	assert.ErrorIs(t, err, UnknownPowAlgorithm)
+}
+
+func TestPowAlgorithmByName_shouldKnowEveryNetwork(t *testing.T) {
+	t.Parallel()
+
+	for _, params := range []chaincfg.ChainParams{chaincfg.MainNet, chaincfg.TestNet, chaincfg.RegTest} {
+		_, err := PowAlgorithmByName(params.PowAlgorithm)
+		assert.NoError(t, err, params.Name)
+	}
+}
+
+func TestVerify_shouldRequireThePowAlgorithmOfTheNetwork(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a block hashed with another algorithm than the one of the network
+	previous := GenerateGenesisBlock()
+	other, err := PowAlgorithmByName(PowDoubleSha256)
+	require.NoError(t, err)
+	otherHash, err := calculateTargetHashWith(other, previous, nil, 120, 7)
+	require.NoError(t, err)
+	active, err := activePow()
+	require.NoError(t, err)
+	activeHash, err := calculateTargetHashWith(active, previous, nil, 120, 7)
+	require.NoError(t, err)
+
+	// when
+	verified := Verify(previous, 120, 7, otherHash, nil)
+
+	// then
+	assertThat.False(verified)
+	assertThat.True(Verify(previous, 120, 7, activeHash, nil))
+}
@@ -0,0 +1,100 @@
+package blockchain
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	t "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+var ShareWindowNotValid = errors.New("share window not valid, it must hold at least one share")
+
+// Share is a solution of a block challenge at the lower difficulty of a mining pool, proving the work of a worker
+// even though it does not solve the block.
+type Share struct {
+	Worker     string
+	Difficulty int
+}
+
+// work is how many hashes it takes on average to find the share.
+func (s Share) work() float64 {
+	return math.Ldexp(1, s.Difficulty)
+}
+
+// ShareWindow holds the last N shares of a mining pool, which the reward of the next block found is split over.
+type ShareWindow struct {
+	size   int
+	shares []Share
+}
+
+func NewShareWindow(size int) (*ShareWindow, error) {
+	if size < 1 {
+		return nil, ShareWindowNotValid
+	}
+	return &ShareWindow{size: size, shares: make([]Share, 0, size)}, nil
+}
+
+// Add the share to the window, pushing the oldest one out when full.
+func (w *ShareWindow) Add(share Share) {
+	if len(w.shares) == w.size {
+		copy(w.shares, w.shares[1:])
+		w.shares = w.shares[:len(w.shares)-1]
+	}
+	w.shares = append(w.shares, share)
+}
+
+// Shares in the window, from the oldest.
+func (w *ShareWindow) Shares() []Share {
+	shares := make([]Share, len(w.shares))
+	copy(shares, w.shares)
+	return shares
+}
+
+// Clone the window, so that it can be split later on while the shares keep coming.
+func (w *ShareWindow) Clone() *ShareWindow {
+	shares := make([]Share, len(w.shares), w.size)
+	copy(shares, w.shares)
+	return &ShareWindow{size: w.size, shares: shares}
+}
+
+// Split the reward between the workers proportionally to the work of their shares in the window (PPLNS).
+// The outputs are ordered by the worker address and the amount lost to rounding goes to the worker with the most
+// work, so that the same window always gives the same coinbase. Workers whose part rounds down to nothing are left
+// out. An empty window gives no outputs.
+func (w *ShareWindow) Split(reward int) []*t.Output {
+	work := make(map[string]float64)
+	total := 0.0
+	for _, share := range w.shares {
+		work[share.Worker] += share.work()
+		total += share.work()
+	}
+	if total == 0 {
+		return nil
+	}
+
+	workers := make([]string, 0, len(work))
+	for worker := range work {
+		workers = append(workers, worker)
+	}
+	sort.Strings(workers)
+
+	amounts := make(map[string]int, len(workers))
+	top, paid := workers[0], 0
+	for _, worker := range workers {
+		amounts[worker] = int(math.Floor(float64(reward) * work[worker] / total))
+		paid += amounts[worker]
+		if work[worker] > work[top] {
+			top = worker
+		}
+	}
+	amounts[top] += reward - paid
+
+	outputs := make([]*t.Output, 0, len(workers))
+	for _, worker := range workers {
+		if amounts[worker] > 0 {
+			outputs = append(outputs, t.NewOutput(amounts[worker], worker))
+		}
+	}
+	return outputs
+}
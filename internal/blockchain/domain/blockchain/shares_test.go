@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewShareWindow_shouldRequireRoom(t *testing.T) {
+	t.Parallel()
+
+	// when
+	_, err := NewShareWindow(0)
+
+	// then
+	assert.ErrorIs(t, err, ShareWindowNotValid)
+}
+
+func TestShareWindow_Split(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		description string
+		size        int
+		shares      []Share
+		reward      int
+		expected    []*transaction.Output
+	}{
+		{
+			description: "empty window",
+			size:        10,
+			reward:      100,
+		},
+		{
+			description: "single worker",
+			size:        10,
+			shares:      []Share{{Worker: "alice", Difficulty: 6}, {Worker: "alice", Difficulty: 6}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(100, "alice")},
+		},
+		{
+			description: "proportional to the shares",
+			size:        10,
+			shares:      []Share{{Worker: "bob", Difficulty: 6}, {Worker: "alice", Difficulty: 6}, {Worker: "bob", Difficulty: 6}, {Worker: "bob", Difficulty: 6}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(25, "alice"), transaction.NewOutput(75, "bob")},
+		},
+		{
+			description: "weighted by the share difficulty",
+			size:        10,
+			shares:      []Share{{Worker: "alice", Difficulty: 7}, {Worker: "bob", Difficulty: 6}, {Worker: "bob", Difficulty: 6}, {Worker: "carol", Difficulty: 8}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(25, "alice"), transaction.NewOutput(25, "bob"), transaction.NewOutput(50, "carol")},
+		},
+		{
+			description: "rounding goes to the most work",
+			size:        10,
+			shares:      []Share{{Worker: "alice", Difficulty: 6}, {Worker: "bob", Difficulty: 6}, {Worker: "bob", Difficulty: 6}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(33, "alice"), transaction.NewOutput(67, "bob")},
+		},
+		{
+			description: "only the last shares",
+			size:        2,
+			shares:      []Share{{Worker: "alice", Difficulty: 6}, {Worker: "bob", Difficulty: 6}, {Worker: "carol", Difficulty: 6}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(50, "bob"), transaction.NewOutput(50, "carol")},
+		},
+		{
+			description: "nothing left for too little work",
+			size:        10,
+			shares:      []Share{{Worker: "alice", Difficulty: 2}, {Worker: "bob", Difficulty: 12}},
+			reward:      100,
+			expected:    []*transaction.Output{transaction.NewOutput(100, "bob")},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			window, err := NewShareWindow(tc.size)
+			require.NoError(t, err)
+			for _, share := range tc.shares {
+				window.Add(share)
+			}
+
+			// when
+			outputs := window.Split(tc.reward)
+
+			// then
+			assert.Equal(t, tc.expected, outputs)
+			assert.Equal(t, min(len(tc.shares), tc.size), len(window.Shares()))
+		})
+	}
+}
+
+func TestShareWindow_Clone_shouldNotFollowTheWindow(t *testing.T) {
+	t.Parallel()
+
+	// given
+	window, err := NewShareWindow(2)
+	require.NoError(t, err)
+	window.Add(Share{Worker: "alice", Difficulty: 6})
+	clone := window.Clone()
+
+	// when
+	window.Add(Share{Worker: "bob", Difficulty: 6})
+	window.Add(Share{Worker: "bob", Difficulty: 6})
+
+	// then
+	assert.Equal(t, []*transaction.Output{transaction.NewOutput(10, "alice")}, clone.Split(10))
+}
@@ -0,0 +1,82 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	t "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// solveBatch is how many nonces a worker tries between checking for cancellation and counting its hashes.
+const solveBatch = 1024
+
+// Solve searches for a nonce solving the challenge for the block with the given transactions and timestamp,
+// hashing with the proof-of-work algorithm of the network.
+// The nonce space is split between the workers, each of them trying every workers-th nonce. It stops once one
+// of the workers finds a solution, when the nonces run out or when the context gets cancelled.
+// The computed hashes are added to the counter, so that the caller can tell the hashrate while the search goes on.
+func (c *Challenge) Solve(ctx context.Context, workers int, previousBlock Block, transactions []t.Transaction, timestamp int64, hashes *atomic.Uint64) (bool, error) {
+	pow, err := activePow()
+	if err != nil {
+		return false, err
+	}
+	rest, err := targetPreimage(previousBlock, transactions, timestamp)
+	if err != nil {
+		return false, err
+	}
+	workers = max(workers, 1)
+
+	search, stop := context.WithCancel(ctx)
+	defer stop()
+
+	solutions := make(chan Challenge, workers)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(first uint64) {
+			defer wg.Done()
+			preimage := make([]byte, 4+len(rest))
+			copy(preimage[4:], rest)
+
+			var counted uint64
+			defer func() { hashes.Add(counted) }()
+			for nonce := first; nonce <= math.MaxUint32; nonce += uint64(workers) {
+				if counted == solveBatch {
+					hashes.Add(counted)
+					counted = 0
+					if search.Err() != nil {
+						return
+					}
+				}
+
+				binary.BigEndian.PutUint32(preimage, uint32(nonce))
+				hash := pow.Hash(preimage)
+				counted++
+				if hashMatchesDifficulty(hash, c.Difficulty) {
+					solved := *c
+					solved.Nonce = uint32(nonce)
+					solved.HashValue = base64.StdEncoding.EncodeToString(hash)
+					solutions <- solved
+					stop()
+					return
+				}
+			}
+		}(uint64(worker))
+	}
+	wg.Wait()
+
+	select {
+	case solved := <-solutions:
+		*c = solved
+		return true, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeSolve(t *testing.T) {
+	t.Parallel()
+
+	genesis := GenerateGenesisBlock()
+	coinbase, err := transaction.NewCoinbase("miner", 1)
+	require.NoError(t, err)
+	transactions := []transaction.Transaction{*coinbase}
+	timestamp := genesis.TimestampMilis + 100
+
+	for _, workers := range []int{0, 1, 4} {
+		// given
+		challenge, err := NewChallenge(12, 2)
+		require.NoError(t, err)
+		var hashes atomic.Uint64
+
+		// when
+		solved, err := challenge.Solve(context.Background(), workers, genesis, transactions, timestamp, &hashes)
+
+		// then
+		assert.NoError(t, err)
+		assert.True(t, solved)
+		assert.True(t, challenge.MatchesDifficulty())
+		assert.True(t, Verify(genesis, timestamp, challenge.Nonce, challenge.HashValue, transactions))
+		assert.NotZero(t, hashes.Load())
+	}
+}
+
+func TestChallengeSolve_shouldStopWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	// given
+	genesis := GenerateGenesisBlock()
+	challenge, err := NewChallenge(MaxDifficulty, 2)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var hashes atomic.Uint64
+
+	// when
+	solved, err := challenge.Solve(ctx, 4, genesis, nil, genesis.TimestampMilis+100, &hashes)
+
+	// then
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, solved)
+	assert.Empty(t, challenge.HashValue)
+}
@@ -0,0 +1,40 @@
+package blockchain
+
+import (
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// Supply of the coins on the chain. Issued counts everything minted so far, while Circulating counts only
+// the unspent outputs, so it leaves out the fees nobody claimed.
+type Supply struct {
+	Issued      int
+	Circulating int
+	Maximum     int
+}
+
+// GetSupply scans the whole chain to work out the supply.
+func (chain *BlockChain) GetSupply() Supply {
+	supply := Supply{
+		Maximum: transaction.ActiveSubsidy().MaxSupply(chaincfg.Active().GenesisAmount),
+	}
+
+	for _, block := range chain.Blocks {
+		if block.Index == 0 {
+			for _, tx := range block.Transactions {
+				for _, output := range tx.Outputs() {
+					supply.Issued += output.Amount()
+				}
+			}
+			continue
+		}
+		if len(block.Transactions) > 0 && block.Transactions[0].IsCoinbase() {
+			supply.Issued += transaction.ActiveSubsidy().At(block.Index)
+		}
+	}
+
+	for _, output := range transaction.UnspentOutputsFrom(transactionsOf(chain.Blocks)) {
+		supply.Circulating += output.Amount()
+	}
+	return supply
+}
@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSupply(t *testing.T) {
+	t.Parallel()
+	assertThat := assert.New(t)
+
+	// given a chain with a block which burns part of the genesis output as an unclaimed fee
+	genesis := GenerateGenesisBlock()
+	chain, err := ImportBlockchain([]Block{genesis})
+	require.NoError(t, err)
+	coinbase, err := transaction.NewCoinbase("miner", 1)
+	require.NoError(t, err)
+	burning, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(genesis.Transactions[0].ID(), 0, "")},
+		[]*transaction.Output{transaction.NewOutput(chaincfg.Active().GenesisAmount-10, "someone")},
+	)
+	require.NoError(t, err)
+	block := mineWithTransactions(t, chain.Blocks, genesis.TimestampMilis+100, *coinbase, *burning)
+	require.NoError(t, chain.AddBlock(block))
+
+	// when
+	supply := chain.GetSupply()
+
+	// then
+	assertThat.Equal(chaincfg.Active().GenesisAmount+transaction.ActiveSubsidy().At(1), supply.Issued)
+	assertThat.Equal(chaincfg.Active().GenesisAmount+transaction.ActiveSubsidy().At(1)-10, supply.Circulating)
+	assertThat.Equal(transaction.ActiveSubsidy().MaxSupply(chaincfg.Active().GenesisAmount), supply.Maximum)
+}
+
+func mineWithTransactions(t *testing.T, blocks []Block, timestamp int64, transactions ...transaction.Transaction) Block {
+	t.Helper()
+	chain := &BlockChain{Blocks: blocks}
+
+	difficulty, err := GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := NewChallenge(difficulty, 2)
+	require.NoError(t, err)
+	require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	return block
+}
@@ -0,0 +1,31 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+var TransactionsNotValid = errors.New("block transactions are not valid")
+
+// ValidateTransactions checks the transactions of consecutive blocks, the first of which extends the block
+// the given outputs are unspent at.
+func ValidateTransactions(unspentAtParent transaction.UnspentOutputRepository, blocks ...Block) error {
+	unspent := transaction.NewUnspentView(unspentAtParent)
+	for _, block := range blocks {
+		if err := transaction.ValidateBlockTransactions(block.Transactions, unspent, block.Index); err != nil {
+			return fmt.Errorf("%w: block %d: %w", TransactionsNotValid, block.Index, err)
+		}
+		unspent.Connect(block.Transactions)
+	}
+	return nil
+}
+
+func transactionsOf(blocks []Block) []transaction.Transaction {
+	transactions := make([]transaction.Transaction, 0, len(blocks))
+	for _, block := range blocks {
+		transactions = append(transactions, block.Transactions...)
+	}
+	return transactions
+}
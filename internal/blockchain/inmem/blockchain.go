@@ -1,15 +1,27 @@
 package inmem
 
 import (
+	"fmt"
+	"log/slog"
+	"sync"
+
 	"github.com/patrykferenc/eecoin/internal/blockchain/inmem/persistence"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
 	"github.com/patrykferenc/eecoin/internal/common/event"
 )
 
+// BlockChain keeps the main chain in memory, along with the outputs it leaves unspent and the undo data of every block,
+// so that the transactions of a new block are validated against the outputs unspent at its parent.
 type BlockChain struct {
 	chain     *blockchain.BlockChain
-	publisher event.Publisher // TODO#30 - we will refactor this class and send the event from the command handler
+	side      *blockchain.SideBranches
+	unspent   *transactioninmem.UnspentOutputRepository
+	undo      []transaction.Undo // by the height of the block
+	publisher event.Publisher    // TODO#30 - we will refactor this class and send the event from the command handler
+	rw        sync.RWMutex
 }
 
 func NewBlockChain(publisher event.Publisher) (*BlockChain, error) {
@@ -18,24 +30,191 @@ func NewBlockChain(publisher event.Publisher) (*BlockChain, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &BlockChain{
-		chain:     ch,
-		publisher: publisher,
-	}, nil
+	b, err := newBlockChain(ch)
+	if err != nil {
+		return nil, err
+	}
+	b.publisher = publisher
+	return b, nil
 }
 
-func LoadPersistedBlockchain(path string) (*BlockChain, error) {
-	ch, err := persistence.Load(path)
+// LoadPersistedBlockchain loads the newest valid snapshot of the chain, see persistence.LoadNewest.
+func LoadPersistedBlockchain(path string, backups int) (*BlockChain, error) {
+	ch, err := persistence.LoadNewest(path, backups)
 	if err != nil {
 		return nil, err
 	}
-	return &BlockChain{chain: ch}, nil
+	return newBlockChain(ch)
+}
+
+// newBlockChain connects the blocks of the chain one by one, to work out the unspent outputs and the undo data.
+func newBlockChain(ch *blockchain.BlockChain) (*BlockChain, error) {
+	b := &BlockChain{
+		chain:   ch,
+		side:    blockchain.NewSideBranches(),
+		unspent: transactioninmem.NewUnspentOutputRepository(),
+	}
+	for _, block := range ch.Blocks {
+		undo, err := transaction.NewUndo(block.Transactions, b.unspent)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting block %d: %w", block.Index, err)
+		}
+		b.connect(undo)
+	}
+	return b, nil
 }
 
-func (b *BlockChain) GetChain() blockchain.BlockChain {
-	return *b.chain
+func (b *BlockChain) GetChain() (blockchain.BlockChain, error) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	return *b.chain, nil
+}
+
+func (b *BlockChain) GetTip() (blockchain.Block, error) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	return b.chain.GetLast(), nil
+}
+
+func (b *BlockChain) GetBlock(hash string) (blockchain.Block, error) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	return b.chain.GetBlockByHash(hash)
+}
+
+func (b *BlockChain) GetHeaders(from int, count int) ([]blockchain.Header, error) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	return b.chain.GetHeaders(from, count), nil
+}
+
+// UnspentAt disconnects the blocks of the main chain above the fork point of the branch ending with the block,
+// using their undo data, then connects the blocks of the branch.
+func (b *BlockChain) UnspentAt(hash string) (transaction.UnspentOutputRepository, error) {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	fork, branch, err := b.forkOf(hash)
+	if err != nil {
+		return nil, err
+	}
+	view := transaction.NewUnspentView(b.unspent)
+	for i := len(b.undo) - 1; i >= fork; i-- {
+		view.Disconnect(b.undo[i])
+	}
+	for _, block := range branch {
+		view.Connect(block.Transactions)
+	}
+	return view, nil
+}
+
+// forkOf returns the height of the first block of the main chain which is not on the branch ending with the block
+// with the given hash, and the blocks of the branch from there on. The caller holds the lock.
+func (b *BlockChain) forkOf(hash string) (int, []blockchain.Block, error) {
+	if block, err := b.chain.GetBlockByHash(hash); err == nil {
+		return block.Index + 1, nil, nil
+	}
+
+	tip, err := b.side.Get(hash)
+	if err != nil {
+		return 0, nil, err
+	}
+	branch, err := b.side.BranchTo(b.chain, tip)
+	if err != nil {
+		return 0, nil, err
+	}
+	return branch[0].Index, branch, nil
 }
 
 func (b *BlockChain) PutBlock(block blockchain.Block) error {
-	return b.chain.AddBlock(block)
+	b.rw.Lock()
+	defer b.rw.Unlock()
+
+	undo, err := transaction.NewUndo(block.Transactions, b.unspent)
+	if err != nil {
+		return fmt.Errorf("error connecting block %d: %w", block.Index, err)
+	}
+	if err := b.chain.AddBlock(block); err != nil {
+		return err
+	}
+	b.connect(undo)
+	return nil
+}
+
+func (b *BlockChain) PutSideBlock(block blockchain.Block) error {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	return b.side.Add(b.chain, block)
+}
+
+func (b *BlockChain) Reorganize(tip blockchain.Block) ([]blockchain.Block, []blockchain.Block, error) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+
+	branch, err := b.side.BranchTo(b.chain, tip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the candidate shares the blocks up to the fork point, the branch is appended to a copy of them
+	candidate := &blockchain.BlockChain{Blocks: b.chain.Blocks}
+	disconnected, err := candidate.Reorganize(branch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the undo data of the branch is worked out before anything changes, so that the chain and the unspent outputs
+	// are left as they are when the branch spends an output which is not unspent
+	fork := branch[0].Index
+	view := transaction.NewUnspentView(b.unspent)
+	for i := len(b.undo) - 1; i >= fork; i-- {
+		view.Disconnect(b.undo[i])
+	}
+	connected := make([]transaction.Undo, 0, len(branch))
+	for _, block := range branch {
+		undo, err := transaction.NewUndo(block.Transactions, view)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error connecting block %d: %w", block.Index, err)
+		}
+		view.Connect(block.Transactions)
+		connected = append(connected, undo)
+	}
+
+	b.chain = candidate
+	for len(b.undo) > fork {
+		b.disconnect()
+	}
+	for _, undo := range connected {
+		b.connect(undo)
+	}
+
+	b.side.Remove(branch...)
+	for _, block := range disconnected {
+		if err := b.side.Add(b.chain, block); err != nil {
+			slog.Warn("could not keep disconnected block on a side branch", "hash", block.ContentHash, "error", err)
+		}
+	}
+
+	return disconnected, branch, nil
+}
+
+// connect applies the undo data of the block on top of the main chain to the unspent outputs. The caller holds the lock.
+func (b *BlockChain) connect(undo transaction.Undo) {
+	_ = b.unspent.Remove(undo.Spent...)
+	_ = b.unspent.Add(undo.Created...)
+	b.undo = append(b.undo, undo)
+}
+
+// disconnect reverts the change the block at the tip of the main chain made to the unspent outputs.
+// The caller holds the lock.
+func (b *BlockChain) disconnect() {
+	last := b.undo[len(b.undo)-1]
+	_ = b.unspent.Remove(last.Created...)
+	_ = b.unspent.Add(last.Spent...)
+	b.undo = b.undo[:len(b.undo)-1]
 }
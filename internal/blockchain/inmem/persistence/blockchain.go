@@ -1,13 +1,21 @@
 package persistence
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 
 	bc "github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
+// LegacyChain is a chain persisted as JSON by the earlier versions. Its transaction IDs, block hashes and signatures
+// are not the ones of the canonical encoding, and only the owners of the keys could sign it again, so no peer would
+// accept it. It has to be synced from the peers instead, so the node refuses to start on it rather than write the
+// synced chain over it.
+var LegacyChain = errors.New("chain persisted as JSON by an earlier version, remove it to sync the chain from the peers")
+
 type ChainDto struct {
 	Blocks []blockDTO `json:"blocks"`
 }
@@ -34,12 +42,15 @@ func MapToActual(chain ChainDto) (bc.BlockChain, error) {
 		}
 
 		dtoBlocks[i] = bc.Block{
-			Index:          block.Index,
-			TimestampMilis: block.TimestampMilis,
-			ContentHash:    block.ContentHash,
-			PrevHash:       block.PrevHash,
-			Transactions:   transactions,
-			Challenge:      challengeDTOToModel(block.Challange),
+			Header: bc.Header{
+				Index:          block.Index,
+				TimestampMilis: block.TimestampMilis,
+				ContentHash:    block.ContentHash,
+				PrevHash:       block.PrevHash,
+				MerkleRoot:     block.MerkleRoot,
+				Challenge:      challengeDTOToModel(block.Challange),
+			},
+			Transactions: transactions,
 		}
 	}
 	output, err := bc.ImportBlockchain(dtoBlocks)
@@ -49,32 +60,40 @@ func MapToActual(chain ChainDto) (bc.BlockChain, error) {
 	return *output, nil
 }
 
-func Persist(chain bc.BlockChain, path string) error {
-	mappedToDto := MapToDto(chain)
-	b, err := json.Marshal(mappedToDto)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, b, 0600)
+// Persist writes the chain in the canonical encoding, behind a header with its checksum. The previous snapshots are
+// kept as backups, the newest as path.1, up to the given number of them.
+func Persist(chain bc.BlockChain, path string, backups int) error {
+	return writeSnapshot(path, backups, canonical.Marshal(chain))
 }
 
+// Load reads the chain written by Persist. A chain persisted without the header by the earlier versions is still read,
+// one persisted as JSON is not, see LegacyChain.
 func Load(path string) (*bc.BlockChain, error) {
 	persistedContent, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if hasHeader(persistedContent) {
+		persistedContent, err = verifyHeader(persistedContent)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
 
-	blockchainDto := ChainDto{}
-	err = json.Unmarshal(persistedContent, &blockchainDto)
-	if err != nil {
-		return nil, err
+	if isLegacy(persistedContent) {
+		return nil, fmt.Errorf("%s: %w", path, LegacyChain)
 	}
 
-	blockchain, err := MapToActual(blockchainDto)
-	if err != nil {
+	var persisted bc.BlockChain
+	if err := canonical.Unmarshal(persistedContent, &persisted); err != nil {
 		return nil, err
 	}
-	return &blockchain, nil
+	return bc.ImportBlockchain(persisted.Blocks)
+}
+
+// isLegacy tells the JSON chain files apart, they are an object while the canonical encoding starts with its version.
+func isLegacy(content []byte) bool {
+	return len(content) > 0 && content[0] == '{'
 }
 
 type blockDTO struct {
@@ -82,6 +101,7 @@ type blockDTO struct {
 	TimestampMilis int64            `json:"timestamp"`
 	ContentHash    string           `json:"content_hash"`
 	PrevHash       string           `json:"prev_hash"`
+	MerkleRoot     string           `json:"merkle_root"`
 	Transactions   []transactionDTO `json:"transactions"` // TODO#30
 	Challange      challengeDTO     `json:"challenge"`
 }
@@ -97,6 +117,7 @@ func asDTO(block bc.Block) blockDTO {
 		TimestampMilis: block.TimestampMilis,
 		ContentHash:    block.ContentHash,
 		PrevHash:       block.PrevHash,
+		MerkleRoot:     block.MerkleRoot,
 		Transactions:   transactions,
 		Challange:      challengeModelToDTO(block.Challenge),
 	}
@@ -122,9 +143,10 @@ func (o outputDTO) asOutput() *transaction.Output {
 }
 
 type transactionDTO struct {
-	ID      string      `json:"id"`
-	Inputs  []inputDTO  `json:"inputs"`
-	Outputs []outputDTO `json:"outputs"`
+	ID          string      `json:"id"`
+	Inputs      []inputDTO  `json:"inputs"`
+	Outputs     []outputDTO `json:"outputs"`
+	Replaceable bool        `json:"replaceable,omitempty"`
 }
 
 func transDTO(tx transaction.Transaction) transactionDTO {
@@ -146,9 +168,10 @@ func transDTO(tx transaction.Transaction) transactionDTO {
 	}
 
 	return transactionDTO{
-		ID:      tx.ID().String(),
-		Inputs:  inputs,
-		Outputs: outputs,
+		ID:          tx.ID().String(),
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Replaceable: tx.Replaceable(),
 	}
 }
 
@@ -163,6 +186,9 @@ func asModel(dto transactionDTO) (*transaction.Transaction, error) {
 		outputs[i] = out.asOutput()
 	}
 
+	if dto.Replaceable {
+		return transaction.NewReplaceableFrom(inputs, outputs)
+	}
 	return transaction.NewFrom(inputs, outputs)
 }
 
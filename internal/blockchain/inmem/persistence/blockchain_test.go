@@ -1,9 +1,13 @@
 package persistence
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
 	"testing"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +22,7 @@ func TestPersistBlockChain(t *testing.T) {
 	require.NoError(t, err)
 
 	// when - then
-	saveErr := Persist(*chain, dir)
+	saveErr := Persist(*chain, dir, 0)
 	assertThat.Nil(saveErr)
 
 	// when - then
@@ -29,3 +33,42 @@ func TestPersistBlockChain(t *testing.T) {
 	assertThat.Equal(loaded.GetFirst(), chain.GetFirst())
 	assertThat.Equal(loaded.GetLast(), chain.GetLast())
 }
+
+func TestPersistBlockChain_shouldWriteTheCanonicalEncoding(t *testing.T) {
+	// given
+	path := t.TempDir() + "/chain"
+	chain, err := blockchain.ImportBlockchain([]blockchain.Block{blockchain.GenerateGenesisBlock()})
+	require.NoError(t, err)
+
+	// when
+	err = Persist(*chain, path, 0)
+
+	// then the canonical encoding follows the header
+	require.NoError(t, err)
+	persisted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	encoded := canonical.Marshal(*chain)
+	checksum := sha256.Sum256(encoded)
+	assert.Equal(t, append(append([]byte("EECHAIN\x01"), checksum[:]...), encoded...), persisted)
+}
+
+func TestLoad_shouldRefuseTheLegacyChain(t *testing.T) {
+	// given a chain persisted as JSON
+	genesis := blockchain.GenerateGenesisBlock()
+	content, err := json.Marshal(ChainDto{Blocks: []blockDTO{asDTO(genesis)}})
+	require.NoError(t, err)
+	path := t.TempDir() + "/chain"
+	require.NoError(t, os.WriteFile(path, content, 0600))
+
+	// when
+	_, err = Load(path)
+
+	// then
+	assert.ErrorIs(t, err, LegacyChain)
+
+	// and when it is the newest snapshot
+	_, err = LoadNewest(path, 0)
+
+	// then it is not taken for a missing or broken one
+	assert.ErrorIs(t, err, LegacyChain)
+}
@@ -0,0 +1,152 @@
+package persistence
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	bc "github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+// fileVersion is the version of the header, the chain behind it has its own version in the canonical encoding.
+const fileVersion byte = 1
+
+// magic starts every chain file written with a header.
+var magic = []byte("EECHAIN")
+
+var (
+	UnsupportedFileVersion = errors.New("chain file version not supported")
+	ChecksumMismatch       = errors.New("chain file checksum does not match its content")
+	NoValidSnapshot        = errors.New("no valid chain snapshot")
+)
+
+// headerSize is the magic, the version and the sha256 checksum of the content.
+var headerSize = len(magic) + 1 + sha256.Size
+
+func hasHeader(content []byte) bool {
+	return bytes.HasPrefix(content, magic)
+}
+
+// verifyHeader checks the version and the checksum in the header and returns the content behind it.
+func verifyHeader(file []byte) ([]byte, error) {
+	if len(file) < headerSize {
+		return nil, fmt.Errorf("%w: header truncated", ChecksumMismatch)
+	}
+	if version := file[len(magic)]; version != fileVersion {
+		return nil, fmt.Errorf("%w: %d", UnsupportedFileVersion, version)
+	}
+	checksum := file[len(magic)+1 : headerSize]
+	content := file[headerSize:]
+	if sum := sha256.Sum256(content); !bytes.Equal(sum[:], checksum) {
+		return nil, ChecksumMismatch
+	}
+	return content, nil
+}
+
+func withHeader(content []byte) []byte {
+	sum := sha256.Sum256(content)
+	file := make([]byte, 0, headerSize+len(content))
+	file = append(file, magic...)
+	file = append(file, fileVersion)
+	file = append(file, sum[:]...)
+	return append(file, content...)
+}
+
+// writeSnapshot writes the content to a temporary file next to the path and syncs it, so that it is complete on the
+// disk before it is renamed over the path. The snapshot it replaces is shifted into the backups first.
+// A crash at any point leaves either the old or the new snapshot, or a temporary file which is never read.
+func writeSnapshot(path string, backups int, content []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(withHeader(content)); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+
+	if err := rotateBackups(path, backups); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// rotateBackups shifts every snapshot one place back, path.1 becoming path.2 and the path itself becoming path.1.
+// The oldest backup falls out.
+func rotateBackups(path string, backups int) error {
+	for i := backups; i > 0; i-- {
+		from := backupPath(path, i-1)
+		if err := os.Rename(from, backupPath(path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupPath of the snapshot the given number of writes back, the path itself being the newest one.
+func backupPath(path string, age int) string {
+	if age == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, age)
+}
+
+// syncDir makes the renames in the directory durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// LoadNewest loads the newest valid snapshot: the path itself, then its backups from the newest one. The snapshots
+// which cannot be read or are not valid are skipped. It fails with os.ErrNotExist when there is no snapshot at all,
+// and with LegacyChain as soon as a snapshot was persisted as JSON, as the chain in it could not be replaced anyway.
+func LoadNewest(path string, backups int) (*bc.BlockChain, error) {
+	found := false
+	for age := 0; age <= backups; age++ {
+		snapshot := backupPath(path, age)
+		chain, err := Load(snapshot)
+		if err == nil {
+			if age > 0 {
+				slog.Warn("loaded chain from a backup", "path", snapshot, "height", len(chain.Blocks)-1)
+			}
+			return chain, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if errors.Is(err, LegacyChain) {
+			return nil, err
+		}
+		found = true
+		slog.Error("chain snapshot not valid", "path", snapshot, "error", err)
+	}
+
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	return nil, NoValidSnapshot
+}
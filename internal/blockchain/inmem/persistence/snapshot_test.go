@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersist_shouldKeepBackupsOfThePreviousSnapshots(t *testing.T) {
+	assertThat := assert.New(t)
+
+	// given
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chain")
+	chain := genesisChain(t)
+
+	// when
+	for range 4 {
+		require.NoError(t, Persist(*chain, path, 2))
+	}
+
+	// then only the snapshot and two backups are left, without temporary files
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	assertThat.Equal([]string{"chain", "chain.1", "chain.2"}, names)
+}
+
+func TestLoadNewest_shouldSkipSnapshotsWhichAreNotValid(t *testing.T) {
+	assertThat := assert.New(t)
+
+	// given two snapshots, the newest of which got corrupted
+	path := filepath.Join(t.TempDir(), "chain")
+	older := genesisChain(t)
+	require.NoError(t, Persist(*older, path, 3))
+	newer := genesisChain(t)
+	mineEmpty(t, newer)
+	require.NoError(t, Persist(*newer, path, 3))
+	corrupt(t, path)
+
+	// when
+	_, loadErr := Load(path)
+	loaded, err := LoadNewest(path, 3)
+
+	// then the backup is loaded
+	assertThat.ErrorIs(loadErr, ChecksumMismatch)
+	require.NoError(t, err)
+	assertThat.Equal(older.Blocks, loaded.Blocks)
+
+	// when the backup got corrupted as well
+	corrupt(t, path+".1")
+	_, err = LoadNewest(path, 3)
+
+	// then
+	assertThat.ErrorIs(err, NoValidSnapshot)
+}
+
+func TestLoadNewest_shouldTellThereIsNoSnapshot(t *testing.T) {
+	// when
+	_, err := LoadNewest(filepath.Join(t.TempDir(), "chain"), 3)
+
+	// then
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLoad_shouldReadTheCanonicalEncodingWithoutHeader(t *testing.T) {
+	// given a chain written before the header was added
+	path := filepath.Join(t.TempDir(), "chain")
+	chain := genesisChain(t)
+	require.NoError(t, os.WriteFile(path, canonical.Marshal(*chain), 0600))
+
+	// when
+	loaded, err := Load(path)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, chain.Blocks, loaded.Blocks)
+}
+
+func TestLoad_shouldRejectUnsupportedFileVersion(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "chain")
+	require.NoError(t, Persist(*genesisChain(t), path, 0))
+	persisted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	persisted[len(magic)] = fileVersion + 1
+	require.NoError(t, os.WriteFile(path, persisted, 0600))
+
+	// when
+	_, err = Load(path)
+
+	// then
+	assert.ErrorIs(t, err, UnsupportedFileVersion)
+}
+
+func genesisChain(t *testing.T) *blockchain.BlockChain {
+	t.Helper()
+	chain, err := blockchain.ImportBlockchain([]blockchain.Block{blockchain.GenerateGenesisBlock()})
+	require.NoError(t, err)
+	return chain
+}
+
+func mineEmpty(t *testing.T, chain *blockchain.BlockChain) {
+	t.Helper()
+	difficulty, err := blockchain.GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := blockchain.NewChallenge(difficulty, 1)
+	require.NoError(t, err)
+	timestamp := chain.GetLast().TimestampMilis + 1000
+	solved, err := challenge.Solve(context.Background(), 1, chain.GetLast(), []transaction.Transaction{}, timestamp, new(atomic.Uint64))
+	require.NoError(t, err)
+	require.True(t, solved)
+	block, err := chain.NewBlock(timestamp, []transaction.Transaction{}, challenge)
+	require.NoError(t, err)
+	require.NoError(t, chain.AddBlock(block))
+}
+
+// corrupt flips the last byte of the file, as a write cut short by a crash would leave it different.
+func corrupt(t *testing.T, path string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content[len(content)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, content, 0600))
+}
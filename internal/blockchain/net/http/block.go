@@ -2,44 +2,46 @@ package http
 
 import (
 	"encoding/json"
-	t "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"errors"
 	"log/slog"
 	"net/http"
+	neturl "net/url"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/patrykferenc/eecoin/internal/blockchain/command"
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
 )
 
+const blocksURL = "/blocks"
+
 func postBlock(addBlockHandler command.AddBlockHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var dto blockDTO
-
-		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-			slog.Warn("failed to decode block JSON", "error", err)
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
-			return
-		}
+		var block blockchain.Block
+		if isCanonical(r.Header) {
+			if err := readCanonical(r.Body, &block); err != nil {
+				slog.Warn("failed to decode canonical block", "error", err)
+				http.Error(w, "invalid canonical body", http.StatusBadRequest)
+				return
+			}
+		} else {
+			var dto blockDTO
+			if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+				slog.Warn("failed to decode block JSON", "error", err)
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
 
-		transactions := make([]t.Transaction, len(dto.Transactions)) // TODO#30
-		for i, tx := range dto.Transactions {
-			translated, err := asModel(tx)
+			var err error
+			block, err = dto.asBlock()
 			if err != nil {
 				slog.Warn("failed to decode transaction", "error", err)
 				http.Error(w, "invalid body or faulty decoding method", http.StatusInternalServerError)
 				return
 			}
-			transactions[i] = *translated
 		}
 
-		if err := addBlockHandler.Handle(command.AddBlock{
-			ToAdd: blockchain.Block{
-				Index:          dto.Index,
-				TimestampMilis: dto.TimestampMilis,
-				ContentHash:    dto.ContentHash,
-				PrevHash:       dto.PrevHash,
-				Transactions:   transactions,
-			},
-		}); err != nil {
+		if err := addBlockHandler.Handle(command.AddBlock{ToAdd: block}); err != nil {
 			slog.Warn("failed to add block to chain", "error", err)
 			http.Error(w, "failed to add block to chain", http.StatusInternalServerError)
 			return
@@ -49,3 +51,33 @@ func postBlock(addBlockHandler command.AddBlockHandler) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+func getBlock(getBlockQuery query.GetBlock) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hash, err := neturl.PathUnescape(chi.URLParam(r, "hash"))
+		if err != nil {
+			http.Error(w, "invalid block hash", http.StatusBadRequest)
+			return
+		}
+
+		block, err := getBlockQuery.Get(hash)
+		if errors.Is(err, blockchain.BlockNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to get block", "hash", hash, "error", err)
+			http.Error(w, "failed to get block", http.StatusInternalServerError)
+			return
+		}
+
+		if acceptsCanonical(r) {
+			writeCanonical(w, block)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(asDTO(block)); err != nil {
+			slog.Error("failed to encode block", "error", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+)
+
+// BlockClient downloads headers and single blocks from peers.
+type BlockClient struct {
+	client http.Client
+}
+
+func NewBlockClient() *BlockClient {
+	return &BlockClient{
+		client: http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *BlockClient) GetHeaders(peer string, from int, count int) ([]blockchain.Header, error) {
+	query := neturl.Values{}
+	query.Set("from", fmt.Sprint(from))
+	query.Set("count", fmt.Sprint(count))
+
+	var dto headersDTO
+	if err := c.get(peer+headersURL+"?"+query.Encode(), &dto); err != nil {
+		return nil, fmt.Errorf("failed to get headers from %s: %w", peer, err)
+	}
+
+	headers := make([]blockchain.Header, len(dto.Headers))
+	for i, header := range dto.Headers {
+		headers[i] = header.asHeader()
+	}
+	return headers, nil
+}
+
+// GetBlock asks for the block in the canonical encoding, the peers which only know JSON answer with it instead.
+func (c *BlockClient) GetBlock(peer string, hash string) (blockchain.Block, error) {
+	resp, err := c.do(peer+blocksURL+"/"+neturl.PathEscape(hash), canonical.MediaType+", application/json")
+	if err != nil {
+		return blockchain.Block{}, fmt.Errorf("failed to get block %s from %s: %w", hash, peer, err)
+	}
+	defer resp.Body.Close()
+
+	if isCanonical(resp.Header) {
+		var block blockchain.Block
+		if err := readCanonical(resp.Body, &block); err != nil {
+			return blockchain.Block{}, fmt.Errorf("failed to decode block %s from %s: %w", hash, peer, err)
+		}
+		return block, nil
+	}
+
+	var dto blockDTO
+	if err := json.NewDecoder(resp.Body).Decode(&dto); err != nil {
+		return blockchain.Block{}, fmt.Errorf("failed to decode block %s from %s: %w", hash, peer, err)
+	}
+	block, err := dto.asBlock()
+	if err != nil {
+		return blockchain.Block{}, fmt.Errorf("failed to decode block %s from %s: %w", hash, peer, err)
+	}
+	return block, nil
+}
+
+// GetUnspentProofs downloads the unspent outputs of the address, each with the proof that it is in a block.
+// The proofs are only decoded here, they still have to be verified against the headers.
+func (c *BlockClient) GetUnspentProofs(peer string, address string) ([]query.UnspentProof, error) {
+	var dto unspentProofsDTO
+	if err := c.get(peer+"/address/"+neturl.PathEscape(address)+"/proofs", &dto); err != nil {
+		return nil, fmt.Errorf("failed to get unspent proofs from %s: %w", peer, err)
+	}
+
+	proofs := make([]query.UnspentProof, len(dto.Proofs))
+	for i, proof := range dto.Proofs {
+		decoded, err := proof.asUnspentProof()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode unspent proof from %s: %w", peer, err)
+		}
+		proofs[i] = decoded
+	}
+	return proofs, nil
+}
+
+func (c *BlockClient) get(target string, dto any) error {
+	resp, err := c.do(target, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(dto)
+}
+
+func (c *BlockClient) do(target string, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+	return resp, nil
+}
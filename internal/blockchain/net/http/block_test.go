@@ -56,11 +56,13 @@ func TestBlockHandler_HandleBlockPost(t *testing.T) {
 		Transactions:   []transactionDTO{sampleTransactionDTO},
 	}
 	mockBlock := blockchain.Block{
-		Index:          mockBlockDTO.Index,
-		TimestampMilis: mockBlockDTO.TimestampMilis,
-		ContentHash:    mockBlockDTO.ContentHash,
-		PrevHash:       mockBlockDTO.PrevHash,
-		Transactions:   []transaction.Transaction{*sampleTransaction},
+		Header: blockchain.Header{
+			Index:          mockBlockDTO.Index,
+			TimestampMilis: mockBlockDTO.TimestampMilis,
+			ContentHash:    mockBlockDTO.ContentHash,
+			PrevHash:       mockBlockDTO.PrevHash,
+		},
+		Transactions: []transaction.Transaction{*sampleTransaction},
 	}
 
 	t.Run("Successful Block Post", func(t *testing.T) {
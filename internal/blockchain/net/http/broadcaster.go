@@ -2,7 +2,6 @@ package http
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,6 +9,7 @@ import (
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 )
 
 var url = "/block"
@@ -25,8 +25,7 @@ func NewBroadcaster() *Broadcaster {
 func (b *Broadcaster) Broadcast(block blockchain.Block, peers []string) error {
 	errors := make(chan error, len(peers))
 
-	dto := asDTO(block)
-	body, err := json.Marshal(dto)
+	body, err := block.MarshalBinary()
 	if err != nil {
 		return fmt.Errorf("could not marshal block: %w", err)
 	}
@@ -38,6 +37,7 @@ func (b *Broadcaster) Broadcast(block blockchain.Block, peers []string) error {
 				errors <- err
 				return
 			}
+			req.Header.Set("Content-Type", canonical.MediaType)
 
 			_, err = b.client.Do(req)
 			if err != nil {
@@ -65,6 +65,7 @@ type blockDTO struct {
 	TimestampMilis int64            `json:"timestamp"`
 	ContentHash    string           `json:"content_hash"`
 	PrevHash       string           `json:"prev_hash"`
+	MerkleRoot     string           `json:"merkle_root"`
 	Transactions   []transactionDTO `json:"transactions"` // TODO#30
 	Challenge      challengeDTO     `json:"challenge"`
 }
@@ -80,11 +81,35 @@ func asDTO(block blockchain.Block) blockDTO {
 		TimestampMilis: block.TimestampMilis,
 		ContentHash:    block.ContentHash,
 		PrevHash:       block.PrevHash,
+		MerkleRoot:     block.MerkleRoot,
 		Transactions:   transactions,
 		Challenge:      challengeModelToDTO(block.Challenge),
 	}
 }
 
+func (dto blockDTO) asBlock() (blockchain.Block, error) {
+	transactions := make([]transaction.Transaction, len(dto.Transactions)) // TODO#30
+	for i, tx := range dto.Transactions {
+		translated, err := asModel(tx)
+		if err != nil {
+			return blockchain.Block{}, err
+		}
+		transactions[i] = *translated
+	}
+
+	return blockchain.Block{
+		Header: blockchain.Header{
+			Index:          dto.Index,
+			TimestampMilis: dto.TimestampMilis,
+			ContentHash:    dto.ContentHash,
+			PrevHash:       dto.PrevHash,
+			MerkleRoot:     dto.MerkleRoot,
+			Challenge:      dto.Challenge.asChallenge(),
+		},
+		Transactions: transactions,
+	}, nil
+}
+
 type inputDTO struct {
 	OutputID    string `json:"output_id"`
 	OutputIndex int    `json:"output_index"`
@@ -105,9 +130,10 @@ func (o outputDTO) asOutput() *transaction.Output {
 }
 
 type transactionDTO struct {
-	ID      string      `json:"id"`
-	Inputs  []inputDTO  `json:"inputs"`
-	Outputs []outputDTO `json:"outputs"`
+	ID          string      `json:"id"`
+	Inputs      []inputDTO  `json:"inputs"`
+	Outputs     []outputDTO `json:"outputs"`
+	Replaceable bool        `json:"replaceable,omitempty"`
 }
 
 func transDTO(tx transaction.Transaction) transactionDTO {
@@ -129,9 +155,10 @@ func transDTO(tx transaction.Transaction) transactionDTO {
 	}
 
 	return transactionDTO{
-		ID:      tx.ID().String(),
-		Inputs:  inputs,
-		Outputs: outputs,
+		ID:          tx.ID().String(),
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Replaceable: tx.Replaceable(),
 	}
 }
 
@@ -146,6 +173,9 @@ func asModel(dto transactionDTO) (*transaction.Transaction, error) {
 		outputs[i] = out.asOutput()
 	}
 
+	if dto.Replaceable {
+		return transaction.NewReplaceableFrom(inputs, outputs)
+	}
 	return transaction.NewFrom(inputs, outputs)
 }
 
@@ -164,3 +194,12 @@ func challengeModelToDTO(challenge blockchain.Challenge) challengeDTO {
 		TimeCapMillis: challenge.TimeCapMillis,
 	}
 }
+
+func (c challengeDTO) asChallenge() blockchain.Challenge {
+	return blockchain.Challenge{
+		Difficulty:    c.Difficulty,
+		Nonce:         c.Nonce,
+		HashValue:     c.HashValue,
+		TimeCapMillis: c.TimeCapMillis,
+	}
+}
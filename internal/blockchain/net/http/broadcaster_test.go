@@ -1,7 +1,6 @@
 package http
 
 import (
-	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +11,7 @@ import (
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 
 	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +20,7 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 	// given setup
 	var mu sync.Mutex
 	calledPeers := []string{}
-	receivedBodies := []blockDTO{}
+	receivedBodies := []blockchain.Block{}
 
 	// and given peers respond with 200 OK
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +33,10 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 			return
 		}
 
+		if r.Header.Get("Content-Type") != canonical.MediaType {
+			t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err != nil {
 			t.Errorf("failed to read request body: %v", err)
@@ -40,10 +44,10 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 			return
 		}
 
-		var receivedBlock blockDTO
-		err = json.Unmarshal(bodyBytes, &receivedBlock)
+		var receivedBlock blockchain.Block
+		err = receivedBlock.UnmarshalBinary(bodyBytes)
 		if err != nil {
-			t.Errorf("failed to decode JSON: %v", err)
+			t.Errorf("failed to decode block: %v", err)
 			http.Error(w, "bad request", http.StatusInternalServerError)
 			return
 		}
@@ -58,7 +62,7 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 	// and given
 	peerURLs := []string{mockServer.URL}
 	broadcaster := NewBroadcaster()
-	sampleTransaction, err := transaction.NewFrom(nil, nil)
+	sampleTransaction, err := transaction.NewFrom([]*transaction.Input{}, []*transaction.Output{transaction.NewOutput(5, "address")})
 	require.NoError(t, err, "NewFrom should not return an error")
 	sampleChallange := blockchain.Challenge{
 		Nonce:         123,
@@ -66,30 +70,16 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 		Difficulty:    2,
 		TimeCapMillis: 2,
 	}
-	sampleChallangeDto := challengeDTO{
-		Nonce:         123,
-		HashValue:     "12345",
-		Difficulty:    2,
-		TimeCapMillis: 2,
-	}
 	mockBlock := blockchain.Block{
-		Index:          1,
-		TimestampMilis: 123456789,
-		ContentHash:    "12345",
-		PrevHash:       "54321",
-		Transactions:   []transaction.Transaction{*sampleTransaction},
-		Challenge:      sampleChallange,
-	}
-	expectedTransactionDTO := transDTO(*sampleTransaction)
-	expectedBody := blockDTO{
-		Index:          mockBlock.Index,
-		TimestampMilis: mockBlock.TimestampMilis,
-		ContentHash:    mockBlock.ContentHash,
-		PrevHash:       mockBlock.PrevHash,
-		Transactions:   []transactionDTO{expectedTransactionDTO},
-		Challenge:      sampleChallangeDto,
+		Header: blockchain.Header{
+			Index:          1,
+			TimestampMilis: 123456789,
+			ContentHash:    "12345",
+			PrevHash:       "54321",
+			Challenge:      sampleChallange,
+		},
+		Transactions: []transaction.Transaction{*sampleTransaction},
 	}
-	_ = expectedBody // TODO#30 - fix issue with marshalling ID
 
 	// when
 	err = broadcaster.Broadcast(mockBlock, peerURLs)
@@ -104,5 +94,5 @@ func TestBroadcaster_Broadcast(t *testing.T) {
 	assert.Equal(t, len(peerURLs), len(calledPeers), "All peers should be called")
 	assert.Contains(t, calledPeers, strings.TrimPrefix(mockServer.URL, "http://"), "The mock server should be called")
 	assert.Equal(t, 1, len(receivedBodies), "Exactly one block body should be received")
-	// assert.Equal(t, expectedBody, receivedBodies[0], "The request body should match the expected blockDTO")  // TODO#30 fix issue with marshalling ID
+	assert.Equal(t, mockBlock, receivedBodies[0], "The request body should match the broadcast block")
 }
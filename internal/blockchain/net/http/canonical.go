@@ -0,0 +1,37 @@
+package http
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+)
+
+// acceptsCanonical tells whether the client asked for the canonical encoding, the peers do while people get JSON.
+func acceptsCanonical(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), canonical.MediaType)
+}
+
+// isCanonical tells whether the body is in the canonical encoding rather than JSON.
+func isCanonical(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == canonical.MediaType
+}
+
+func writeCanonical(w http.ResponseWriter, v canonical.Marshaler) {
+	w.Header().Set("Content-Type", canonical.MediaType)
+	if _, err := w.Write(canonical.Marshal(v)); err != nil {
+		slog.Error("failed to write canonical response", "error", err)
+	}
+}
+
+func readCanonical(body io.Reader, v canonical.Unmarshaler) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return canonical.Unmarshal(data, v)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGetChain struct {
+	chain blockchain.BlockChain
+}
+
+func (f fakeGetChain) Get() (blockchain.BlockChain, error) {
+	return f.chain, nil
+}
+
+type fakeGetBlock struct {
+	chain blockchain.BlockChain
+}
+
+func (f fakeGetBlock) Get(hash string) (blockchain.Block, error) {
+	return f.chain.GetBlockByHash(hash)
+}
+
+func newCanonicalServer(t *testing.T) (*httptest.Server, blockchain.BlockChain) {
+	chain, err := blockchain.ImportBlockchain([]blockchain.Block{blockchain.GenerateGenesisBlock()})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get(chainURL, getChain(fakeGetChain{chain: *chain}))
+	r.Get(blocksURL+"/{hash}", getBlock(fakeGetBlock{chain: *chain}))
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, *chain
+}
+
+func TestGetBlock_shouldNegotiateTheEncoding(t *testing.T) {
+	// given
+	server, chain := newCanonicalServer(t)
+	genesis := chain.GetFirst()
+
+	tt := []struct {
+		accept      string
+		contentType string
+	}{
+		{accept: "", contentType: "application/json"},
+		{accept: "application/json", contentType: "application/json"},
+		{accept: canonical.MediaType, contentType: canonical.MediaType},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.accept, func(t *testing.T) {
+			// when
+			req := httptest.NewRequest(http.MethodGet, blocksURL+"/"+neturl.PathEscape(genesis.ContentHash), nil)
+			req.Header.Set("Accept", tc.accept)
+			rec := httptest.NewRecorder()
+			server.Config.Handler.ServeHTTP(rec, req)
+
+			// then
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.contentType, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestBlockClient_GetBlock_shouldReceiveTheCanonicalBlock(t *testing.T) {
+	// given
+	server, chain := newCanonicalServer(t)
+
+	// when
+	block, err := NewBlockClient().GetBlock(server.URL, chain.GetFirst().ContentHash)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, chain.GetFirst(), block)
+}
+
+func TestChainClient_Get_shouldReceiveTheCanonicalChain(t *testing.T) {
+	// given
+	server, chain := newCanonicalServer(t)
+
+	// when
+	received, err := NewChainClient().Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, chain, received)
+}
+
+func TestPostBlock_shouldAcceptTheCanonicalBlock(t *testing.T) {
+	// given
+	genesis := blockchain.GenerateGenesisBlock()
+	body, err := genesis.MarshalBinary()
+	require.NoError(t, err)
+
+	t.Run("canonical body", func(t *testing.T) {
+		// given
+		handler := &mockHandler{expected: &genesis}
+		req := httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body))
+		req.Header.Set("Content-Type", canonical.MediaType)
+		rec := httptest.NewRecorder()
+
+		// when
+		postBlock(handler)(rec, req)
+
+		// then
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, handler.called)
+	})
+
+	t.Run("truncated canonical body", func(t *testing.T) {
+		// given
+		handler := &mockHandler{}
+		req := httptest.NewRequest(http.MethodPost, "/block", bytes.NewReader(body[:len(body)-1]))
+		req.Header.Set("Content-Type", canonical.MediaType)
+		rec := httptest.NewRecorder()
+
+		// when
+		postBlock(handler)(rec, req)
+
+		// then
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, 0, handler.called)
+	})
+}
@@ -11,8 +11,19 @@ import (
 
 func getChain(getChainQueryHandler query.GetChain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		dtoChain := persistence.MapToDto(getChainQueryHandler.Get())
-		err := json.NewEncoder(w).Encode(dtoChain)
+		chain, err := getChainQueryHandler.Get()
+		if err != nil {
+			slog.Error("Cannot read blockchain", "error", err)
+			http.Error(w, "could not read the chain", http.StatusInternalServerError)
+			return
+		}
+		if acceptsCanonical(r) {
+			writeCanonical(w, chain)
+			return
+		}
+
+		dtoChain := persistence.MapToDto(chain)
+		err = json.NewEncoder(w).Encode(dtoChain)
 		if err != nil {
 			slog.Error("Cannot encode blockchain")
 			http.NotFound(w, r)
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem/persistence"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+)
+
+const chainURL = "/chain"
+
+type ChainClient struct {
+	client http.Client
+}
+
+func NewChainClient() *ChainClient {
+	return &ChainClient{
+		client: http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Get downloads the whole chain of the peer. Every block is validated while importing the chain.
+// The chain is asked for in the canonical encoding, the peers which only know JSON answer with it instead.
+func (c *ChainClient) Get(peer string) (blockchain.BlockChain, error) {
+	req, err := http.NewRequest(http.MethodGet, peer+chainURL, nil)
+	if err != nil {
+		return blockchain.BlockChain{}, fmt.Errorf("failed to get chain from %s: %w", peer, err)
+	}
+	req.Header.Set("Accept", canonical.MediaType+", application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return blockchain.BlockChain{}, fmt.Errorf("failed to get chain from %s: %w", peer, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return blockchain.BlockChain{}, fmt.Errorf("failed to get chain from %s: %v", peer, resp.Status)
+	}
+
+	if isCanonical(resp.Header) {
+		var received blockchain.BlockChain
+		if err := readCanonical(resp.Body, &received); err != nil {
+			return blockchain.BlockChain{}, fmt.Errorf("failed to decode chain from %s: %w", peer, err)
+		}
+		chain, err := blockchain.ImportBlockchain(received.Blocks)
+		if err != nil {
+			return blockchain.BlockChain{}, fmt.Errorf("chain from %s is not valid: %w", peer, err)
+		}
+		return *chain, nil
+	}
+
+	var dto persistence.ChainDto
+	if err := json.NewDecoder(resp.Body).Decode(&dto); err != nil {
+		return blockchain.BlockChain{}, fmt.Errorf("failed to decode chain from %s: %w", peer, err)
+	}
+
+	chain, err := persistence.MapToActual(dto)
+	if err != nil {
+		return blockchain.BlockChain{}, fmt.Errorf("chain from %s is not valid: %w", peer, err)
+	}
+	return chain, nil
+}
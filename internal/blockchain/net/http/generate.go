@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+const (
+	generateURL = "/admin/generate"
+
+	MaxBlocksPerGenerate = 1000
+)
+
+type generateDTO struct {
+	Blocks  int    `json:"blocks"`
+	Address string `json:"address,omitempty"`
+}
+
+type generatedDTO struct {
+	Hashes []string `json:"hashes"`
+}
+
+// RouteGenerate exposes the on-demand block generation. It is meant for the networks which do not mine continuously.
+func RouteGenerate(r chi.Router, mineBlock command.MineBlockHandler) {
+	r.Post(generateURL, postGenerate(mineBlock))
+}
+
+// postGenerate mines the requested number of blocks before responding with their hashes.
+// The requests are served one at a time, so that the blocks of one request follow each other.
+// The mining stops once the client goes away, and when fewer blocks than requested get mined the request fails.
+func postGenerate(mineBlockHandler command.MineBlockHandler) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto generateDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if dto.Blocks < 1 || dto.Blocks > MaxBlocksPerGenerate {
+			http.Error(w, fmt.Sprintf("blocks must be between 1 and %d", MaxBlocksPerGenerate), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		hashes := make([]string, 0, dto.Blocks)
+		err := mineBlockHandler.Handle(command.MineBlock{
+			Context: r.Context(),
+			Blocks:  dto.Blocks,
+			Address: dto.Address,
+			Mined: func(block blockchain.Block) {
+				hashes = append(hashes, block.ContentHash)
+			},
+		})
+		if err != nil {
+			slog.Error("failed to generate blocks", "error", err)
+		}
+		slog.Info("Blocks generated", "count", len(hashes))
+		if len(hashes) < dto.Blocks {
+			http.Error(w, fmt.Sprintf("mined %d of %d blocks", len(hashes), dto.Blocks), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(generatedDTO{Hashes: hashes}); err != nil {
+			slog.Error("failed to encode generated blocks", "error", err)
+		}
+	}
+}
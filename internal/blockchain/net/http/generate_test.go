@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostGenerate_shouldMineRequestedBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	publisher := &mock.Publisher{}
+	mineBlock := command.NewMineBlockHandler("self", repo, publisher, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), 2)
+	handler := postGenerate(mineBlock)
+
+	// when
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, generateURL, strings.NewReader(`{"blocks":3,"address":"alice"}`)))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	var generated generatedDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&generated))
+	chain := chainOf(t, repo)
+	require.Len(t, chain.Blocks, 4)
+	assert.Equal([]string{chain.Blocks[1].ContentHash, chain.Blocks[2].ContentHash, chain.Blocks[3].ContentHash}, generated.Hashes)
+	for _, block := range chain.Blocks[1:] {
+		assert.Equal("alice", block.Transactions[0].Outputs()[0].Address())
+	}
+	assert.Equal(3, publisher.Called)
+}
+
+func TestPostGenerate_shouldRejectInvalidCount(t *testing.T) {
+	handler := postGenerate(nil)
+
+	for _, body := range []string{`{}`, `{"blocks":0}`, `{"blocks":-1}`, `{"blocks":1001}`, `not json`} {
+		// when
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, generateURL, strings.NewReader(body)))
+
+		// then
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestPostGenerate_shouldFailWhenMiningStopsEarly(t *testing.T) {
+	// given a client which went away
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	mineBlock := command.NewMineBlockHandler("self", repo, &mock.Publisher{}, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), 2)
+	handler := postGenerate(mineBlock)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, generateURL, strings.NewReader(`{"blocks":3}`)).WithContext(ctx))
+
+	// then
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "mined 0 of 3 blocks")
+	assert.Len(t, chainOf(t, repo).Blocks, 1)
+}
+
+func chainOf(t *testing.T, repo command.BlockChainRepository) blockchain.BlockChain {
+	t.Helper()
+	chain, err := repo.GetChain()
+	require.NoError(t, err)
+	return chain
+}
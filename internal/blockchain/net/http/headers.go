@@ -0,0 +1,88 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+)
+
+const (
+	headersURL = "/headers"
+
+	// MaxHeadersPerRequest caps how many headers a peer sends back at once.
+	MaxHeadersPerRequest = 2000
+)
+
+type headerDTO struct {
+	Index          int          `json:"index"`
+	TimestampMilis int64        `json:"timestamp"`
+	ContentHash    string       `json:"content_hash"`
+	PrevHash       string       `json:"prev_hash"`
+	MerkleRoot     string       `json:"merkle_root"`
+	Challenge      challengeDTO `json:"challenge"`
+}
+
+type headersDTO struct {
+	Headers []headerDTO `json:"headers"`
+}
+
+func asHeaderDTO(header blockchain.Header) headerDTO {
+	return headerDTO{
+		Index:          header.Index,
+		TimestampMilis: header.TimestampMilis,
+		ContentHash:    header.ContentHash,
+		PrevHash:       header.PrevHash,
+		MerkleRoot:     header.MerkleRoot,
+		Challenge:      challengeModelToDTO(header.Challenge),
+	}
+}
+
+func (dto headerDTO) asHeader() blockchain.Header {
+	return blockchain.Header{
+		Index:          dto.Index,
+		TimestampMilis: dto.TimestampMilis,
+		ContentHash:    dto.ContentHash,
+		PrevHash:       dto.PrevHash,
+		MerkleRoot:     dto.MerkleRoot,
+		Challenge:      dto.Challenge.asChallenge(),
+	}
+}
+
+func getHeaders(getHeadersQuery query.GetHeaders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, err := strconv.Atoi(r.URL.Query().Get("from"))
+		if err != nil || from < 0 {
+			http.Error(w, "from must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		count := MaxHeadersPerRequest
+		if raw := r.URL.Query().Get("count"); raw != "" {
+			count, err = strconv.Atoi(raw)
+			if err != nil || count <= 0 {
+				http.Error(w, "count must be a positive number", http.StatusBadRequest)
+				return
+			}
+		}
+		count = min(count, MaxHeadersPerRequest)
+
+		headers, err := getHeadersQuery.Get(from, count)
+		if err != nil {
+			slog.Error("failed to read headers", "error", err)
+			http.Error(w, "could not read the headers", http.StatusInternalServerError)
+			return
+		}
+		dto := headersDTO{Headers: make([]headerDTO, len(headers))}
+		for i, header := range headers {
+			dto.Headers[i] = asHeaderDTO(header)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(dto); err != nil {
+			slog.Error("failed to encode headers", "error", err)
+		}
+	}
+}
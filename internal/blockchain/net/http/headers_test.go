@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockClient_shouldGetHeadersAndBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	Route(r, nil, query.NewGetChain(repo), query.NewGetHeaders(repo), query.NewGetBlock(repo), query.NewGetSupply(repo))
+	server := httptest.NewServer(r)
+	defer server.Close()
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+	client := NewBlockClient()
+
+	// when
+	headers, err := client.GetHeaders(server.URL, 0, 10)
+
+	// then
+	assert.NoError(err)
+	assert.Equal([]blockchain.Header{genesis.Header}, headers)
+
+	// when
+	block, err := client.GetBlock(server.URL, genesis.ContentHash)
+
+	// then
+	assert.NoError(err)
+	assert.Equal(genesis.Header, block.Header)
+	assert.Equal(genesis.Transactions[0].ID(), block.Transactions[0].ID())
+
+	// when
+	_, err = client.GetBlock(server.URL, "unknown/hash=")
+
+	// then
+	assert.Error(err)
+}
+
+func TestGetHeaders_shouldRejectInvalidRange(t *testing.T) {
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	handler := getHeaders(query.NewGetHeaders(repo))
+
+	for _, target := range []string{"/headers", "/headers?from=-1", "/headers?from=0&count=0", "/headers?from=x"} {
+		// when
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		// then
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+)
+
+const miningURL = "/mining"
+
+type mining interface {
+	Start() error
+	Stop()
+	Status() application.MiningStatus
+}
+
+type miningStatusDTO struct {
+	Running     bool    `json:"running"`
+	Height      int     `json:"height"`
+	Difficulty  int     `json:"difficulty"`
+	Hashrate    float64 `json:"hashrate"`
+	BlocksFound int     `json:"blocksFound"`
+	Rewards     int     `json:"rewards"`
+}
+
+// RouteMining exposes switching the miner on and off and checking on it.
+func RouteMining(r chi.Router, m mining) {
+	r.Post(miningURL+"/start", postMiningStart(m))
+	r.Post(miningURL+"/stop", postMiningStop(m))
+	r.Get(miningURL+"/status", getMiningStatus(m))
+}
+
+func postMiningStart(m mining) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := m.Start()
+		if errors.Is(err, application.MiningOnDemand) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to start mining", "error", err)
+			http.Error(w, "failed to start mining", http.StatusInternalServerError)
+			return
+		}
+		writeMiningStatus(w, m)
+	}
+}
+
+func postMiningStop(m mining) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.Stop()
+		writeMiningStatus(w, m)
+	}
+}
+
+func getMiningStatus(m mining) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeMiningStatus(w, m)
+	}
+}
+
+func writeMiningStatus(w http.ResponseWriter, m mining) {
+	status := m.Status()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(miningStatusDTO{
+		Running:     status.Running,
+		Height:      status.Height,
+		Difficulty:  status.Difficulty,
+		Hashrate:    status.Hashrate,
+		BlocksFound: status.BlocksFound,
+		Rewards:     status.Rewards,
+	}); err != nil {
+		slog.Error("failed to encode mining status", "error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMining struct {
+	status application.MiningStatus
+	err    error
+}
+
+func (m *fakeMining) Start() error {
+	if m.err != nil {
+		return m.err
+	}
+	m.status.Running = true
+	return nil
+}
+
+func (m *fakeMining) Stop() {
+	m.status.Running = false
+}
+
+func (m *fakeMining) Status() application.MiningStatus {
+	return m.status
+}
+
+func TestRouteMining(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	m := &fakeMining{status: application.MiningStatus{Height: 5, Difficulty: 9, Hashrate: 1500, BlocksFound: 2, Rewards: 200}}
+	r := chi.NewRouter()
+	RouteMining(r, m)
+
+	tt := []struct {
+		method  string
+		target  string
+		running bool
+	}{
+		{method: http.MethodGet, target: "/mining/status", running: false},
+		{method: http.MethodPost, target: "/mining/start", running: true},
+		{method: http.MethodGet, target: "/mining/status", running: true},
+		{method: http.MethodPost, target: "/mining/stop", running: false},
+	}
+
+	for _, tc := range tt {
+		// when
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+
+		// then
+		require.Equal(t, http.StatusOK, rec.Code, tc.target)
+		var status miningStatusDTO
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+		assert.Equal(miningStatusDTO{Running: tc.running, Height: 5, Difficulty: 9, Hashrate: 1500, BlocksFound: 2, Rewards: 200}, status, tc.target)
+	}
+}
+
+func TestPostMiningStart_shouldConflictWhenMiningOnDemand(t *testing.T) {
+	// given
+	handler := postMiningStart(&fakeMining{err: application.MiningOnDemand})
+
+	// when
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/mining/start", nil))
+
+	// then
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
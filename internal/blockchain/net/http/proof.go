@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+const (
+	proofURL         = "/tx/{id}/proof"
+	unspentProofsURL = "/address/{address}/proofs"
+)
+
+type merkleStepDTO struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// transactionProofDTO carries the transaction ID in hex, the way the wallet prints it.
+type transactionProofDTO struct {
+	TransactionID string          `json:"transaction_id"`
+	Header        headerDTO       `json:"header"`
+	Steps         []merkleStepDTO `json:"steps"`
+}
+
+func asTransactionProofDTO(proof query.TransactionProof) transactionProofDTO {
+	steps := make([]merkleStepDTO, len(proof.Proof.Steps))
+	for i, step := range proof.Proof.Steps {
+		steps[i] = merkleStepDTO{Hash: step.Hash, Left: step.Left}
+	}
+	return transactionProofDTO{
+		TransactionID: hex.EncodeToString([]byte(proof.Proof.TransactionID)),
+		Header:        asHeaderDTO(proof.Header),
+		Steps:         steps,
+	}
+}
+
+func (dto transactionProofDTO) asTransactionProof() (query.TransactionProof, error) {
+	id, err := hex.DecodeString(dto.TransactionID)
+	if err != nil {
+		return query.TransactionProof{}, err
+	}
+	steps := make([]blockchain.MerkleStep, len(dto.Steps))
+	for i, step := range dto.Steps {
+		steps[i] = blockchain.MerkleStep{Hash: step.Hash, Left: step.Left}
+	}
+	return query.TransactionProof{
+		Header: dto.Header.asHeader(),
+		Proof:  blockchain.MerkleProof{TransactionID: transaction.ID(id), Steps: steps},
+	}, nil
+}
+
+func getTransactionProof(getProofQuery query.GetTransactionProof) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := hex.DecodeString(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "transaction ID must be hex", http.StatusBadRequest)
+			return
+		}
+
+		proof, err := getProofQuery.Get(transaction.ID(id))
+		if errors.Is(err, blockchain.BlockNotFound) || errors.Is(err, blockchain.TransactionNotInBlock) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to prove transaction", "id", chi.URLParam(r, "id"), "error", err)
+			http.Error(w, "failed to prove transaction", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(asTransactionProofDTO(proof)); err != nil {
+			slog.Error("failed to encode transaction proof", "error", err)
+		}
+	}
+}
+
+type unspentProofDTO struct {
+	OutputIndex int `json:"output_index"`
+	// Transaction is in the canonical encoding, the client derives its ID from it
+	Transaction string              `json:"transaction"`
+	Proof       transactionProofDTO `json:"proof"`
+}
+
+type unspentProofsDTO struct {
+	Proofs []unspentProofDTO `json:"proofs"`
+}
+
+func asUnspentProofDTO(proof query.UnspentProof) (unspentProofDTO, error) {
+	tx, err := proof.Transaction.MarshalBinary()
+	if err != nil {
+		return unspentProofDTO{}, err
+	}
+	return unspentProofDTO{
+		OutputIndex: proof.OutputIndex,
+		Transaction: base64.StdEncoding.EncodeToString(tx),
+		Proof:       asTransactionProofDTO(proof.TransactionProof),
+	}, nil
+}
+
+func (dto unspentProofDTO) asUnspentProof() (query.UnspentProof, error) {
+	encoded, err := base64.StdEncoding.DecodeString(dto.Transaction)
+	if err != nil {
+		return query.UnspentProof{}, err
+	}
+	var tx transaction.Transaction
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return query.UnspentProof{}, err
+	}
+	proof, err := dto.Proof.asTransactionProof()
+	if err != nil {
+		return query.UnspentProof{}, err
+	}
+	return query.UnspentProof{OutputIndex: dto.OutputIndex, Transaction: tx, TransactionProof: proof}, nil
+}
+
+func getUnspentProofs(getProofsQuery query.GetUnspentProofs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := chi.URLParam(r, "address")
+		proofs, err := getProofsQuery.Get(address)
+		if err != nil {
+			slog.Error("failed to prove unspent outputs", "address", address, "error", err)
+			http.Error(w, "failed to prove unspent outputs", http.StatusInternalServerError)
+			return
+		}
+
+		dto := unspentProofsDTO{Proofs: make([]unspentProofDTO, len(proofs))}
+		for i, proof := range proofs {
+			dto.Proofs[i], err = asUnspentProofDTO(proof)
+			if err != nil {
+				slog.Error("failed to encode unspent proof", "error", err)
+				http.Error(w, "failed to prove unspent outputs", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(dto); err != nil {
+			slog.Error("failed to encode unspent proofs", "error", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransactionProof(t *testing.T) {
+	// given
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Get(proofURL, getTransactionProof(query.NewGetTransactionProof(repo)))
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+	id := hex.EncodeToString([]byte(genesis.Transactions[0].ID()))
+
+	// when
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tx/"+id+"/proof", nil))
+
+	// then the proof leads to the Merkle root of the header
+	require.Equal(t, http.StatusOK, rec.Code)
+	var dto transactionProofDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&dto))
+	proof, err := dto.asTransactionProof()
+	require.NoError(t, err)
+	assert.Equal(t, genesis.Header, proof.Header)
+	assert.Equal(t, genesis.Transactions[0].ID(), proof.Proof.TransactionID)
+	assert.True(t, blockchain.VerifyMerkleProof(proof.Header, proof.Proof))
+}
+
+func TestGetTransactionProof_shouldError(t *testing.T) {
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Get(proofURL, getTransactionProof(query.NewGetTransactionProof(repo)))
+
+	tt := []struct {
+		target   string
+		expected int
+	}{
+		{target: "/tx/not-hex/proof", expected: http.StatusBadRequest},
+		{target: "/tx/" + hex.EncodeToString([]byte("unknown")) + "/proof", expected: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		// when
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+		// then
+		assert.Equal(t, tc.expected, rec.Code, tc.target)
+	}
+}
+
+func TestBlockClient_GetUnspentProofs(t *testing.T) {
+	// given the genesis output left unspent
+	repo, err := inmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain := chainOf(t, repo)
+	genesis := chain.GetFirst()
+	genesisTx := genesis.Transactions[0]
+	address := genesisTx.Outputs()[0].Address()
+	unspent := &mock.UnspentOutputRepository{UnspentOutputs: map[string][]transaction.UnspentOutput{
+		address: {transaction.NewUnspentOutput(genesisTx.ID(), 0, genesisTx.Outputs()[0].Amount(), address)},
+	}}
+	r := chi.NewRouter()
+	RouteProofs(r, query.NewGetTransactionProof(repo), query.NewGetUnspentProofs(repo, unspent))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// when
+	proofs, err := NewBlockClient().GetUnspentProofs(server.URL, address)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	assert.Equal(t, 0, proofs[0].OutputIndex)
+	assert.Equal(t, genesisTx.ID(), proofs[0].Transaction.ID())
+	assert.Equal(t, genesis.Header, proofs[0].Header)
+	assert.True(t, blockchain.VerifyMerkleProof(proofs[0].Header, proofs[0].Proof))
+}
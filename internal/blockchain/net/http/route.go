@@ -6,7 +6,16 @@ import (
 	"github.com/patrykferenc/eecoin/internal/blockchain/query"
 )
 
-func Route(r chi.Router, addBlock command.AddBlockHandler, chain query.GetChain) {
+func Route(r chi.Router, addBlock command.AddBlockHandler, chain query.GetChain, headers query.GetHeaders, block query.GetBlock, supply query.GetSupply) {
 	r.Post("/block", postBlock(addBlock))
-	r.Get("/chain", getChain(chain))
+	r.Get(chainURL, getChain(chain))
+	r.Get(headersURL, getHeaders(headers))
+	r.Get(blocksURL+"/{hash}", getBlock(block))
+	r.Get(supplyURL, getSupply(supply))
+}
+
+// RouteProofs serves the proofs the light clients check against the headers.
+func RouteProofs(r chi.Router, transactionProof query.GetTransactionProof, unspentProofs query.GetUnspentProofs) {
+	r.Get(proofURL, getTransactionProof(transactionProof))
+	r.Get(unspentProofsURL, getUnspentProofs(unspentProofs))
 }
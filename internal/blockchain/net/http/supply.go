@@ -0,0 +1,37 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+)
+
+const supplyURL = "/supply"
+
+type supplyDTO struct {
+	Issued      int `json:"issued"`
+	Circulating int `json:"circulating"`
+	Maximum     int `json:"maximum"`
+}
+
+func getSupply(getSupplyQuery query.GetSupply) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		supply, err := getSupplyQuery.Get()
+		if err != nil {
+			slog.Error("failed to read supply", "error", err)
+			http.Error(w, "could not read the supply", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(supplyDTO{
+			Issued:      supply.Issued,
+			Circulating: supply.Circulating,
+			Maximum:     supply.Maximum,
+		}); err != nil {
+			slog.Error("failed to encode supply", "error", err)
+		}
+	}
+}
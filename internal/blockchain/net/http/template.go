@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+)
+
+type blockTemplates interface {
+	New(payout string) (application.BlockTemplate, error)
+	Submit(id string, nonce uint32, timestampMillis int64) (blockchain.Block, error)
+}
+
+// BlockTemplateDTO is the block template as the miners see it, over HTTP and over the pool protocols alike.
+type BlockTemplateDTO struct {
+	ID           string           `json:"id"`
+	Previous     blockDTO         `json:"previous"`
+	Transactions []transactionDTO `json:"transactions"`
+	Difficulty   int              `json:"difficulty"`
+	TimeCap      int64            `json:"time_cap"`
+	MinTimestamp int64            `json:"min_timestamp"`
+	// Target is the highest hash solving the challenge, hex encoded.
+	Target string `json:"target"`
+	// Algorithm is the proof-of-work hash of the network.
+	Algorithm string `json:"algorithm"`
+	// MerkleRoot of the transactions, which the header commits to. The miners hash the nonce, as 4 big-endian bytes,
+	// followed by the canonical encoding of the index of the block, the hash of the previous one, the timestamp
+	// and the Merkle root, so they do not have to encode the transactions themselves.
+	MerkleRoot string `json:"merkle_root"`
+}
+
+type submitDTO struct {
+	TemplateID string `json:"template_id"`
+	Nonce      uint32 `json:"nonce"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+type submittedDTO struct {
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// RouteMiningTemplates exposes the block templates to the miners running outside the node.
+func RouteMiningTemplates(r chi.Router, templates blockTemplates) {
+	r.Get(miningURL+"/template", getMiningTemplate(templates))
+	r.Post(miningURL+"/submit", postMiningSubmit(templates))
+}
+
+// getMiningTemplate builds a block template with the coinbase paying to the address from the query,
+// or to the node itself when there is none.
+func getMiningTemplate(templates blockTemplates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl, err := templates.New(r.URL.Query().Get("address"))
+		if err != nil {
+			slog.Error("failed to create block template", "error", err)
+			http.Error(w, "failed to create block template", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(AsBlockTemplateDTO(tmpl)); err != nil {
+			slog.Error("failed to encode block template", "error", err)
+		}
+	}
+}
+
+func AsBlockTemplateDTO(tmpl application.BlockTemplate) BlockTemplateDTO {
+	transactions := make([]transactionDTO, len(tmpl.Transactions))
+	for i, tx := range tmpl.Transactions {
+		transactions[i] = transDTO(tx)
+	}
+
+	return BlockTemplateDTO{
+		ID:           tmpl.ID,
+		Previous:     asDTO(tmpl.Previous),
+		Transactions: transactions,
+		Difficulty:   tmpl.Challenge.Difficulty,
+		TimeCap:      tmpl.Challenge.TimeCapMillis,
+		MinTimestamp: tmpl.MinTimestampMillis,
+		Target:       hex.EncodeToString(blockchain.Target(tmpl.Challenge.Difficulty)),
+		Algorithm:    chaincfg.Active().PowAlgorithm,
+		MerkleRoot:   blockchain.MerkleRoot(tmpl.Transactions),
+	}
+}
+
+func postMiningSubmit(templates blockTemplates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto submitDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		block, err := templates.Submit(dto.TemplateID, dto.Nonce, dto.Timestamp)
+		switch {
+		case errors.Is(err, application.TemplateNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, blockchain.BlockDidNotMatchDiff),
+			errors.Is(err, blockchain.BlockWasNotWithinTime),
+			errors.Is(err, blockchain.BlockNotValid),
+			errors.Is(err, blockchain.TransactionsNotValid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			slog.Error("failed to submit block", "error", err)
+			http.Error(w, "failed to submit block", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(submittedDTO{Index: block.Index, Hash: block.ContentHash}); err != nil {
+			slog.Error("failed to encode submitted block", "error", err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	transactioninmem "github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteMiningTemplates_shouldAcceptTheSolvedTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	publisher := &mock.Publisher{}
+	repo, err := inmem.NewBlockChain(publisher)
+	require.NoError(t, err)
+	templates := application.NewTemplates(repo, transactioninmem.NewPoolRepository(), transactioninmem.NewUnspentOutputRepository(), command.NewAddBlockHandler(repo, publisher), "self")
+	r := chi.NewRouter()
+	RouteMiningTemplates(r, templates)
+
+	// when
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/mining/template?address=miner", nil))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	var tmpl BlockTemplateDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&tmpl))
+	chain := chainOf(t, repo)
+	genesis := chain.GetLast()
+	assert.Equal(genesis.Index, tmpl.Previous.Index)
+	assert.Equal(genesis.ContentHash, tmpl.Previous.ContentHash)
+	assert.Equal(genesis.Challenge.Difficulty, tmpl.Difficulty)
+	assert.Equal(hex.EncodeToString(blockchain.Target(tmpl.Difficulty)), tmpl.Target)
+	assert.Equal(blockchain.PowSha256, tmpl.Algorithm)
+	require.Len(t, tmpl.Transactions, 1)
+	assert.Equal("miner", tmpl.Transactions[0].Outputs[0].Address)
+
+	// when the miner solves it from the template alone, hashing the header without encoding the transactions
+	timestamp := max(time.Now().UnixMilli(), tmpl.MinTimestamp)
+	e := canonical.NewEncoder()
+	e.Int64(int64(tmpl.Previous.Index + 1))
+	e.String(tmpl.Previous.ContentHash)
+	e.Int64(timestamp)
+	e.String(tmpl.MerkleRoot)
+	target, err := hex.DecodeString(tmpl.Target)
+	require.NoError(t, err)
+	var nonce uint32
+	for ; ; nonce++ {
+		preimage := binary.BigEndian.AppendUint32(nil, nonce)
+		hash := sha256.Sum256(append(preimage, e.Encoded()...))
+		if bytes.Compare(hash[:], target) <= 0 {
+			break
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	body := fmt.Sprintf(`{"template_id":%q,"nonce":%d,"timestamp":%d}`, tmpl.ID, nonce, timestamp)
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mining/submit", strings.NewReader(body)))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var submitted submittedDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&submitted))
+	chain = chainOf(t, repo)
+	assert.Equal(submittedDTO{Index: 1, Hash: chain.GetLast().ContentHash}, submitted)
+	assert.Equal(1, publisher.Called)
+}
+
+type fakeTemplates struct {
+	err error
+}
+
+func (f fakeTemplates) New(string) (application.BlockTemplate, error) {
+	return application.BlockTemplate{}, f.err
+}
+
+func (f fakeTemplates) Submit(string, uint32, int64) (blockchain.Block, error) {
+	return blockchain.Block{}, f.err
+}
+
+func TestPostMiningSubmit_shouldMapErrors(t *testing.T) {
+	tt := []struct {
+		body     string
+		err      error
+		expected int
+	}{
+		{body: `not json`, expected: http.StatusBadRequest},
+		{body: `{"template_id":"x"}`, err: application.TemplateNotFound, expected: http.StatusNotFound},
+		{body: `{"template_id":"x"}`, err: blockchain.BlockDidNotMatchDiff, expected: http.StatusBadRequest},
+		{body: `{"template_id":"x"}`, err: blockchain.BlockWasNotWithinTime, expected: http.StatusBadRequest},
+		{body: `{"template_id":"x"}`, err: fmt.Errorf("could not add block to chain: %w", blockchain.TransactionsNotValid), expected: http.StatusBadRequest},
+		{body: `{"template_id":"x"}`, err: errors.New("disk full"), expected: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		// given
+		handler := postMiningSubmit(fakeTemplates{err: tc.err})
+
+		// when
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/mining/submit", strings.NewReader(tc.body)))
+
+		// then
+		assert.Equal(t, tc.expected, rec.Code, tc.err)
+	}
+}
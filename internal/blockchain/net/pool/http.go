@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const url = "/pool"
+
+// Route exposes the pool to the workers speaking HTTP.
+func Route(r chi.Router, p pool) {
+	r.Get(url+"/work", getWork(p))
+	r.Post(url+"/submit", postSubmit(p))
+	r.Get(url+"/workers", getWorkers(p))
+}
+
+func getWork(p pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := p.Work()
+		if err != nil {
+			slog.Error("failed to create pool job", "error", err)
+			http.Error(w, "failed to create pool job", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, asJobDTO(job))
+	}
+}
+
+func postSubmit(p pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto shareDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		submitted, err := p.Submit(dto.Worker, dto.JobID, dto.Nonce, dto.Timestamp)
+		if status, ok := rejected(err); err != nil && ok {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to submit share", "error", err)
+			http.Error(w, "failed to submit share", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, asAcceptedDTO(submitted))
+	}
+}
+
+func getWorkers(p pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, asWorkerDTOs(p.Workers()))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode pool response", "error", err)
+	}
+}
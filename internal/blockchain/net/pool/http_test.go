@@ -0,0 +1,119 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePool struct {
+	job       application.Job
+	submitted application.Submitted
+	err       error
+	shares    []shareDTO
+}
+
+func (p *fakePool) Work() (application.Job, error) {
+	return p.job, p.err
+}
+
+func (p *fakePool) Submit(worker string, jobID string, nonce uint32, timestampMillis int64) (application.Submitted, error) {
+	p.shares = append(p.shares, shareDTO{Worker: worker, JobID: jobID, Nonce: nonce, Timestamp: timestampMillis})
+	return p.submitted, p.err
+}
+
+func (p *fakePool) Workers() []application.WorkerStats {
+	return []application.WorkerStats{{Address: "alice", Accepted: 3, InWindow: 2}}
+}
+
+func newJob() application.Job {
+	return application.Job{
+		Template: application.BlockTemplate{
+			ID:                 "job",
+			Previous:           blockchain.Block{Header: blockchain.Header{Index: 4, ContentHash: "tip"}},
+			Challenge:          blockchain.Challenge{Difficulty: 9, TimeCapMillis: 1},
+			MinTimestampMillis: 100,
+		},
+		ShareDifficulty: 4,
+	}
+}
+
+func TestRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	block := blockchain.Block{Header: blockchain.Header{ContentHash: "found"}}
+	p := &fakePool{job: newJob(), submitted: application.Submitted{Block: &block}}
+	r := chi.NewRouter()
+	Route(r, p)
+
+	// when
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pool/work", nil))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	var job jobDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+	assert.Equal("job", job.ID)
+	assert.Equal(4, job.Previous.Index)
+	assert.Equal(9, job.Difficulty)
+	assert.Equal(4, job.ShareDifficulty)
+	assert.True(strings.HasPrefix(job.ShareTarget, "0fff"))
+
+	// when
+	rec = httptest.NewRecorder()
+	body := `{"worker":"alice","job_id":"job","nonce":7,"timestamp":120}`
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pool/submit", strings.NewReader(body)))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	var accepted acceptedDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&accepted))
+	assert.Equal(acceptedDTO{Accepted: true, BlockHash: "found"}, accepted)
+	assert.Equal([]shareDTO{{Worker: "alice", JobID: "job", Nonce: 7, Timestamp: 120}}, p.shares)
+
+	// when
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pool/workers", nil))
+
+	// then
+	require.Equal(t, http.StatusOK, rec.Code)
+	var workers []workerDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&workers))
+	assert.Equal([]workerDTO{{Address: "alice", Accepted: 3, InWindow: 2}}, workers)
+}
+
+func TestPostSubmit_shouldMapErrors(t *testing.T) {
+	tt := []struct {
+		err      error
+		expected int
+	}{
+		{err: application.TemplateNotFound, expected: http.StatusNotFound},
+		{err: application.ShareDuplicate, expected: http.StatusConflict},
+		{err: application.ShareNotValid, expected: http.StatusBadRequest},
+		{err: application.WorkerNotValid, expected: http.StatusBadRequest},
+		{err: errors.New("disk full"), expected: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		// given
+		handler := postSubmit(&fakePool{err: tc.err})
+
+		// when
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/pool/submit", strings.NewReader(`{"worker":"alice"}`)))
+
+		// then
+		assert.Equal(t, tc.expected, rec.Code, tc.err)
+	}
+}
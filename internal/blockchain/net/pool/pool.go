@@ -0,0 +1,82 @@
+// Package pool serves the workers of the mining pool, over HTTP and over line-delimited JSON on TCP.
+// Both protocols hand out the same jobs and take the same shares.
+package pool
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	blockchainhttp "github.com/patrykferenc/eecoin/internal/blockchain/net/http"
+)
+
+type pool interface {
+	Work() (application.Job, error)
+	Submit(worker string, jobID string, nonce uint32, timestampMillis int64) (application.Submitted, error)
+	Workers() []application.WorkerStats
+}
+
+type jobDTO struct {
+	blockchainhttp.BlockTemplateDTO
+	ShareDifficulty int `json:"share_difficulty"`
+	// ShareTarget is the highest hash accepted as a share, hex encoded.
+	ShareTarget string `json:"share_target"`
+}
+
+type shareDTO struct {
+	Worker    string `json:"worker"`
+	JobID     string `json:"job_id"`
+	Nonce     uint32 `json:"nonce"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type acceptedDTO struct {
+	Accepted bool `json:"accepted"`
+	// BlockHash is set when the share solved the block as well.
+	BlockHash string `json:"block_hash,omitempty"`
+}
+
+type workerDTO struct {
+	Address  string `json:"address"`
+	Accepted int    `json:"accepted"`
+	InWindow int    `json:"in_window"`
+}
+
+func asJobDTO(job application.Job) jobDTO {
+	return jobDTO{
+		BlockTemplateDTO: blockchainhttp.AsBlockTemplateDTO(job.Template),
+		ShareDifficulty:  job.ShareDifficulty,
+		ShareTarget:      hex.EncodeToString(blockchain.Target(job.ShareDifficulty)),
+	}
+}
+
+func asAcceptedDTO(submitted application.Submitted) acceptedDTO {
+	dto := acceptedDTO{Accepted: true}
+	if submitted.Block != nil {
+		dto.BlockHash = submitted.Block.ContentHash
+	}
+	return dto
+}
+
+func asWorkerDTOs(stats []application.WorkerStats) []workerDTO {
+	workers := make([]workerDTO, len(stats))
+	for i, s := range stats {
+		workers[i] = workerDTO{Address: s.Address, Accepted: s.Accepted, InWindow: s.InWindow}
+	}
+	return workers
+}
+
+// rejected tells whether the share was turned down because of the worker, and with which HTTP status.
+func rejected(err error) (int, bool) {
+	switch {
+	case errors.Is(err, application.TemplateNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, application.ShareDuplicate):
+		return http.StatusConflict, true
+	case errors.Is(err, application.ShareNotValid), errors.Is(err, application.WorkerNotValid):
+		return http.StatusBadRequest, true
+	}
+	return http.StatusInternalServerError, false
+}
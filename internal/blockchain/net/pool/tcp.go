@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net"
+)
+
+// MaxLineBytes caps the length of a single request of the TCP protocol.
+const MaxLineBytes = 64 * 1024
+
+const (
+	methodWork    = "work"
+	methodSubmit  = "submit"
+	methodWorkers = "workers"
+)
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result any             `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Server serves the workers speaking line-delimited JSON over TCP. Every request is a line with an id, the method
+// (work, submit or workers) and its params. Every response is a line with the id of the request and either
+// the result or the error.
+type Server struct {
+	pool pool
+}
+
+func NewServer(p pool) *Server {
+	return &Server{pool: p}
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("Pool listening on " + listener.Addr().String())
+	return s.Serve(listener)
+}
+
+// Serve the connections accepted by the listener until it fails.
+func (s *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineBytes)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		if err := encoder.Encode(s.dispatch(scanner.Bytes())); err != nil {
+			slog.Debug("pool worker disconnected", "remote", conn.RemoteAddr(), "error", err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Warn("failed to read from pool worker", "remote", conn.RemoteAddr(), "error", err)
+	}
+}
+
+func (s *Server) dispatch(line []byte) response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return response{Error: "invalid JSON request"}
+	}
+
+	switch req.Method {
+	case methodWork:
+		job, err := s.pool.Work()
+		if err != nil {
+			slog.Error("failed to create pool job", "error", err)
+			return response{ID: req.ID, Error: "failed to create pool job"}
+		}
+		return response{ID: req.ID, Result: asJobDTO(job)}
+	case methodSubmit:
+		var share shareDTO
+		if err := json.Unmarshal(req.Params, &share); err != nil {
+			return response{ID: req.ID, Error: "invalid share"}
+		}
+		submitted, err := s.pool.Submit(share.Worker, share.JobID, share.Nonce, share.Timestamp)
+		if _, ok := rejected(err); err != nil && ok {
+			return response{ID: req.ID, Error: err.Error()}
+		}
+		if err != nil {
+			slog.Error("failed to submit share", "error", err)
+			return response{ID: req.ID, Error: "failed to submit share"}
+		}
+		return response{ID: req.ID, Result: asAcceptedDTO(submitted)}
+	case methodWorkers:
+		return response{ID: req.ID, Result: asWorkerDTOs(s.pool.Workers())}
+	}
+	return response{ID: req.ID, Error: "unknown method " + req.Method}
+}
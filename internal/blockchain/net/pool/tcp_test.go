@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_shouldAnswerEveryLine(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	p := &fakePool{job: newJob()}
+	server, client := net.Pipe()
+	defer client.Close()
+	go NewServer(p).handle(server)
+	lines := bufio.NewScanner(client)
+
+	call := func(request string) map[string]json.RawMessage {
+		t.Helper()
+		_, err := fmt.Fprintln(client, request)
+		require.NoError(t, err)
+		require.True(t, lines.Scan())
+		var resp map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &resp))
+		return resp
+	}
+
+	// when
+	resp := call(`{"id":1,"method":"work"}`)
+
+	// then
+	assert.JSONEq(`1`, string(resp["id"]))
+	var job jobDTO
+	require.NoError(t, json.Unmarshal(resp["result"], &job))
+	assert.Equal("job", job.ID)
+	assert.Equal(4, job.ShareDifficulty)
+
+	// when
+	resp = call(`{"id":2,"method":"submit","params":{"worker":"alice","job_id":"job","nonce":7,"timestamp":120}}`)
+
+	// then
+	assert.JSONEq(`2`, string(resp["id"]))
+	assert.JSONEq(`{"accepted":true}`, string(resp["result"]))
+	assert.Equal([]shareDTO{{Worker: "alice", JobID: "job", Nonce: 7, Timestamp: 120}}, p.shares)
+
+	// when
+	resp = call(`{"id":"three","method":"workers"}`)
+
+	// then
+	assert.JSONEq(`"three"`, string(resp["id"]))
+	assert.JSONEq(`[{"address":"alice","accepted":3,"in_window":2}]`, string(resp["result"]))
+
+	// when
+	resp = call(`{"id":4,"method":"mine"}`)
+
+	// then
+	assert.JSONEq(`"unknown method mine"`, string(resp["error"]))
+
+	// when
+	resp = call(`not json`)
+
+	// then
+	assert.JSONEq(`"invalid JSON request"`, string(resp["error"]))
+}
+
+func TestServer_shouldReportRejectedShares(t *testing.T) {
+	// given
+	s := NewServer(&fakePool{err: application.ShareDuplicate})
+
+	// when
+	resp := s.dispatch([]byte(`{"id":1,"method":"submit","params":{"worker":"alice","job_id":"job"}}`))
+
+	// then
+	assert.Equal(t, application.ShareDuplicate.Error(), resp.Error)
+	assert.Nil(t, resp.Result)
+}
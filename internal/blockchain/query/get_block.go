@@ -0,0 +1,22 @@
+package query
+
+import (
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+type GetBlock interface {
+	Get(hash string) (blockchain.Block, error)
+}
+
+type getBlock struct {
+	repo command.BlockChainRepository
+}
+
+func NewGetBlock(repo command.BlockChainRepository) GetBlock {
+	return &getBlock{repo: repo}
+}
+
+func (g *getBlock) Get(hash string) (blockchain.Block, error) {
+	return g.repo.GetBlock(hash)
+}
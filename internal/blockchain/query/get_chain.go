@@ -6,7 +6,7 @@ import (
 )
 
 type GetChain interface {
-	Get() blockchain.BlockChain
+	Get() (blockchain.BlockChain, error)
 }
 
 type getChain struct {
@@ -17,6 +17,6 @@ func NewGetChain(repo command.BlockChainRepository) GetChain { // TODO: move the
 	return &getChain{repo: repo}
 }
 
-func (g *getChain) Get() blockchain.BlockChain {
+func (g *getChain) Get() (blockchain.BlockChain, error) {
 	return g.repo.GetChain()
 }
@@ -0,0 +1,22 @@
+package query
+
+import (
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+type GetHeaders interface {
+	Get(from int, count int) ([]blockchain.Header, error)
+}
+
+type getHeaders struct {
+	repo command.BlockChainRepository
+}
+
+func NewGetHeaders(repo command.BlockChainRepository) GetHeaders {
+	return &getHeaders{repo: repo}
+}
+
+func (g *getHeaders) Get(from int, count int) ([]blockchain.Header, error) {
+	return g.repo.GetHeaders(from, count)
+}
@@ -0,0 +1,26 @@
+package query
+
+import (
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+)
+
+type GetSupply interface {
+	Get() (blockchain.Supply, error)
+}
+
+type getSupply struct {
+	repo command.BlockChainRepository
+}
+
+func NewGetSupply(repo command.BlockChainRepository) GetSupply {
+	return &getSupply{repo: repo}
+}
+
+func (g *getSupply) Get() (blockchain.Supply, error) {
+	chain, err := g.repo.GetChain()
+	if err != nil {
+		return blockchain.Supply{}, err
+	}
+	return chain.GetSupply(), nil
+}
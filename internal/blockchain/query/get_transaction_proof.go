@@ -0,0 +1,42 @@
+package query
+
+import (
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// TransactionProof shows that a transaction is in the block with the header, without sending the rest of the block.
+type TransactionProof struct {
+	Header blockchain.Header
+	Proof  blockchain.MerkleProof
+}
+
+type GetTransactionProof interface {
+	Get(id transaction.ID) (TransactionProof, error)
+}
+
+type getTransactionProof struct {
+	repo command.BlockChainRepository
+}
+
+func NewGetTransactionProof(repo command.BlockChainRepository) GetTransactionProof {
+	return &getTransactionProof{repo: repo}
+}
+
+func (g *getTransactionProof) Get(id transaction.ID) (TransactionProof, error) {
+	chain, err := g.repo.GetChain()
+	if err != nil {
+		return TransactionProof{}, err
+	}
+	block, err := chain.GetBlockByTransactionID(id)
+	if err != nil {
+		return TransactionProof{}, err
+	}
+
+	proof, err := blockchain.NewMerkleProof(block.Transactions, id)
+	if err != nil {
+		return TransactionProof{}, err
+	}
+	return TransactionProof{Header: block.Header, Proof: proof}, nil
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/command"
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// UnspentProof is an unspent output of an address, sent along with the transaction creating it and the proof that
+// the transaction is in a block. A light client checks it against the headers alone: the ID of the transaction
+// commits to the output, the Merkle root of the header commits to the transaction.
+type UnspentProof struct {
+	OutputIndex int
+	Transaction transaction.Transaction
+	TransactionProof
+}
+
+type GetUnspentProofs interface {
+	Get(address string) ([]UnspentProof, error)
+}
+
+type getUnspentProofs struct {
+	repo    command.BlockChainRepository
+	unspent transaction.UnspentOutputRepository
+}
+
+func NewGetUnspentProofs(repo command.BlockChainRepository, unspent transaction.UnspentOutputRepository) GetUnspentProofs {
+	return &getUnspentProofs{repo: repo, unspent: unspent}
+}
+
+func (g *getUnspentProofs) Get(address string) ([]UnspentProof, error) {
+	outputs, err := g.unspent.GetByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := g.repo.GetChain()
+	if err != nil {
+		return nil, err
+	}
+	proofs := make([]UnspentProof, 0, len(outputs))
+	for _, output := range outputs {
+		block, err := chain.GetBlockByTransactionID(output.OutputID())
+		if err != nil {
+			return nil, fmt.Errorf("could not find the block of an unspent output: %w", err)
+		}
+		proof, err := blockchain.NewMerkleProof(block.Transactions, output.OutputID())
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range block.Transactions {
+			if tx.ID() == output.OutputID() {
+				proofs = append(proofs, UnspentProof{
+					OutputIndex:      output.OutputIndex(),
+					Transaction:      tx,
+					TransactionProof: TransactionProof{Header: block.Header, Proof: proof},
+				})
+				break
+			}
+		}
+	}
+	return proofs, nil
+}
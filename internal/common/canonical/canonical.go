@@ -0,0 +1,219 @@
+// Package canonical is the one binary encoding of the chain data. The blocks and the transactions are hashed, signed,
+// persisted and sent to the peers in it, so every value has to have exactly one encoding: the integers are big-endian
+// of a fixed width, the byte strings and the lists are prefixed with their length, and the messages start with
+// the version of the encoding.
+package canonical
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Version of the encoding, which every message starts with.
+const Version byte = 1
+
+// MediaType is the content type of the messages sent over HTTP.
+const MediaType = "application/vnd.eecoin.canonical"
+
+var (
+	UnsupportedVersion = errors.New("unsupported canonical encoding version")
+	Truncated          = errors.New("canonical encoding truncated")
+	TrailingBytes      = errors.New("canonical encoding followed by trailing bytes")
+	LengthNotValid     = errors.New("canonical encoding length not valid")
+	BoolNotValid       = errors.New("canonical encoding boolean not valid")
+)
+
+// Marshaler is a value which has a canonical encoding.
+type Marshaler interface {
+	MarshalCanonical(e *Encoder)
+}
+
+// Unmarshaler is a value which can be read back from its canonical encoding.
+type Unmarshaler interface {
+	UnmarshalCanonical(d *Decoder)
+}
+
+// Marshal the value into a message, prefixed with the version of the encoding.
+func Marshal(v Marshaler) []byte {
+	e := NewEncoder()
+	v.MarshalCanonical(e)
+	return e.Encoded()
+}
+
+// Unmarshal the message into the value. The whole message has to be consumed.
+func Unmarshal(data []byte, v Unmarshaler) error {
+	d, err := NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.UnmarshalCanonical(d)
+	return d.Finish()
+}
+
+// Encoder writes the canonical encoding, starting with the version.
+type Encoder struct {
+	buf []byte
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{buf: []byte{Version}}
+}
+
+// Encoded returns everything written so far.
+func (e *Encoder) Encoded() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Uint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+// Bool writes true as one and false as zero.
+func (e *Encoder) Bool(v bool) {
+	if v {
+		e.Uint8(1)
+	} else {
+		e.Uint8(0)
+	}
+}
+
+func (e *Encoder) Uint32(v uint32) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, v)
+}
+
+func (e *Encoder) Uint64(v uint64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+}
+
+// Int64 writes the two's complement of the value, the ints of the domain are written this way as well.
+func (e *Encoder) Int64(v int64) {
+	e.Uint64(uint64(v))
+}
+
+// Length writes how many elements a list has, the elements follow.
+func (e *Encoder) Length(n int) {
+	e.Uint32(uint32(n))
+}
+
+func (e *Encoder) Bytes(v []byte) {
+	e.Length(len(v))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *Encoder) String(v string) {
+	e.Length(len(v))
+	e.buf = append(e.buf, v...)
+}
+
+// Decoder reads the canonical encoding. The first error sticks, after it every read returns the zero value,
+// so that the caller can check the error once it is done.
+type Decoder struct {
+	data []byte
+	err  error
+}
+
+// NewDecoder reads the version of the message and prepares reading the rest of it.
+func NewDecoder(data []byte) (*Decoder, error) {
+	if len(data) == 0 {
+		return nil, Truncated
+	}
+	if data[0] != Version {
+		return nil, fmt.Errorf("%w: %d", UnsupportedVersion, data[0])
+	}
+	return &Decoder{data: data[1:]}, nil
+}
+
+// Err is the first error the decoder ran into.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Fail stops the decoding with the error, unless it failed already.
+func (d *Decoder) Fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// Finish checks that the message was read without errors and up to its end.
+func (d *Decoder) Finish() error {
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%w: %d", TrailingBytes, len(d.data))
+	}
+	return d.err
+}
+
+func (d *Decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data) {
+		d.err = Truncated
+		return nil
+	}
+	taken := d.data[:n]
+	d.data = d.data[n:]
+	return taken
+}
+
+func (d *Decoder) Uint8() uint8 {
+	b := d.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// Bool reads a byte which has to be either one or zero, so that the value has one encoding only.
+func (d *Decoder) Bool() bool {
+	b := d.Uint8()
+	if d.err == nil && b > 1 {
+		d.err = fmt.Errorf("%w: %d", BoolNotValid, b)
+		return false
+	}
+	return b == 1
+}
+
+func (d *Decoder) Uint32() uint32 {
+	b := d.take(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *Decoder) Uint64() uint64 {
+	b := d.take(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *Decoder) Int64() int64 {
+	return int64(d.Uint64())
+}
+
+// Length reads how many elements a list has. As every element takes at least a byte, a list cannot be longer
+// than what is left of the message, which keeps a forged length from making the caller allocate too much.
+func (d *Decoder) Length() int {
+	n := d.Uint32()
+	if d.err == nil && int64(n) > int64(len(d.data)) {
+		d.err = fmt.Errorf("%w: %d elements with %d bytes left", LengthNotValid, n, len(d.data))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *Decoder) Bytes() []byte {
+	b := d.take(d.Length())
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (d *Decoder) String() string {
+	return string(d.take(d.Length()))
+}
@@ -0,0 +1,104 @@
+package canonical
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sample struct {
+	small  uint8
+	nonce  uint32
+	amount int64
+	names  []string
+	raw    []byte
+	flag   bool
+}
+
+func (s sample) MarshalCanonical(e *Encoder) {
+	e.Uint8(s.small)
+	e.Uint32(s.nonce)
+	e.Int64(s.amount)
+	e.Length(len(s.names))
+	for _, name := range s.names {
+		e.String(name)
+	}
+	e.Bytes(s.raw)
+	e.Bool(s.flag)
+}
+
+func (s *sample) UnmarshalCanonical(d *Decoder) {
+	s.small = d.Uint8()
+	s.nonce = d.Uint32()
+	s.amount = d.Int64()
+	s.names = make([]string, d.Length())
+	for i := range s.names {
+		s.names[i] = d.String()
+	}
+	s.raw = d.Bytes()
+	s.flag = d.Bool()
+}
+
+func TestMarshal_golden(t *testing.T) {
+	// given
+	s := sample{small: 7, nonce: 0x01020304, amount: -2, names: []string{"ab", ""}, raw: []byte{0xff}, flag: true}
+
+	// when
+	encoded := Marshal(s)
+
+	// then
+	assert.Equal(t, ""+
+		"01"+ // version
+		"07"+ // small
+		"01020304"+ // nonce
+		"fffffffffffffffe"+ // amount
+		"00000002"+ // two names
+		"00000002"+"6162"+ // "ab"
+		"00000000"+ // ""
+		"00000001"+"ff"+ // raw
+		"01", // flag
+		hex.EncodeToString(encoded))
+}
+
+func TestUnmarshal_shouldRoundTrip(t *testing.T) {
+	// given
+	s := sample{small: 7, nonce: 0x01020304, amount: -2, names: []string{"ab", ""}, raw: []byte{0xff}, flag: true}
+
+	// when
+	var decoded sample
+	err := Unmarshal(Marshal(s), &decoded)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, s, decoded)
+}
+
+func TestUnmarshal_shouldReject(t *testing.T) {
+	valid := Marshal(sample{names: []string{"ab"}})
+
+	tt := []struct {
+		description string
+		data        []byte
+		expected    error
+	}{
+		{description: "empty", data: nil, expected: Truncated},
+		{description: "unknown version", data: append([]byte{2}, valid[1:]...), expected: UnsupportedVersion},
+		{description: "truncated", data: valid[:len(valid)-2], expected: Truncated},
+		{description: "trailing bytes", data: append(append([]byte{}, valid...), 0), expected: TrailingBytes},
+		{description: "boolean not valid", data: append(append([]byte{}, valid[:len(valid)-1]...), 2), expected: BoolNotValid},
+		{description: "forged length", data: append(append([]byte{}, valid[:14]...), 0xff, 0xff, 0xff, 0xff), expected: LengthNotValid},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			var decoded sample
+			err := Unmarshal(tc.data, &decoded)
+
+			// then
+			assert.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package chaincfg
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var UnknownNetwork = errors.New("unknown network")
+
+// ChainParams describe a network: its genesis block, how often the blocks should come, how much they mint
+// and where the nodes listen. Every network has its own genesis block, so the nodes of different networks
+// never accept each other's blocks, nor the transactions spending their outputs.
+type ChainParams struct {
+	Name string
+	Port int
+	// ChainID is signed along with every input, so that a transaction cannot be replayed on another network.
+	ChainID uint32
+
+	GenesisTimestampMillis int64
+	GenesisAddress         string // public marshaled x509 PKIX
+	GenesisAmount          int
+	GenesisDifficulty      int
+
+	DifficultyAdjustmentInterval int
+	TargetBlockTimeMillis        int64
+
+	InitialSubsidy  int
+	HalvingInterval int
+
+	// MaxBlockSize is how many bytes the canonical encoding of the transactions of a block can take up.
+	MaxBlockSize int
+	// MaxBlockTransactions is how many transactions a block can hold, its coinbase included.
+	MaxBlockTransactions int
+
+	// PowAlgorithm names the hash the blocks are mined with, see the Pow constants of the blockchain domain.
+	PowAlgorithm string
+
+	// MineOnDemand turns the continuous mining off, the blocks are only generated when asked for.
+	MineOnDemand bool
+	// NoRetargeting keeps the difficulty of the genesis block for the whole chain.
+	NoRetargeting bool
+}
+
+var MainNet = ChainParams{
+	Name:                         "mainnet",
+	Port:                         22137,
+	ChainID:                      1,
+	GenesisTimestampMillis:       time.Date(2024, 11, 16, 20, 23, 0, 0, time.UTC).UnixMilli(),
+	GenesisAddress:               "3059301306072a8648ce3d020106082a8648ce3d03010703420004376119d02e6b95174f1c6af6bdc26c4280036104909fc8025dd3ebf8ed524e5abe265b67c1102edd0204ebdc3ab8556fe979be13a51526cea0d414b133061ec3",
+	GenesisAmount:                10000,
+	GenesisDifficulty:            9,
+	DifficultyAdjustmentInterval: 10,
+	TargetBlockTimeMillis:        100 * 60,
+	InitialSubsidy:               100,
+	HalvingInterval:              100_000,
+	MaxBlockSize:                 1_000_000,
+	MaxBlockTransactions:         5_000,
+	PowAlgorithm:                 "sha256",
+}
+
+// TestNet mines with a memory-hard hash, so that nobody takes it over with a few GPUs.
+var TestNet = ChainParams{
+	Name:                         "testnet",
+	Port:                         22138,
+	ChainID:                      2,
+	GenesisTimestampMillis:       time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC).UnixMilli(),
+	GenesisAddress:               "3059301306072a8648ce3d020106082a8648ce3d03010703420004619863ad88baa73c3a53c4eecc2b99c0551d3311779ab7a70e0ca1eef6c6089e9873a744706e6df1b91010ef1add80837b122f15e864e4f1aebc52a93c2ef2a9",
+	GenesisAmount:                10000,
+	GenesisDifficulty:            9,
+	DifficultyAdjustmentInterval: 10,
+	TargetBlockTimeMillis:        100 * 60,
+	InitialSubsidy:               100,
+	HalvingInterval:              100_000,
+	MaxBlockSize:                 1_000_000,
+	MaxBlockTransactions:         5_000,
+	PowAlgorithm:                 "argon2id",
+}
+
+// RegTest is meant for local testing. The blocks are only mined when asked for, at the lowest difficulty,
+// and the subsidy halves quickly.
+var RegTest = ChainParams{
+	Name:                         "regtest",
+	Port:                         22139,
+	ChainID:                      3,
+	GenesisTimestampMillis:       time.Date(2024, 12, 1, 12, 0, 0, 0, time.UTC).UnixMilli(),
+	GenesisAddress:               "3059301306072a8648ce3d020106082a8648ce3d030107034200049f6343f47b9d3737bceaea11bd70f498ce972aa35f5da114f42426c7927d62031798864b7a4ee5196143bbf0cae0ae2abd172e97b87b3badf47fa0771a893148",
+	GenesisAmount:                10000,
+	GenesisDifficulty:            2,
+	DifficultyAdjustmentInterval: 10,
+	TargetBlockTimeMillis:        100 * 60,
+	InitialSubsidy:               100,
+	HalvingInterval:              150,
+	MaxBlockSize:                 1_000_000,
+	MaxBlockTransactions:         5_000,
+	PowAlgorithm:                 "sha256",
+	MineOnDemand:                 true,
+	NoRetargeting:                true,
+}
+
+var active atomic.Pointer[ChainParams]
+
+func init() {
+	Use(MainNet)
+}
+
+// ByName finds the profile of the network with the given name.
+func ByName(name string) (ChainParams, error) {
+	for _, params := range []ChainParams{MainNet, TestNet, RegTest} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+	return ChainParams{}, fmt.Errorf("%w: %q", UnknownNetwork, name)
+}
+
+// Use makes the node follow the given network. It should be called once, before any block is created.
+func Use(params ChainParams) {
+	active.Store(&params)
+}
+
+// Active returns the parameters of the network the node follows, mainnet unless told otherwise.
+func Active() ChainParams {
+	return *active.Load()
+}
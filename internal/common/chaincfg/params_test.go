@@ -0,0 +1,36 @@
+package chaincfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByName(t *testing.T) {
+	tt := []struct {
+		name     string
+		expected ChainParams
+		err      error
+	}{
+		{name: "mainnet", expected: MainNet},
+		{name: "testnet", expected: TestNet},
+		{name: "regtest", expected: RegTest},
+		{name: "", err: UnknownNetwork},
+		{name: "simnet", err: UnknownNetwork},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			params, err := ByName(tc.name)
+
+			// then
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, params)
+		})
+	}
+}
+
+func TestActive_shouldDefaultToMainNet(t *testing.T) {
+	assert.Equal(t, MainNet, Active())
+}
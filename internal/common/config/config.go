@@ -5,12 +5,18 @@ import (
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 )
 
 type Config struct {
 	Peers       Peers       `yaml:"peers"`
 	Log         Log         `yaml:"log"`
 	Persistence Persistence `yaml:"persistence"`
+	Sync        Sync        `yaml:"sync"`
+	Chain       Chain       `yaml:"chain"`
+	Mining      Mining      `yaml:"mining"`
+	Pool        Pool        `yaml:"pool"`
+	Mempool     Mempool     `yaml:"mempool"`
 }
 
 type Peers struct {
@@ -26,7 +32,63 @@ type Log struct {
 type Persistence struct {
 	ChainFilePath      string        `yaml:"chainPath" env:"CHAIN_FILE_PATH" env-default:"/etc/eecoin/chain"`
 	UpdateFileDuration time.Duration `yaml:"updateFileDuration" env:"CHAIN_UPDATE_FILE_DURATION" env-default:"1m"`
+	ChainFileBackups   int           `yaml:"chainBackups" env:"CHAIN_FILE_BACKUPS" env-default:"3"` // previous snapshots kept next to the chain file
 	SelfKey            string        `yaml:"selfKey" env:"SELF_KEY" env-default:"3059301306072a8648ce3d020106082a8648ce3d03010703420004fd957c299f6532aa445fc33f3fc87a7e9d5b8e32e0e9faaf8e38f706afdb6751a127cefe9e07fcca442e1053956fefdcb3bd8b412e7aade982638a3792890ed0"`
+	Engine             string        `yaml:"engine" env:"CHAIN_STORAGE_ENGINE" env-default:"file"`
+	DatabasePath       string        `yaml:"databasePath" env:"CHAIN_DATABASE_PATH" env-default:"/etc/eecoin/chain.db"`
+}
+
+const (
+	StorageEngineFile = "file"
+	StorageEngineBolt = "bolt"
+)
+
+// Bolt tells whether the chain and the unspent outputs should be kept in the embedded database,
+// rather than in memory with the chain written to a file every UpdateFileDuration.
+func (p *Persistence) Bolt() bool {
+	return p.Engine == StorageEngineBolt
+}
+
+const (
+	SyncModeFull         = "full"
+	SyncModeHeadersFirst = "headers-first"
+)
+
+type Sync struct {
+	Mode string `yaml:"mode" env:"SYNC_MODE" env-default:"full"`
+}
+
+// HeadersFirst tells whether the node should download and verify the headers before fetching the blocks.
+func (s *Sync) HeadersFirst() bool {
+	return s.Mode == SyncModeHeadersFirst
+}
+
+type Mining struct {
+	Enabled bool `yaml:"enabled" env:"MINING_ENABLED" env-default:"true"`
+	Workers int  `yaml:"workers" env:"MINING_WORKERS" env-default:"0"` // one per CPU when not positive
+}
+
+type Pool struct {
+	Enabled         bool   `yaml:"enabled" env:"POOL_ENABLED" env-default:"false"`
+	ShareDifficulty int    `yaml:"shareDifficulty" env:"POOL_SHARE_DIFFICULTY" env-default:"6"`
+	Window          int    `yaml:"window" env:"POOL_WINDOW" env-default:"1000"`
+	TCPAddress      string `yaml:"tcpAddress" env:"POOL_TCP_ADDRESS"` // workers only speak HTTP when empty
+}
+
+type Mempool struct {
+	JournalPath  string        `yaml:"journalPath" env:"MEMPOOL_JOURNAL_PATH" env-default:"/etc/eecoin/mempool.journal"` // the pool is only kept in memory when empty
+	MaxAge       time.Duration `yaml:"maxAge" env:"MEMPOOL_MAX_AGE" env-default:"336h"`                                  // transactions never expire when zero
+	MaxSize      int           `yaml:"maxSize" env:"MEMPOOL_MAX_SIZE" env-default:"64000000"`                            // bytes, the pool is not capped when zero
+	ReplaceByFee bool          `yaml:"replaceByFee" env:"MEMPOOL_REPLACE_BY_FEE" env-default:"false"`                    // opt-in, a transaction paying more replaces the replaceable ones it conflicts with
+}
+
+type Chain struct {
+	Network string `yaml:"network" env:"NETWORK" env-default:"mainnet"`
+}
+
+// Params returns the parameters of the network the node should follow.
+func (c *Chain) Params() (chaincfg.ChainParams, error) {
+	return chaincfg.ByName(c.Network)
 }
 
 func (l *Log) LevelIfSet() (slog.Level, error) {
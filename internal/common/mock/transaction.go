@@ -84,6 +84,27 @@ func (r *PoolRepository) Get(id transaction.ID) (*transaction.Transaction, error
 	return tx, nil
 }
 
+func (r *PoolRepository) SpentBy(outputID transaction.ID, outputIndex int) (transaction.ID, bool) {
+	r.Called++
+	for id, tx := range r.Transactions {
+		for _, in := range tx.Inputs() {
+			if in.OutputID() == outputID && in.OutputIndex() == outputIndex {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r *PoolRepository) Size() int {
+	r.Called++
+	size := 0
+	for _, tx := range r.Transactions {
+		size += tx.Size()
+	}
+	return size
+}
+
 func NewPoolRepository() *PoolRepository {
 	return &PoolRepository{
 		Transactions: make(map[transaction.ID]*transaction.Transaction),
@@ -21,7 +21,8 @@ type Commands struct {
 }
 
 type Queries struct {
-	GetPeers query.GetPeers
+	GetPeers        query.GetPeers
+	GetHealthyPeers query.GetPeers
 }
 
 func NewComponent(peersFile io.ReadCloser) (Component, error) {
@@ -38,7 +39,8 @@ func NewComponent(peersFile io.ReadCloser) (Component, error) {
 
 	return Component{
 		Queries: Queries{
-			GetPeers: query.NewGetPeers(context),
+			GetPeers:        query.NewGetPeers(context),
+			GetHealthyPeers: query.NewGetHealthyPeers(context),
 		},
 		Commands: Commands{
 			SendPing:   command.NewSendPingHandler(sender, context),
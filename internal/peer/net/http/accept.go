@@ -1,16 +1,19 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/patrykferenc/eecoin/internal/peer/command"
 )
 
 func getPing(acceptPingHandler command.AcceptPingHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		hostIP := strings.Split(r.RemoteAddr, ":")[0]                                                             // TODO: handle ips better - maybe use X-Forwarded-For or get it from the request
-		if err := acceptPingHandler.Handle(command.AcceptPing{Host: "http://" + hostIP + ":22137"}); err != nil { // TODO: make port configurable (and clean up the rest)
+		hostIP := strings.Split(r.RemoteAddr, ":")[0] // TODO: handle ips better - maybe use X-Forwarded-For or get it from the request
+		host := fmt.Sprintf("http://%s:%d", hostIP, chaincfg.Active().Port)
+		if err := acceptPingHandler.Handle(command.AcceptPing{Host: host}); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
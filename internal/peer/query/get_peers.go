@@ -24,3 +24,23 @@ func (q *getPeersQuery) Get() ([]string, error) {
 
 	return peerAddresses, nil
 }
+
+type getHealthyPeersQuery struct {
+	repo peer.PeerContext
+}
+
+// NewGetHealthyPeers returns a query for the peers which answered the last ping.
+func NewGetHealthyPeers(repo peer.PeerContext) GetPeers {
+	return &getHealthyPeersQuery{repo: repo}
+}
+
+func (q *getHealthyPeersQuery) Get() ([]string, error) {
+	peers := q.repo.Peers().Healthy()
+
+	peerAddresses := make([]string, len(peers))
+	for i, peer := range peers {
+		peerAddresses[i] = peer.Host
+	}
+
+	return peerAddresses, nil
+}
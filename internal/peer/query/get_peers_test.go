@@ -17,6 +17,19 @@ func TestShouldGetPeers(t *testing.T) {
 	assert.Equal(t, []string{"10.1.2.3:8080"}, peerAddresses)
 }
 
+func TestShouldGetOnlyHealthyPeers(t *testing.T) {
+	peers := peer.NewPeers([]*peer.Peer{
+		{Host: "10.1.2.3:8080", Status: peer.StatusHealthy},
+		{Host: "10.1.2.4:8080", Status: peer.StatusUnhealthy},
+		{Host: "10.1.2.5:8080", Status: peer.StatusUnknown},
+	})
+	query := query.NewGetHealthyPeers(&mockedPeerContext{peers: peers})
+
+	peerAddresses, err := query.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.1.2.3:8080"}, peerAddresses)
+}
+
 type mockedPeerContext struct {
 	peers *peer.Peers
 }
@@ -1,6 +1,7 @@
 package application
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -13,91 +14,165 @@ type TransactionPoolRetriever interface {
 	Get(peers []string) ([]transaction.Transaction, error)
 }
 
-type UpdatableTransactionPoolRepository interface {
-	transaction.PoolRepository
-	Set(transactions []transaction.Transaction) error
+// TransactionPool admits the transactions to the pool under its policy, see transaction.Pool.
+type TransactionPool interface {
+	Add(tx *transaction.Transaction) error
 }
 
-type UnspentOutputRetriever interface {
-	Get(peers []string) ([]transaction.UnspentOutput, error)
+type UpdatableUnspentOutputRepository interface {
+	transaction.UnspentOutputRepository
+	Add(unspentOutputs ...transaction.UnspentOutput) error
+	Remove(unspentOutputs ...transaction.UnspentOutput) error
+}
+
+// UnspentOutputUpdater brings the unspent outputs in step with the main chain.
+type UnspentOutputUpdater interface {
+	Update() error
 }
 
 type TransactionUpdater struct {
-	pool             UpdatableTransactionPoolRepository
-	poolRetriever    TransactionPoolRetriever
-	unspent          transaction.UnspentOutputRepository
-	unspentRetriever UnspentOutputRetriever
-	peers            query.GetPeers
-	bc               BlockChainRepository
+	pool          transaction.PoolRepository
+	admission     TransactionPool
+	poolRetriever TransactionPoolRetriever
+	unspent       UnspentOutputUpdater
+	peers         query.GetPeers
 }
 
 type BlockChainRepository interface { // TODO#30 make not public, refactor to not return the blockchain as a whole (unsafe to read)
-	GetChain() blockchain.BlockChain
+	GetChain() (blockchain.BlockChain, error)
 }
 
 func NewTransactionUpdater(
-	pool UpdatableTransactionPoolRepository,
+	pool transaction.PoolRepository,
+	admission TransactionPool,
 	poolRetriever TransactionPoolRetriever,
-	unspent transaction.UnspentOutputRepository,
-	unspentRetriever UnspentOutputRetriever,
-	bc BlockChainRepository,
+	unspent UnspentOutputUpdater,
 	peers query.GetPeers,
 ) *TransactionUpdater {
 	return &TransactionUpdater{
-		pool:             pool,
-		poolRetriever:    poolRetriever,
-		unspent:          unspent,
-		unspentRetriever: unspentRetriever,
-		peers:            peers,
-		bc:               bc,
+		pool:          pool,
+		admission:     admission,
+		poolRetriever: poolRetriever,
+		unspent:       unspent,
+		peers:         peers,
 	}
 }
 
-func (u *TransactionUpdater) UpdateFromRemote() error {
+// UpdatePoolFromRemote fetches the transaction pool of the peers and admits the transactions the policy of the pool
+// admits. Unspent outputs are never taken from the peers, they are always rebuilt from the local chain.
+func (u *TransactionUpdater) UpdatePoolFromRemote() error {
 	peers, err := u.peers.Get()
 	if err != nil {
 		return err
 	}
-
-	remoteUnspent, err := u.unspentRetriever.Get(peers)
-	if err != nil {
-		return err
-	}
-	err = u.unspent.Set(remoteUnspent)
-	if err != nil {
-		return err
+	if len(peers) == 0 {
+		return nil
 	}
 
 	transactions, err := u.poolRetriever.Get(peers)
 	if err != nil {
 		return err
 	}
-	err = u.pool.Set(transactions)
+	admitted, err := u.admit(transactions)
 	if err != nil {
 		return err
 	}
-	slog.Info("transaction pool updated from remote", "poolCount", len(transactions), "unspentCount", len(remoteUnspent))
+	slog.Info("transaction pool updated from remote", "poolCount", len(transactions), "admitted", admitted)
 	return nil
 }
 
+// admit adds the transactions to the pool through its policy. They come in no particular order, so the ones spending
+// an output which is not unspent are tried again once the others are admitted, as they can spend from those.
+// The transactions the pool rejects are left out, only the other errors are returned.
+func (u *TransactionUpdater) admit(transactions []transaction.Transaction) (int, error) {
+	admitted := 0
+	for len(transactions) > 0 {
+		var waiting []transaction.Transaction
+		for _, tx := range transactions {
+			err := u.admission.Add(&tx)
+			var rejection *transaction.Rejection
+			switch {
+			case err == nil:
+				admitted++
+			case errors.As(err, &rejection) && rejection.Reason == transaction.OutputNotUnspent:
+				waiting = append(waiting, tx)
+			case errors.As(err, &rejection):
+				slog.Debug("transaction not admitted to the pool", "id", tx.ID(), "reason", err)
+			default:
+				return admitted, err
+			}
+		}
+		if len(waiting) == len(transactions) {
+			break
+		}
+		transactions = waiting
+	}
+	return admitted, nil
+}
+
+// UpdateFromBlockchain brings the unspent outputs up to date with the local chain.
 func (u *TransactionUpdater) UpdateFromBlockchain() error {
-	chain := u.bc.GetChain()
+	if err := u.unspent.Update(); err != nil {
+		return fmt.Errorf("error updating unspent from blockchain: %w", err)
+	}
+
+	slog.Info("unspent updated from blockchain")
+	return nil
+}
 
-	// update unspent from the blockchain
-	unspent := make([]transaction.UnspentOutput, 0)
-	for _, block := range chain.Blocks {
+// ConnectBlock updates the unspent outputs after a block was added to the chain and drops its transactions from the pool.
+func (u *TransactionUpdater) ConnectBlock(block blockchain.Block) error {
+	if err := u.unspent.Update(); err != nil {
+		return fmt.Errorf("error updating unspent after block %d: %w", block.Index, err)
+	}
+
+	mined := make([]transaction.ID, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		mined[i] = tx.ID()
+	}
+	if err := u.pool.Remove(mined...); err != nil {
+		return fmt.Errorf("error removing mined transactions from the pool: %w", err)
+	}
+	return nil
+}
+
+// Reorganize updates the unspent outputs after the chain switched to another branch.
+// Transactions from the disconnected blocks which did not make it into the new branch are put back into the pool,
+// as long as its policy admits them.
+func (u *TransactionUpdater) Reorganize(disconnected, connected []blockchain.Block) error {
+	if err := u.unspent.Update(); err != nil {
+		return fmt.Errorf("error updating unspent after reorganization: %w", err)
+	}
+
+	confirmed := make(map[transaction.ID]struct{})
+	for _, block := range connected {
 		for _, tx := range block.Transactions {
-			for i, output := range tx.Outputs() {
-				unspent = append(unspent, transaction.NewUnspentOutput(tx.ID(), i, output.Amount(), output.Address()))
+			confirmed[tx.ID()] = struct{}{}
+		}
+	}
+
+	toRemove := make([]transaction.ID, 0, len(confirmed))
+	for id := range confirmed {
+		toRemove = append(toRemove, id)
+	}
+	if err := u.pool.Remove(toRemove...); err != nil {
+		return fmt.Errorf("error removing confirmed transactions from the pool: %w", err)
+	}
+
+	var orphans []transaction.Transaction
+	for _, block := range disconnected {
+		for _, tx := range block.Transactions {
+			if _, ok := confirmed[tx.ID()]; ok || tx.IsCoinbase() || len(tx.Inputs()) == 0 {
+				continue
 			}
+			orphans = append(orphans, tx)
 		}
 	}
-	err := u.unspent.Set(unspent)
+	orphaned, err := u.admit(orphans)
 	if err != nil {
-		return fmt.Errorf("error updating unspent from blockchain: %w", err)
+		return fmt.Errorf("error returning transactions to the pool: %w", err)
 	}
 
-	slog.Info("unspent updated from blockchain", "unspentCount", len(unspent))
-
+	slog.Info("unspent updated after reorganization", "disconnected", len(disconnected), "connected", len(connected), "returnedToPool", orphaned)
 	return nil
 }
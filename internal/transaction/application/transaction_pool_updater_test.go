@@ -0,0 +1,75 @@
+package application_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/application"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionUpdater_shouldAdmitTheRemotePoolThroughThePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	// given an owner of an unspent output
+	owner, ownerAddr := newKey(t)
+	funding := transaction.NewUnspentOutput("funding", 0, 100, ownerAddr)
+	unspent := inmem.NewUnspentOutputRepository()
+	require.NoError(t, unspent.Add(funding))
+
+	// and the pool of a peer, holding a child before its parent, a forged transaction and a double spend
+	receiver, receiverAddr := newKey(t)
+	parent, err := transaction.NewWithFee(receiverAddr, ownerAddr, 60, 5, owner, unspent)
+	require.NoError(t, err)
+	parentOutputs := inmem.NewUnspentOutputRepository()
+	require.NoError(t, parentOutputs.Add(transaction.UnspentOutputsFrom([]transaction.Transaction{*parent})...))
+	child, err := transaction.NewWithFee("someone", receiverAddr, 50, 10, receiver, parentOutputs)
+	require.NoError(t, err)
+	forged, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(parent.ID(), 1, child.Inputs()[0].Signature())},
+		[]*transaction.Output{transaction.NewOutput(35, "thief")},
+	)
+	require.NoError(t, err)
+	conflicting, err := transaction.New("someone else", ownerAddr, 100, owner, unspent)
+	require.NoError(t, err)
+	retriever := &poolRetriever{transactions: []transaction.Transaction{*child, *forged, *parent, *conflicting}}
+
+	repository := inmem.NewPoolRepository()
+	pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{})
+	updater := application.NewTransactionUpdater(repository, pool, retriever, nil, mock.NewPeers([]string{"peer"}))
+
+	// when
+	err = updater.UpdatePoolFromRemote()
+
+	// then only the transactions the pool admits are in it
+	assert.NoError(err)
+	assert.True(repository.Exists(parent.ID()))
+	assert.True(repository.Exists(child.ID()))
+	assert.False(repository.Exists(forged.ID()))
+	assert.False(repository.Exists(conflicting.ID()))
+}
+
+type poolRetriever struct {
+	transactions []transaction.Transaction
+}
+
+func (r *poolRetriever) Get([]string) ([]transaction.Transaction, error) {
+	return r.transactions, nil
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return key, hex.EncodeToString(raw)
+}
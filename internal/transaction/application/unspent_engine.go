@@ -0,0 +1,111 @@
+package application
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+// UnspentOutputEngine keeps the unspent outputs in step with the main chain. It connects the blocks one by one,
+// keeping the undo data of each of them, so that the blocks can be disconnected again when the chain gets reorganized.
+// Events can arrive out of order, so on every update the engine compares the blocks it connected with the current chain.
+type UnspentOutputEngine struct {
+	unspent   UpdatableUnspentOutputRepository
+	bc        BlockChainRepository
+	connected []connectedBlock
+	mu        sync.Mutex
+}
+
+type connectedBlock struct {
+	hash string
+	undo transaction.Undo
+}
+
+func NewUnspentOutputEngine(unspent UpdatableUnspentOutputRepository, bc BlockChainRepository) *UnspentOutputEngine {
+	return &UnspentOutputEngine{
+		unspent: unspent,
+		bc:      bc,
+	}
+}
+
+// Update disconnects the blocks which are no longer on the main chain and connects the ones which are missing.
+func (e *UnspentOutputEngine) Update() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	chain, err := e.bc.GetChain()
+	if err != nil {
+		return err
+	}
+
+	common := min(len(e.connected), len(chain.Blocks))
+	for common > 0 && e.connected[common-1].hash != chain.Blocks[common-1].ContentHash {
+		common--
+	}
+
+	disconnected := 0
+	for len(e.connected) > common {
+		if err := e.disconnect(); err != nil {
+			return err
+		}
+		disconnected++
+	}
+
+	connected := 0
+	for _, block := range chain.Blocks[common:] {
+		if err := e.connect(block); err != nil {
+			return fmt.Errorf("error connecting block %d: %w", block.Index, err)
+		}
+		connected++
+	}
+
+	if disconnected > 0 || connected > 0 {
+		slog.Debug("unspent outputs updated", "disconnected", disconnected, "connected", connected, "height", len(e.connected)-1)
+	}
+	return nil
+}
+
+// Rebuild forgets all the unspent outputs and connects the whole chain again.
+func (e *UnspentOutputEngine) Rebuild() error {
+	e.mu.Lock()
+	if err := e.unspent.Set([]transaction.UnspentOutput{}); err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("error clearing unspent outputs: %w", err)
+	}
+	e.connected = nil
+	e.mu.Unlock()
+
+	return e.Update()
+}
+
+func (e *UnspentOutputEngine) connect(block blockchain.Block) error {
+	undo, err := transaction.NewUndo(block.Transactions, e.unspent)
+	if err != nil {
+		return err
+	}
+	if err := e.unspent.Remove(undo.Spent...); err != nil {
+		return err
+	}
+	if err := e.unspent.Add(undo.Created...); err != nil {
+		return err
+	}
+
+	e.connected = append(e.connected, connectedBlock{hash: block.ContentHash, undo: undo})
+	return nil
+}
+
+func (e *UnspentOutputEngine) disconnect() error {
+	last := e.connected[len(e.connected)-1]
+	if err := e.unspent.Remove(last.undo.Created...); err != nil {
+		return fmt.Errorf("error disconnecting block %s: %w", last.hash, err)
+	}
+	if err := e.unspent.Add(last.undo.Spent...); err != nil {
+		return fmt.Errorf("error disconnecting block %s: %w", last.hash, err)
+	}
+
+	e.connected = e.connected[:len(e.connected)-1]
+	return nil
+}
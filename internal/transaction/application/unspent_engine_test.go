@@ -0,0 +1,114 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	blockchaininmem "github.com/patrykferenc/eecoin/internal/blockchain/inmem"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/application"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnspentOutputEngine_shouldFollowChainThroughReorganization(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a chain
+	repo, err := blockchaininmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	chain, err := repo.GetChain()
+	require.NoError(t, err)
+	genesis := chain.GetFirst()
+	genesisTx := genesis.Transactions[0]
+	unspent := inmem.NewUnspentOutputRepository()
+	engine := application.NewUnspentOutputEngine(unspent, repo)
+	require.NoError(t, engine.Update())
+
+	// when a block spending the genesis output connects
+	spend, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(genesisTx.ID(), 0, "")},
+		[]*transaction.Output{transaction.NewOutput(chaincfg.Active().GenesisAmount, "bob")},
+	)
+	require.NoError(t, err)
+	mainBlock := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+100, coinbase(t, "alice", 1), *spend)
+	require.NoError(t, repo.PutBlock(mainBlock))
+	require.NoError(t, engine.Update())
+
+	// then
+	assert.Empty(outputsOf(t, unspent, chaincfg.Active().GenesisAddress))
+	assert.Len(outputsOf(t, unspent, "bob"), 1)
+	assert.Len(outputsOf(t, unspent, "alice"), 1)
+
+	// when the chain switches to a heavier branch without that block
+	sideOne := mineWith(t, []blockchain.Block{genesis}, genesis.TimestampMilis+200, coinbase(t, "carol", 1))
+	sideTwo := mineWith(t, []blockchain.Block{genesis, sideOne}, genesis.TimestampMilis+300, coinbase(t, "carol", 2))
+	require.NoError(t, repo.PutSideBlock(sideOne))
+	require.NoError(t, repo.PutSideBlock(sideTwo))
+	_, _, err = repo.Reorganize(sideTwo)
+	require.NoError(t, err)
+	require.NoError(t, engine.Update())
+
+	// then the spent output is restored from the undo data
+	assert.Equal([]transaction.UnspentOutput{
+		transaction.NewUnspentOutput(genesisTx.ID(), 0, chaincfg.Active().GenesisAmount, chaincfg.Active().GenesisAddress),
+	}, outputsOf(t, unspent, chaincfg.Active().GenesisAddress))
+	assert.Empty(outputsOf(t, unspent, "bob"))
+	assert.Empty(outputsOf(t, unspent, "alice"))
+	assert.Len(outputsOf(t, unspent, "carol"), 2)
+
+	// and when updating again nothing changes
+	require.NoError(t, engine.Update())
+	all, err := unspent.GetAll()
+	require.NoError(t, err)
+	assert.Len(all, 3)
+}
+
+func TestUnspentOutputEngine_shouldRebuild(t *testing.T) {
+	// given
+	repo, err := blockchaininmem.NewBlockChain(&mock.Publisher{})
+	require.NoError(t, err)
+	unspent := inmem.NewUnspentOutputRepository()
+	require.NoError(t, unspent.Set([]transaction.UnspentOutput{transaction.NewUnspentOutput("stale", 0, 1, "someone")}))
+	engine := application.NewUnspentOutputEngine(unspent, repo)
+
+	// when
+	require.NoError(t, engine.Rebuild())
+	require.NoError(t, engine.Rebuild())
+
+	// then
+	all, err := unspent.GetAll()
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+	assert.Equal(t, chaincfg.Active().GenesisAddress, all[0].Address())
+}
+
+func coinbase(t *testing.T, receiver string, height int) transaction.Transaction {
+	t.Helper()
+	tx, err := transaction.NewCoinbase(receiver, height)
+	require.NoError(t, err)
+	return *tx
+}
+
+func mineWith(t *testing.T, blocks []blockchain.Block, timestamp int64, transactions ...transaction.Transaction) blockchain.Block {
+	t.Helper()
+	chain := &blockchain.BlockChain{Blocks: blocks}
+	difficulty, err := blockchain.GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := blockchain.NewChallenge(difficulty, 2)
+	require.NoError(t, err)
+	require.NoError(t, challenge.RollUntilMatchesDifficulty(chain.GetLast(), transactions, timestamp))
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	return block
+}
+
+func outputsOf(t *testing.T, unspent *inmem.UnspentOutputRepository, address string) []transaction.UnspentOutput {
+	t.Helper()
+	outputs, err := unspent.GetByAddress(address)
+	require.NoError(t, err)
+	return outputs
+}
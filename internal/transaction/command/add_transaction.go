@@ -9,20 +9,19 @@ import (
 
 // AddTransaction is a command to add a transaction to the pool
 type AddTransaction struct {
-	ProvidedID string
-	Inputs     []*transaction.Input
-	Outputs    []*transaction.Output
+	ProvidedID  string
+	Inputs      []*transaction.Input
+	Outputs     []*transaction.Output
+	Replaceable bool
 }
 
+// toTransaction builds the transaction, whether it is acceptable is up to the policy of the pool.
 func (c AddTransaction) toTransaction() (*transaction.Transaction, error) {
-	if len(c.Inputs) == 0 {
-		return nil, fmt.Errorf("no inputs provided")
+	newTransaction := transaction.NewFrom
+	if c.Replaceable {
+		newTransaction = transaction.NewReplaceableFrom
 	}
-	if len(c.Outputs) == 0 {
-		return nil, fmt.Errorf("no outputs provided")
-	}
-
-	tx, err := transaction.NewFrom(c.Inputs, c.Outputs)
+	tx, err := newTransaction(c.Inputs, c.Outputs)
 	if err != nil {
 		return nil, fmt.Errorf("error creating transaction: %w", err)
 	}
@@ -40,7 +39,7 @@ type AddTransactionHandler interface {
 }
 
 type addTransactionHandler struct {
-	pool      transaction.Pool
+	pool      *transaction.Pool
 	publisher event.Publisher
 }
 
@@ -49,7 +48,7 @@ func NewAddTransactionHandler(
 	pool *transaction.Pool,
 ) AddTransactionHandler {
 	return &addTransactionHandler{
-		pool:      *pool,
+		pool:      pool,
 		publisher: publisher,
 	}
 }
@@ -65,7 +64,7 @@ func (h *addTransactionHandler) Handle(c AddTransaction) error {
 		return fmt.Errorf("error adding transaction to pool: %w", err)
 	}
 
-	event, err := event.New(transaction.Added{ID: transaction.ID(c.ProvidedID)}, "x.transaction.added")
+	event, err := event.New(transaction.Added{ID: tx.ID()}, "x.transaction.added")
 	if err != nil {
 		return fmt.Errorf("error creating event: %w", err)
 	}
@@ -16,7 +16,7 @@ func TestShouldAddTransaction(t *testing.T) {
 	assert := assert.New(t)
 	// given
 	poolRepository := mock.NewPoolRepository()
-	pool := transaction.NewPool(poolRepository)
+	pool := transaction.NewPool(poolRepository, &mock.UnspentOutputRepository{}, transaction.PoolConfig{})
 	publisher := &mock.Publisher{}
 	handler := command.NewAddTransactionHandler(publisher, pool)
 
@@ -52,7 +52,7 @@ func TestShouldNotAddInvalidTransaction(t *testing.T) {
 	assert := assert.New(t)
 	// given
 	poolRepository := mock.NewPoolRepository()
-	pool := transaction.NewPool(poolRepository)
+	pool := transaction.NewPool(poolRepository, &mock.UnspentOutputRepository{}, transaction.PoolConfig{})
 	publisher := &mock.Publisher{}
 	handler := command.NewAddTransactionHandler(publisher, pool)
 
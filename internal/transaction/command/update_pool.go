@@ -14,11 +14,11 @@ type UpdatePoolHandler interface {
 }
 
 type updatePoolHandler struct {
-	pool transaction.Pool
+	pool *transaction.Pool
 	repo transaction.UnspentOutputRepository
 }
 
-func NewUpdatePoolHandler(pool transaction.Pool, repo transaction.UnspentOutputRepository) UpdatePoolHandler {
+func NewUpdatePoolHandler(pool *transaction.Pool, repo transaction.UnspentOutputRepository) UpdatePoolHandler {
 	return &updatePoolHandler{
 		pool: pool,
 		repo: repo,
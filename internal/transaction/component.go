@@ -30,15 +30,18 @@ type Commands struct {
 
 type Application struct {
 	TransactionUpdater *application.TransactionUpdater
+	Pool               *transaction.Pool
 }
 
 func NewComponent(
 	publisher event.Publisher,
 	poolRepository *inmem.PoolRepository,
+	unspent transaction.UnspentOutputRepository,
+	unspentUpdater application.UnspentOutputUpdater,
+	poolConfig transaction.PoolConfig,
 	getPeers peerquery.GetPeers,
-	blockchainRepo application.BlockChainRepository,
 ) Component {
-	pool := transaction.NewPool(poolRepository)
+	pool := transaction.NewPool(poolRepository, unspent, poolConfig)
 	add := command.NewAddTransactionHandler(
 		publisher,
 		pool,
@@ -48,18 +51,15 @@ func NewComponent(
 		pool,
 		broadcaster,
 	)
-	unspent := inmem.NewUnspentOutputRepository()
 	getUnspent := query.NewGetUnspentOutputs(unspent)
 	getBalance := query.NewGetBalance(unspent)
 
-	unspentClient := http.NewUnspentOutputsRepository("noop")
 	poolClient := &http.TransactionPoolClient{}
 	updater := application.NewTransactionUpdater(
 		poolRepository,
+		pool,
 		poolClient,
-		unspent,
-		unspentClient,
-		blockchainRepo,
+		unspentUpdater,
 		getPeers,
 	)
 
@@ -75,6 +75,7 @@ func NewComponent(
 		},
 		Application: Application{
 			TransactionUpdater: updater,
+			Pool:               pool,
 		},
 	}
 }
@@ -0,0 +1,122 @@
+package transaction_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionCanonicalEncoding_golden(t *testing.T) {
+	spent, err := transaction.NewFrom(nil, []*transaction.Output{transaction.NewOutput(5, "a")})
+	require.NoError(t, err)
+
+	tt := []struct {
+		description string
+		inputs      []*transaction.Input
+		outputs     []*transaction.Output
+		replaceable bool
+		encoded     string
+		id          string
+	}{
+		{
+			description: "no inputs",
+			inputs:      nil,
+			outputs:     []*transaction.Output{transaction.NewOutput(5, "a")},
+			encoded:     "01" + "00000000" + "00000001" + "0000000000000005" + "00000001" + "61",
+			id:          "3820d4dca3301c28b40e0b55bf9e90a84bbdaa5f302d56dca989023ce22a9656",
+		},
+		{
+			description: "signed input",
+			inputs:      []*transaction.Input{transaction.NewInput(spent.ID(), 0, "ab")},
+			outputs:     []*transaction.Output{transaction.NewOutput(3, "b"), transaction.NewOutput(2, "a")},
+			encoded: "01" +
+				"00000001" + "00000020" + hex.EncodeToString([]byte(spent.ID())) + "0000000000000000" + "00000002" + "6162" +
+				"00000002" + "0000000000000003" + "00000001" + "62" + "0000000000000002" + "00000001" + "61" +
+				"00",
+			id: "594befb25771cb239073176e167140d0ab37d500cf0feb409f4defc514bc6c1e",
+		},
+		{
+			description: "replaceable",
+			inputs:      []*transaction.Input{transaction.NewInput(spent.ID(), 0, "ab")},
+			outputs:     []*transaction.Output{transaction.NewOutput(3, "b"), transaction.NewOutput(2, "a")},
+			replaceable: true,
+			encoded: "01" +
+				"00000001" + "00000020" + hex.EncodeToString([]byte(spent.ID())) + "0000000000000000" + "00000002" + "6162" +
+				"00000002" + "0000000000000003" + "00000001" + "62" + "0000000000000002" + "00000001" + "61" +
+				"01",
+			id: "2065bbf35df245df193e54fcd2892eff33233c6879a0e9296939483b7a41002f",
+		},
+		{
+			description: "coinbase",
+			inputs:      []*transaction.Input{transaction.NewInput("", 7, "")},
+			outputs:     []*transaction.Output{transaction.NewOutput(50, "a")},
+			encoded: "01" +
+				"00000001" + "00000000" + "0000000000000007" + "00000000" +
+				"00000001" + "0000000000000032" + "00000001" + "61",
+			id: "1cde8c0debd3367cc425a7d2ef801f2494b033cfb4e8ea34f7d9244b34fa4497",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			newTransaction := transaction.NewFrom
+			if tc.replaceable {
+				newTransaction = transaction.NewReplaceableFrom
+			}
+			tx, err := newTransaction(tc.inputs, tc.outputs)
+			require.NoError(t, err)
+
+			// when
+			encoded, err := tx.MarshalBinary()
+
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, tc.encoded, hex.EncodeToString(encoded))
+			assert.Equal(t, tc.id, hex.EncodeToString([]byte(tx.ID())))
+		})
+	}
+}
+
+func TestTransactionCanonicalEncoding_shouldRoundTrip(t *testing.T) {
+	// given
+	tx, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("spent", 1, "signature")},
+		[]*transaction.Output{transaction.NewOutput(3, "b"), transaction.NewOutput(2, "a")},
+	)
+	require.NoError(t, err)
+	encoded, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	// when
+	var decoded transaction.Transaction
+	err = decoded.UnmarshalBinary(encoded)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, *tx, decoded)
+}
+
+func TestTransactionCanonicalEncoding_shouldNotCommitTheIDToTheSignatures(t *testing.T) {
+	// given
+	unsigned, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("spent", 1, "")},
+		[]*transaction.Output{transaction.NewOutput(3, "b")},
+	)
+	require.NoError(t, err)
+
+	// when
+	signed, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("spent", 1, "signature")},
+		[]*transaction.Output{transaction.NewOutput(3, "b")},
+	)
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, unsigned.ID(), signed.ID())
+	assert.NotEqual(t, canonical.Marshal(unsigned), canonical.Marshal(signed))
+}
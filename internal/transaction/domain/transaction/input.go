@@ -2,10 +2,17 @@ package transaction
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 )
 
 type Input struct {
@@ -22,7 +29,8 @@ func NewInput(outputID ID, outputIndex int, signature string) *Input {
 	}
 }
 
-func (i *Input) sign(signer crypto.Signer, idToSign ID, referencedOutput UnspentOutput) error {
+// sign the input at the given index of the transaction, which spends the referenced output, see sigHash.
+func (i *Input) sign(signer crypto.Signer, tx *Transaction, index int, referencedOutput UnspentOutput) error {
 	ourAddress, err := x509.MarshalPKIXPublicKey(signer.Public())
 	if err != nil {
 		return fmt.Errorf("error marshalling public key: %w", err)
@@ -31,15 +39,64 @@ func (i *Input) sign(signer crypto.Signer, idToSign ID, referencedOutput Unspent
 		return fmt.Errorf("output Addr does not match the signer Addr: %s != %s", ours, referencedOutput.address)
 	}
 
-	s, err := signer.Sign(rand.Reader, []byte(idToSign), crypto.SHA256)
+	digest := sigHash(tx, index, referencedOutput, chaincfg.Active().ChainID)
+	der, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
 	if err != nil {
 		return fmt.Errorf("error signing input: %w", err)
 	}
 
-	i.signature = string(s)
+	s, err := fixedSizeSignature(der, signer.Public())
+	if err != nil {
+		return fmt.Errorf("error encoding signature: %w", err)
+	}
+
+	i.signature = hex.EncodeToString(s)
+	return nil
+}
+
+// fixedSizeSignature converts the ASN.1 signature into r and s padded to the size of the curve and concatenated,
+// 64 bytes for P-256, which is the one form the signatures are kept and verified in.
+func fixedSizeSignature(der []byte, public crypto.PublicKey) ([]byte, error) {
+	publicKey, ok := public.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("only ECDSA keys are supported")
+	}
+
+	var parsed struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, err
+	}
+
+	size := signatureHalfSize(publicKey)
+	s := make([]byte, 2*size)
+	parsed.R.FillBytes(s[:size])
+	parsed.S.FillBytes(s[size:])
+	return s, nil
+}
+
+// verify the signature of the input over the digest. The signature has to be exactly r and s padded to the size of
+// the curve, so that it has one encoding only.
+func (i Input) verify(publicKey *ecdsa.PublicKey, digest []byte) error {
+	s, err := hex.DecodeString(i.signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	size := signatureHalfSize(publicKey)
+	if len(s) != 2*size {
+		return fmt.Errorf("signature must be %d bytes, got %d", 2*size, len(s))
+	}
+
+	r := new(big.Int).SetBytes(s[:size])
+	if !ecdsa.Verify(publicKey, digest, r, new(big.Int).SetBytes(s[size:])) {
+		return errors.New("invalid signature for transaction input")
+	}
 	return nil
 }
 
+func signatureHalfSize(publicKey *ecdsa.PublicKey) int {
+	return (publicKey.Curve.Params().BitSize + 7) / 8
+}
+
 func (i Input) Signature() string {
 	return i.signature
 }
@@ -52,6 +109,18 @@ func (i Input) OutputIndex() int {
 	return i.outputIndex
 }
 
+func (i Input) MarshalCanonical(e *canonical.Encoder) {
+	e.String(string(i.outputID))
+	e.Int64(int64(i.outputIndex))
+	e.String(i.signature)
+}
+
+func (i *Input) UnmarshalCanonical(d *canonical.Decoder) {
+	i.outputID = ID(d.String())
+	i.outputIndex = int(d.Int64())
+	i.signature = d.String()
+}
+
 func (i Input) MarshalBinary() ([]byte, error) {
-	return []byte(fmt.Sprintf("%s%d%s", i.outputID, i.outputIndex, i.signature)), nil
+	return canonical.Marshal(i), nil
 }
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,21 +26,25 @@ func TestSignInput(t *testing.T) {
 	require.NoError(t, err)
 	ourAddress, err := x509.MarshalPKIXPublicKey(privateSender.Public())
 	require.NoError(t, err)
-	// and an ID to sign
-	idToSign := ID("someID") // TODO?
+	// and a transaction to sign
+	tx, err := NewFrom([]*Input{input}, []*Output{NewOutput(10, "receiver")})
+	require.NoError(t, err)
 	// and an unspent output
 	referencedOutput := UnspentOutput{
-		outputID: outputID,
-		address:  hex.EncodeToString(ourAddress),
+		outputID:    outputID,
+		outputIndex: outputIndex,
+		amount:      10,
+		address:     hex.EncodeToString(ourAddress),
 	}
 
 	// when
-	err = input.sign(privateSender, idToSign, referencedOutput)
+	err = tx.inputs[0].sign(privateSender, tx, 0, referencedOutput)
 	assert.NoError(err)
 
-	// then
-	assert.NotEmpty(input.Signature())
-	assert.NotEqual(input.Signature(), signatureOmmitedFromSigning)
+	// then the signature is r and s of 32 bytes each, over the sighash
+	assert.NotEqual(tx.inputs[0].Signature(), signatureOmmitedFromSigning)
+	assert.Len(tx.inputs[0].Signature(), 128)
+	assert.NoError(tx.inputs[0].verify(&privateSender.PublicKey, sigHash(tx, 0, referencedOutput, chaincfg.Active().ChainID)))
 }
 
 func TestSignInputShouldNotSignWhenAddressDoesNotMatch(t *testing.T) {
@@ -53,8 +58,9 @@ func TestSignInputShouldNotSignWhenAddressDoesNotMatch(t *testing.T) {
 	// and a signer
 	privateSender, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	require.NoError(t, err)
-	// and an ID to sign
-	idToSign := ID("someID")
+	// and a transaction to sign
+	tx, err := NewFrom([]*Input{input}, []*Output{NewOutput(10, "receiver")})
+	require.NoError(t, err)
 	// and an unspent output
 	referencedOutput := UnspentOutput{
 		outputID: outputID,
@@ -62,7 +68,7 @@ func TestSignInputShouldNotSignWhenAddressDoesNotMatch(t *testing.T) {
 	}
 
 	// when
-	err = input.sign(privateSender, idToSign, referencedOutput)
+	err = tx.inputs[0].sign(privateSender, tx, 0, referencedOutput)
 
 	// then
 	assert.Error(err)
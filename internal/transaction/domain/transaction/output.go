@@ -1,6 +1,6 @@
 package transaction
 
-import "fmt"
+import "github.com/patrykferenc/eecoin/internal/common/canonical"
 
 type Output struct {
 	amount  int
@@ -34,6 +34,16 @@ func generateOutputsFor(amount int, leftover int, senderAddr string, receiverAdd
 	return outputs
 }
 
+func (o Output) MarshalCanonical(e *canonical.Encoder) {
+	e.Int64(int64(o.amount))
+	e.String(o.address)
+}
+
+func (o *Output) UnmarshalCanonical(d *canonical.Decoder) {
+	o.amount = int(d.Int64())
+	o.address = d.String()
+}
+
 func (o Output) MarshalBinary() ([]byte, error) {
-	return []byte(fmt.Sprintf("%d%s", o.amount, o.address)), nil
+	return canonical.Marshal(o), nil
 }
@@ -0,0 +1,324 @@
+package transaction
+
+import (
+	"container/heap"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+)
+
+// feeRate is what a transaction, or a package of them, pays per byte. It is kept as a fraction to be compared exactly.
+type feeRate struct {
+	fee  int
+	size int
+}
+
+func (r feeRate) less(other feeRate) bool {
+	return r.cmp(other) < 0
+}
+
+// cmp compares the fractions crosswise, the products do not have to fit in an int.
+func (r feeRate) cmp(other feeRate) int {
+	left := new(big.Int).Mul(big.NewInt(int64(r.fee)), big.NewInt(int64(other.size)))
+	right := new(big.Int).Mul(big.NewInt(int64(other.fee)), big.NewInt(int64(r.size)))
+	return left.Cmp(right)
+}
+
+func (r feeRate) String() string {
+	if r.size == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.3f", float64(r.fee)/float64(r.size))
+}
+
+// poolEntry is a transaction of the pool along with what it pays and the transactions of the pool it spends from.
+type poolEntry struct {
+	tx      Transaction
+	fee     int
+	size    int
+	parents []ID
+}
+
+// poolGraph links the transactions of the pool to the transactions of the pool they spend from. The transactions
+// the policy of the pool does not admit on top of the unspent outputs and the pool, see check, cannot be mined,
+// so they are left out along with the transactions spending from them.
+type poolGraph struct {
+	entries  map[ID]*poolEntry
+	children map[ID][]ID
+	// left out of the graph
+	unminable []ID
+}
+
+// The signatures are only verified when asked to, as they are the most expensive to check.
+func newPoolGraph(transactions []Transaction, unspent UnspentOutputRepository, verify bool) *poolGraph {
+	pooled := make(map[ID]Transaction, len(transactions))
+	for _, tx := range transactions {
+		pooled[tx.id] = tx
+	}
+
+	g := &poolGraph{
+		entries:  make(map[ID]*poolEntry, len(transactions)),
+		children: make(map[ID][]ID),
+	}
+	var left []ID
+	for _, tx := range transactions {
+		entry, err := newPoolEntry(tx, pooled, unspent, verify)
+		if err != nil {
+			slog.Debug("Transaction of the pool cannot be mined", "id", tx.id, "error", err)
+			left = append(left, tx.id)
+			continue
+		}
+		g.entries[tx.id] = entry
+		for _, parent := range entry.parents {
+			g.children[parent] = append(g.children[parent], tx.id)
+		}
+	}
+
+	unminable := make(map[ID]struct{})
+	for _, id := range left {
+		for descendant := range g.descendants(id) {
+			unminable[descendant] = struct{}{}
+		}
+	}
+	for id := range unminable {
+		delete(g.entries, id)
+		delete(g.children, id)
+		g.unminable = append(g.unminable, id)
+	}
+	return g
+}
+
+func newPoolEntry(tx Transaction, pooled map[ID]Transaction, unspent UnspentOutputRepository, verify bool) (*poolEntry, error) {
+	var parents []ID
+	spendable := func(outputID ID, outputIndex int) (UnspentOutput, error) {
+		parent, ok := pooled[outputID]
+		if !ok {
+			output, err := unspent.GetByOutputIDAndIndex(outputID, outputIndex)
+			if err != nil {
+				return UnspentOutput{}, fmt.Errorf("error getting referenced output: %w", err)
+			}
+			if output == (UnspentOutput{}) {
+				return UnspentOutput{}, reject(OutputNotUnspent, "%x:%d", outputID, outputIndex)
+			}
+			return output, nil
+		}
+		if outputIndex < 0 || outputIndex >= len(parent.outputs) {
+			return UnspentOutput{}, reject(OutputNotUnspent, "%x:%d", outputID, outputIndex)
+		}
+		parents = append(parents, parent.id)
+		out := parent.outputs[outputIndex]
+		return NewUnspentOutput(parent.id, outputIndex, out.amount, out.address), nil
+	}
+
+	fee, referenced, err := checkAmounts(&tx, spendable)
+	if err != nil {
+		return nil, err
+	}
+	if verify {
+		if err := checkSignatures(&tx, referenced); err != nil {
+			return nil, err
+		}
+	}
+	return &poolEntry{tx: tx, fee: fee, size: tx.Size(), parents: parents}, nil
+}
+
+// ancestors of the transaction in the pool, itself included.
+func (g *poolGraph) ancestors(id ID) map[ID]struct{} {
+	found := make(map[ID]struct{})
+	stack := []ID{id}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := found[next]; ok {
+			continue
+		}
+		found[next] = struct{}{}
+		if entry, ok := g.entries[next]; ok {
+			stack = append(stack, entry.parents...)
+		}
+	}
+	return found
+}
+
+// descendants of the transaction in the pool, itself included.
+func (g *poolGraph) descendants(id ID) map[ID]struct{} {
+	found := make(map[ID]struct{})
+	stack := []ID{id}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := found[next]; ok {
+			continue
+		}
+		found[next] = struct{}{}
+		stack = append(stack, g.children[next]...)
+	}
+	return found
+}
+
+// rate of the transactions of the graph among the given ones.
+func (g *poolGraph) rate(ids map[ID]struct{}) feeRate {
+	var r feeRate
+	for id := range ids {
+		if entry, ok := g.entries[id]; ok {
+			r.fee += entry.fee
+			r.size += entry.size
+		}
+	}
+	return r
+}
+
+// sortedIDs puts the transactions of the graph in a stable order, so that the same pool makes the same choices.
+func (g *poolGraph) sortedIDs() []ID {
+	ids := make([]ID, 0, len(g.entries))
+	for id := range g.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// SelectTransactions picks the transactions of the pool to put in a block, paying the highest fee rate first.
+// A transaction is picked along with the transactions of the pool it spends from, its ancestor package, and
+// the package is rated as a whole, so that a child paying a high fee gets its parent mined as well.
+// The transactions the pool would not admit, signatures included, are left out, so that they cannot make the block
+// invalid. The picked transactions fit in maxSize bytes and maxCount transactions and come parents first.
+// It returns them along with the fees they pay.
+func SelectTransactions(pool []Transaction, unspent UnspentOutputRepository, maxSize, maxCount int) ([]Transaction, int) {
+	g := newPoolGraph(pool, unspent, true)
+	candidates := g.sortedIDs()
+	ancestors := make(map[ID]map[ID]struct{}, len(candidates))
+	packages := make(map[ID]*ancestorPackage, len(candidates))
+	queue := make(packageQueue, 0, len(candidates))
+	for _, id := range candidates {
+		ancestors[id] = g.ancestors(id)
+		pkg := &ancestorPackage{id: id, members: make(map[ID]struct{}, len(ancestors[id]))}
+		for ancestor := range ancestors[id] {
+			pkg.members[ancestor] = struct{}{}
+		}
+		pkg.rate = g.rate(pkg.members)
+		packages[id] = pkg
+		queue = append(queue, queued{pkg: pkg, rate: pkg.rate})
+	}
+	heap.Init(&queue)
+
+	picked := make(map[ID]struct{})
+	spent := make(map[outpoint]struct{})
+	var selected []Transaction
+	size, fees := 0, 0
+	for queue.Len() > 0 {
+		next := heap.Pop(&queue).(queued)
+		pkg := next.pkg
+		if pkg.done || next.version != pkg.version {
+			continue
+		}
+		pkg.done = true
+
+		if size+pkg.rate.size > maxSize || len(selected)+len(pkg.members) > maxCount || conflicts(g, pkg.members, spent) {
+			continue
+		}
+
+		// a transaction has more ancestors than any of its ancestors
+		ordered := make([]ID, 0, len(pkg.members))
+		for id := range pkg.members {
+			ordered = append(ordered, id)
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			if a, b := len(ancestors[ordered[i]]), len(ancestors[ordered[j]]); a != b {
+				return a < b
+			}
+			return ordered[i] < ordered[j]
+		})
+		changed := make(map[ID]struct{})
+		for _, id := range ordered {
+			entry := g.entries[id]
+			for _, in := range entry.tx.inputs {
+				spent[outpoint{id: in.outputID, index: in.outputIndex}] = struct{}{}
+			}
+			picked[id] = struct{}{}
+			packages[id].done = true
+			selected = append(selected, entry.tx)
+
+			// the packages of its descendants no longer hold it
+			for descendant := range g.descendants(id) {
+				if other := packages[descendant]; !other.done {
+					delete(other.members, id)
+					other.rate.fee -= entry.fee
+					other.rate.size -= entry.size
+					changed[descendant] = struct{}{}
+				}
+			}
+		}
+		for id := range changed {
+			other := packages[id]
+			if other.done {
+				continue
+			}
+			other.version++
+			heap.Push(&queue, queued{pkg: other, rate: other.rate, version: other.version})
+		}
+		size += pkg.rate.size
+		fees += pkg.rate.fee
+	}
+	return selected, fees
+}
+
+// ancestorPackage is a transaction along with its ancestors which are not picked yet. Once one of them is picked,
+// the package drops it and its rate is updated, so that the packages are not rebuilt on every pick.
+type ancestorPackage struct {
+	id      ID
+	members map[ID]struct{}
+	rate    feeRate
+	version int
+	// done once the transaction is picked, or its package does not fit
+	done bool
+}
+
+// queued is a package in the queue, at the version its rate was queued for.
+type queued struct {
+	pkg     *ancestorPackage
+	rate    feeRate
+	version int
+}
+
+// packageQueue puts the package paying the highest fee rate first, the lowest ID first among the ones paying the same.
+type packageQueue []queued
+
+func (q packageQueue) Len() int { return len(q) }
+
+func (q packageQueue) Less(i, j int) bool {
+	if c := q[i].rate.cmp(q[j].rate); c != 0 {
+		return c > 0
+	}
+	return q[i].pkg.id < q[j].pkg.id
+}
+
+func (q packageQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *packageQueue) Push(x any) { *q = append(*q, x.(queued)) }
+
+func (q *packageQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// conflicts tells whether the package spends an output twice, or an output spent already.
+func conflicts(g *poolGraph, pkg map[ID]struct{}, spent map[outpoint]struct{}) bool {
+	seen := make(map[outpoint]struct{})
+	for id := range pkg {
+		for _, in := range g.entries[id].tx.inputs {
+			key := outpoint{id: in.outputID, index: in.outputIndex}
+			if _, ok := spent[key]; ok {
+				return true
+			}
+			if _, ok := seen[key]; ok {
+				return true
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	return false
+}
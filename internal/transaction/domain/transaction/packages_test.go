@@ -0,0 +1,137 @@
+package transaction_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectTransactions_shouldPickTheHighestFeeRateAncestorPackagesFirst(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	unspent := inmem.NewUnspentOutputRepository()
+	low, _ := paying(t, unspent, "a", 1)
+	mid, _ := paying(t, unspent, "b", 10)
+	// and a parent paying nothing, whose child pays enough for both
+	parent, receiver := paying(t, unspent, "c", 0)
+	child := spendingPooled(t, parent, receiver, 25)
+	// and transactions which cannot be mined
+	orphan, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("missing", 0, "signature")},
+		[]*transaction.Output{transaction.NewOutput(10, "receiver")},
+	)
+	require.NoError(t, err)
+	orphanChild, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(orphan.ID(), 0, "signature")},
+		[]*transaction.Output{transaction.NewOutput(10, "receiver")},
+	)
+	require.NoError(t, err)
+	pool := []transaction.Transaction{*child, *low, *orphanChild, *mid, *parent, *orphan}
+
+	// when
+	selected, fees := transaction.SelectTransactions(pool, unspent, 1<<20, 3)
+
+	// then the package goes first, the parent in front of the child, and the lowest fee rate does not fit
+	assert.Equal([]transaction.ID{parent.ID(), child.ID(), mid.ID()}, idsOf(selected))
+	assert.Equal(35, fees)
+
+	// when there is no room for the third transaction
+	selected, fees = transaction.SelectTransactions(pool, unspent, parent.Size()+child.Size()+mid.Size()-1, 10)
+
+	// then
+	assert.Equal([]transaction.ID{parent.ID(), child.ID()}, idsOf(selected))
+	assert.Equal(25, fees)
+}
+
+func TestSelectTransactions_shouldLeaveOutTransactionsWithSignaturesNotValid(t *testing.T) {
+	// given a transaction paying a high fee with the signature of another transaction, along with its child
+	unspent := inmem.NewUnspentOutputRepository()
+	valid, _ := paying(t, unspent, "a", 5)
+	other, receiver := paying(t, unspent, "b", 40)
+	forged, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("b", 0, valid.Inputs()[0].Signature())},
+		[]*transaction.Output{transaction.NewOutput(other.Outputs()[0].Amount(), other.Outputs()[0].Address())},
+	)
+	require.NoError(t, err)
+	child := spendingPooled(t, forged, receiver, 10)
+
+	// when
+	selected, fees := transaction.SelectTransactions([]transaction.Transaction{*forged, *child, *valid}, unspent, 1<<20, 10)
+
+	// then
+	assert.Equal(t, []transaction.ID{valid.ID()}, idsOf(selected))
+	assert.Equal(t, 5, fees)
+}
+
+func TestPool_shouldEvictTheLowestFeeRateOnceFull(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a pool with room for two transactions
+	unspent := inmem.NewUnspentOutputRepository()
+	low, _ := paying(t, unspent, "a", 1)
+	mid, _ := paying(t, unspent, "b", 10)
+	high, _ := paying(t, unspent, "c", 20)
+	lower, _ := paying(t, unspent, "d", 2)
+	repository := inmem.NewPoolRepository()
+	pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{MaxSize: mid.Size() + high.Size() + low.Size()/2})
+	require.NoError(t, pool.Add(low))
+	require.NoError(t, pool.Add(mid))
+
+	// when a transaction paying more comes
+	err := pool.Add(high)
+
+	// then the one paying the least makes room for it
+	assert.NoError(err)
+	assert.False(pool.Exists(low.ID()))
+	assert.True(pool.Exists(mid.ID()))
+	assert.True(pool.Exists(high.ID()))
+
+	// when a transaction paying less than the pool holds comes
+	err = pool.Add(lower)
+
+	// then it is rejected
+	var rejection *transaction.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(transaction.PoolFull, rejection.Reason)
+	assert.False(pool.Exists(lower.ID()))
+	assert.Len(repository.GetAll(), 2)
+}
+
+// paying creates a transaction spending an output worth 100 of a new owner, which leaves the fee to the miner.
+// It pays 90 less the fee to a new receiver, who is returned as well.
+func paying(t *testing.T, unspent *inmem.UnspentOutputRepository, outputID transaction.ID, fee int) (*transaction.Transaction, *ecdsa.PrivateKey) {
+	t.Helper()
+	owner, ownerAddr := newKey(t)
+	require.NoError(t, unspent.Add(transaction.NewUnspentOutput(outputID, 0, 100, ownerAddr)))
+	receiver, receiverAddr := newKey(t)
+	tx, err := transaction.NewWithFee(receiverAddr, ownerAddr, 90-fee, fee, owner, unspent)
+	require.NoError(t, err)
+	return tx, receiver
+}
+
+// spendingPooled creates a transaction spending what the parent pays to the receiver, leaving the fee to the miner.
+func spendingPooled(t *testing.T, parent *transaction.Transaction, receiver *ecdsa.PrivateKey, fee int) *transaction.Transaction {
+	t.Helper()
+	raw, err := x509.MarshalPKIXPublicKey(receiver.Public())
+	require.NoError(t, err)
+	received := parent.Outputs()[0]
+	unspent := newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*parent})...)
+	tx, err := transaction.NewWithFee("someone", hex.EncodeToString(raw), received.Amount()-fee, fee, receiver, unspent)
+	require.NoError(t, err)
+	return tx
+}
+
+func idsOf(transactions []transaction.Transaction) []transaction.ID {
+	ids := make([]transaction.ID, len(transactions))
+	for i, tx := range transactions {
+		ids[i] = tx.ID()
+	}
+	return ids
+}
@@ -0,0 +1,194 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The reasons the pool rejects a transaction for.
+var (
+	AlreadyInPool     = errors.New("transaction already in the pool")
+	NotSpending       = errors.New("transaction must spend outputs")
+	NoOutputs         = errors.New("transaction must have outputs")
+	OutputNotPositive = errors.New("output amount must be positive")
+	AmountTooLarge    = errors.New("amounts add up to more than the supply")
+	DuplicateInput    = errors.New("transaction spends the same output twice")
+	OutputNotUnspent  = errors.New("spent output is not unspent")
+	SignatureNotValid = errors.New("signature not valid")
+	InputsBelowOutput = errors.New("inputs do not cover the outputs")
+	ConflictsWithPool = errors.New("output already spent by a transaction in the pool")
+	PoolFull          = errors.New("fee rate too low for the full pool")
+	ReplacementTooLow = errors.New("replacement does not pay more than the transactions it replaces")
+)
+
+// Rejection is the error for a transaction the pool does not accept. Reason is one of the reasons above,
+// the error itself tells the details.
+type Rejection struct {
+	Reason error
+	err    error
+}
+
+func reject(reason error, format string, args ...any) *Rejection {
+	return &Rejection{
+		Reason: reason,
+		err:    fmt.Errorf("%w: %s", reason, fmt.Sprintf(format, args...)),
+	}
+}
+
+func (r *Rejection) Error() string {
+	return r.err.Error()
+}
+
+func (r *Rejection) Unwrap() error {
+	return r.err
+}
+
+// admit checks the transaction against the policy of the pool. It cannot spend an output another transaction
+// in the pool already spends, unless that one is replaceable and the transaction pays enough to replace it, and it
+// has to pass check, spending outputs which are unspent or created by the transactions in the pool. It returns
+// the transactions of the pool the transaction replaces.
+func (p *Pool) admit(tx *Transaction) (map[ID]struct{}, error) {
+	if p.pool.Exists(tx.id) {
+		return nil, reject(AlreadyInPool, "%x", tx.id)
+	}
+
+	conflicting := make(map[ID]struct{})
+	for _, in := range tx.inputs {
+		if spender, ok := p.pool.SpentBy(in.outputID, in.outputIndex); ok {
+			if !p.config.ReplaceByFee {
+				return nil, reject(ConflictsWithPool, "output %x:%d is spent by %x", in.outputID, in.outputIndex, spender)
+			}
+			if pending, err := p.pool.Get(spender); err != nil || pending == nil || !pending.Replaceable() {
+				return nil, reject(ConflictsWithPool, "output %x:%d is spent by %x, which is not replaceable", in.outputID, in.outputIndex, spender)
+			}
+			conflicting[spender] = struct{}{}
+		}
+	}
+
+	fee, err := check(tx, p.spendable)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicting) == 0 {
+		return nil, nil
+	}
+	return p.replacing(tx, feeRate{fee: fee, size: tx.Size()}, conflicting)
+}
+
+// check applies the rules of the policy which do not depend on the other transactions of the pool. The transaction
+// has to spend outputs, which spendable finds, with valid signatures. Its outputs have to be positive, cannot add up
+// to more than the supply and have to be covered by its inputs. It returns the fee the transaction pays.
+func check(tx *Transaction, spendable func(outputID ID, outputIndex int) (UnspentOutput, error)) (int, error) {
+	fee, referenced, err := checkAmounts(tx, spendable)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSignatures(tx, referenced); err != nil {
+		return 0, err
+	}
+	return fee, nil
+}
+
+// checkAmounts applies the rules of check but the signatures. It returns the outputs the transaction spends
+// along with its fee.
+func checkAmounts(tx *Transaction, spendable func(outputID ID, outputIndex int) (UnspentOutput, error)) (int, UnspentOutputRepository, error) {
+	if len(tx.inputs) == 0 || tx.IsCoinbase() {
+		return 0, nil, reject(NotSpending, "only the first transaction in a block can mint coins")
+	}
+	if len(tx.outputs) == 0 {
+		return 0, nil, reject(NoOutputs, "%x", tx.id)
+	}
+	max := MaxAmount()
+	for i, output := range tx.outputs {
+		if output.amount <= 0 {
+			return 0, nil, reject(OutputNotPositive, "output %d has amount %d", i, output.amount)
+		}
+		if output.amount > max {
+			return 0, nil, reject(AmountTooLarge, "output %d has amount %d above the supply of %d", i, output.amount, max)
+		}
+	}
+	if err := validateDuplicates(tx.inputs); err != nil {
+		return 0, nil, reject(DuplicateInput, "%v", err)
+	}
+
+	referenced := make([]UnspentOutput, 0, len(tx.inputs))
+	for _, in := range tx.inputs {
+		output, err := spendable(in.outputID, in.outputIndex)
+		if err != nil {
+			return 0, nil, err
+		}
+		referenced = append(referenced, output)
+	}
+
+	spent := newUnspentSet(referenced)
+	fee, err := Fee(*tx, spent)
+	if err != nil {
+		return 0, nil, reject(AmountTooLarge, "%v", err)
+	}
+	if fee < 0 {
+		return 0, nil, reject(InputsBelowOutput, "short by %d", -fee)
+	}
+	return fee, spent, nil
+}
+
+// checkSignatures verifies the signatures of the inputs of the transaction against the outputs it spends. They are
+// the most expensive to check, so they are checked last.
+func checkSignatures(tx *Transaction, referenced UnspentOutputRepository) error {
+	for i := range tx.inputs {
+		if err := validateTransactionIn(tx, i, referenced); err != nil {
+			return reject(SignatureNotValid, "input %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// replacing checks that the transaction pays enough to replace the transactions of the pool it conflicts with,
+// along with their descendants, which cannot be mined without them. It has to pay a higher fee than all of them
+// together, so that the miners do not lose on the replacement, and a higher fee rate than each of the ones it
+// conflicts with. It returns the transactions it replaces.
+func (p *Pool) replacing(tx *Transaction, rate feeRate, conflicting map[ID]struct{}) (map[ID]struct{}, error) {
+	g := newPoolGraph(p.pool.GetAll(), p.unspent, false)
+	replaced := make(map[ID]struct{})
+	for id := range conflicting {
+		for descendant := range g.descendants(id) {
+			replaced[descendant] = struct{}{}
+		}
+	}
+
+	for _, in := range tx.inputs {
+		if _, ok := replaced[in.outputID]; ok {
+			return nil, reject(ConflictsWithPool, "output %x:%d is created by a transaction it replaces", in.outputID, in.outputIndex)
+		}
+	}
+	for id := range conflicting {
+		entry, ok := g.entries[id]
+		if !ok {
+			continue
+		}
+		if old := (feeRate{fee: entry.fee, size: entry.size}); !old.less(rate) {
+			return nil, reject(ReplacementTooLow, "fee rate %s is not above %s of %x", rate, old, id)
+		}
+	}
+	if old := g.rate(replaced); rate.fee <= old.fee {
+		return nil, reject(ReplacementTooLow, "fee %d is not above %d of the %d transactions it replaces", rate.fee, old.fee, len(replaced))
+	}
+	return replaced, nil
+}
+
+// spendable finds the output among the unspent ones or among the outputs of the transactions in the pool.
+func (p *Pool) spendable(outputID ID, outputIndex int) (UnspentOutput, error) {
+	output, err := p.unspent.GetByOutputIDAndIndex(outputID, outputIndex)
+	if err != nil {
+		return UnspentOutput{}, fmt.Errorf("error getting spent output: %w", err)
+	}
+	if output != (UnspentOutput{}) {
+		return output, nil
+	}
+
+	parent, err := p.pool.Get(outputID)
+	if err == nil && parent != nil && outputIndex >= 0 && outputIndex < len(parent.outputs) {
+		out := parent.outputs[outputIndex]
+		return NewUnspentOutput(parent.id, outputIndex, out.amount, out.address), nil
+	}
+	return UnspentOutput{}, reject(OutputNotUnspent, "%x:%d", outputID, outputIndex)
+}
@@ -1,27 +1,140 @@
 package transaction
 
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
 type PoolRepository interface {
 	Add(*Transaction) error
 	Exists(ID) bool
 	Get(ID) (*Transaction, error)
 	Remove(...ID) error
 	GetAll() []Transaction
+	// SpentBy returns the transaction in the pool spending the output, if there is one.
+	SpentBy(outputID ID, outputIndex int) (ID, bool)
+	// Size is how many bytes the transactions in the pool take up, see Transaction.Size.
+	Size() int
+}
+
+// PoolConfig is the policy of the pool on top of the consensus rules.
+type PoolConfig struct {
+	// MaxSize is how many bytes of transactions the pool holds. Once it gets bigger, the transactions paying
+	// the lowest fee rate are evicted. The pool is not capped when zero.
+	MaxSize int
+	// ReplaceByFee lets a transaction paying more replace the transactions of the pool it conflicts with, as long as
+	// they were created replaceable.
+	ReplaceByFee bool
 }
 
 type Pool struct {
-	pool PoolRepository
+	pool    PoolRepository
+	unspent UnspentOutputRepository
+	config  PoolConfig
+	// mu makes the admissions one at a time, so that two conflicting transactions cannot both get in
+	mu sync.Mutex
 }
 
-func NewPool(pool PoolRepository) *Pool {
+func NewPool(pool PoolRepository, unspent UnspentOutputRepository, config PoolConfig) *Pool {
 	return &Pool{
-		pool: pool,
+		pool:    pool,
+		unspent: unspent,
+		config:  config,
 	}
 }
 
+// Add the transaction to the pool if the policy of the pool admits it. A *Rejection tells why it does not.
 func (p *Pool) Add(tx *Transaction) error {
-	// TODO#25 validation
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return p.pool.Add(tx)
+	replaced, err := p.admit(tx)
+	if err != nil {
+		return err
+	}
+	if len(replaced) > 0 {
+		if err := p.remove(replaced); err != nil {
+			return err
+		}
+		slog.Info("Transaction replaced transactions of the pool", "id", tx.id, "replaced", len(replaced))
+	}
+	if err := p.pool.Add(tx); err != nil {
+		return err
+	}
+	return p.evict(tx.id)
+}
+
+// evict removes transactions from the pool until it fits in its size, see overflowing. When the transaction just
+// added is evicted, it is rejected.
+func (p *Pool) evict(added ID) error {
+	if p.config.MaxSize <= 0 || p.pool.Size() <= p.config.MaxSize {
+		return nil
+	}
+
+	evicted, lowest := p.overflowing(p.pool.GetAll())
+	if err := p.remove(evicted); err != nil {
+		return err
+	}
+	if len(evicted) > 0 {
+		slog.Info("Evicted transactions from the full pool", "evicted", len(evicted), "feeRate", lowest)
+	}
+	if _, ok := evicted[added]; ok {
+		return reject(PoolFull, "the pool holds transactions paying above %s per byte", lowest)
+	}
+	return nil
+}
+
+// overflowing picks the transactions to leave out for the rest to fit in the size of the pool. The ones which cannot
+// be mined go first, then the ones whose package with their descendants pays the lowest fee rate, since their
+// descendants cannot be mined without them. It returns them along with the fee rate of the last package left out.
+func (p *Pool) overflowing(transactions []Transaction) (map[ID]struct{}, feeRate) {
+	size := 0
+	sizes := make(map[ID]int, len(transactions))
+	for _, tx := range transactions {
+		sizes[tx.id] = tx.Size()
+		size += sizes[tx.id]
+	}
+	if p.config.MaxSize <= 0 || size <= p.config.MaxSize {
+		return nil, feeRate{}
+	}
+
+	// the signatures were verified when the transactions were admitted, only whether they can still be mined matters
+	g := newPoolGraph(transactions, p.unspent, false)
+	evicted := make(map[ID]struct{})
+	for _, id := range g.unminable {
+		evicted[id] = struct{}{}
+		size -= sizes[id]
+	}
+
+	var lowest feeRate
+	for size > p.config.MaxSize && len(g.entries) > 0 {
+		var victims map[ID]struct{}
+		for _, id := range g.sortedIDs() {
+			descendants := g.descendants(id)
+			if r := g.rate(descendants); victims == nil || r.less(lowest) {
+				victims, lowest = descendants, r
+			}
+		}
+		for id := range victims {
+			delete(g.entries, id)
+			evicted[id] = struct{}{}
+			size -= sizes[id]
+		}
+	}
+	return evicted, lowest
+}
+
+func (p *Pool) remove(ids map[ID]struct{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	toRemove := make([]ID, 0, len(ids))
+	for id := range ids {
+		toRemove = append(toRemove, id)
+	}
+	return p.pool.Remove(toRemove...)
 }
 
 func (p *Pool) Exists(id ID) bool {
@@ -32,29 +145,98 @@ func (p *Pool) Get(id ID) (*Transaction, error) {
 	return p.pool.Get(id)
 }
 
+// Update drops the transactions of the pool which spend an output that is neither among the given unspent outputs nor
+// created by a transaction left in the pool, such as the transactions which got mined or conflict with a mined one,
+// along with the transactions spending from them.
 func (p *Pool) Update(unspent []UnspentOutput) error {
-	unspentIDs := make(map[ID]struct{})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	confirmed := make(map[outpoint]struct{}, len(unspent))
 	for _, u := range unspent {
-		unspentIDs[u.outputID] = struct{}{}
+		confirmed[outpoint{id: u.outputID, index: u.outputIndex}] = struct{}{}
 	}
-	spentIDs := make(map[ID]struct{})
 	current := p.pool.GetAll()
+	pending := make(map[outpoint]struct{})
+	for _, tx := range current {
+		for i := range tx.outputs {
+			pending[outpoint{id: tx.id, index: i}] = struct{}{}
+		}
+	}
 
-	for _, u := range current {
-		for _, i := range u.Inputs() {
-			if _, ok := unspentIDs[i.outputID]; !ok {
-				spentIDs[i.outputID] = struct{}{}
-				break
+	toRemove := make(map[ID]struct{})
+	for removed := true; removed; {
+		removed = false
+		for _, tx := range current {
+			if _, ok := toRemove[tx.id]; ok || spendsOnly(tx, confirmed, pending) {
+				continue
+			}
+			toRemove[tx.id] = struct{}{}
+			for i := range tx.outputs {
+				delete(pending, outpoint{id: tx.id, index: i})
 			}
+			removed = true
+		}
+	}
+	return p.remove(toRemove)
+}
+
+// spendsOnly tells whether every input of the transaction spends one of the outputs.
+func spendsOnly(tx Transaction, confirmed, pending map[outpoint]struct{}) bool {
+	for _, in := range tx.inputs {
+		key := outpoint{id: in.outputID, index: in.outputIndex}
+		_, isConfirmed := confirmed[key]
+		_, isPending := pending[key]
+		if !isConfirmed && !isPending {
+			return false
 		}
 	}
+	return true
+}
 
-	toRemove := make([]ID, len(spentIDs))
-	i := 0
-	for id := range spentIDs {
-		toRemove[i] = id
-		i++
+// Revalidate goes through the transactions, in the order they were added to the pool, and keeps the ones the policy
+// of the pool admits, see check, spending only unspent outputs or outputs of the transactions kept before them,
+// without spending an output one of those already spends. Once the kept transactions do not fit in the size of the
+// pool, the ones the pool would evict are not kept either. Nothing is put into the pool, the transactions which are
+// not kept are returned as rejected.
+func (p *Pool) Revalidate(transactions []Transaction) (kept []Transaction, rejected []Transaction, err error) {
+	view := NewUnspentView(p.unspent)
+	spendable := func(outputID ID, outputIndex int) (UnspentOutput, error) {
+		output, err := view.GetByOutputIDAndIndex(outputID, outputIndex)
+		if err != nil {
+			return UnspentOutput{}, fmt.Errorf("error getting spent output: %w", err)
+		}
+		if output == (UnspentOutput{}) {
+			return UnspentOutput{}, reject(OutputNotUnspent, "%x:%d", outputID, outputIndex)
+		}
+		return output, nil
 	}
 
-	return p.pool.Remove(toRemove...)
+	for _, tx := range transactions {
+		if _, err := check(&tx, spendable); err != nil {
+			var rejection *Rejection
+			if !errors.As(err, &rejection) {
+				return nil, nil, err
+			}
+			slog.Debug("Transaction no longer admitted to the pool", "id", tx.id, "reason", err)
+			rejected = append(rejected, tx)
+			continue
+		}
+		view.apply(tx)
+		kept = append(kept, tx)
+	}
+
+	evicted, _ := p.overflowing(kept)
+	if len(evicted) == 0 {
+		return kept, rejected, nil
+	}
+	fitting := make([]Transaction, 0, len(kept)-len(evicted))
+	for _, tx := range kept {
+		if _, ok := evicted[tx.id]; ok {
+			rejected = append(rejected, tx)
+			continue
+		}
+		fitting = append(fitting, tx)
+	}
+	return fitting, rejected, nil
 }
@@ -1,21 +1,31 @@
 package transaction_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"math"
 	"testing"
 
 	"github.com/patrykferenc/eecoin/internal/common/mock"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
-	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction/transactiontest"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPool(t *testing.T) {
 	assert := assert.New(t)
 	// given
 	m := mock.NewPoolRepository()
-	pool := transaction.NewPool(m)
+	owner, ownerAddr := newKey(t)
+	unspent := inmem.NewUnspentOutputRepository()
+	require.NoError(t, unspent.Add(transaction.NewUnspentOutput("funding", 0, 100, ownerAddr)))
+	pool := transaction.NewPool(m, unspent, transaction.PoolConfig{})
 	// and given transaction
-	tx, err := transactiontest.NewGenesisLike("someAddress", 100)
+	tx, err := transaction.New("receiver", ownerAddr, 100, owner, unspent)
 	assert.NoError(err)
 
 	// when adding a transaction
@@ -27,6 +37,315 @@ func TestPool(t *testing.T) {
 	assert.True(pool.Exists(tx.ID()))
 }
 
+func TestPool_shouldAdmitOnlyValidTransactions(t *testing.T) {
+	// given an owner of an unspent output
+	owner, ownerAddr := newKey(t)
+	unspent := newUnspent(t, transaction.NewUnspentOutput("funding", 0, 100, ownerAddr))
+
+	// and a transaction in the pool already spending it
+	receiver, receiverAddr := newKey(t)
+	pooled, err := transaction.New(receiverAddr, ownerAddr, 60, owner, unspent)
+	require.NoError(t, err)
+
+	// and the transactions sent to the pool
+	child, err := transaction.New("someone", receiverAddr, 60, receiver, newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*pooled})...))
+	require.NoError(t, err)
+	conflicting, err := transaction.New("someone else", ownerAddr, 100, owner, unspent)
+	require.NoError(t, err)
+	missing, err := transaction.New("receiver", ownerAddr, 100, owner, newUnspent(t, transaction.NewUnspentOutput("missing", 0, 100, ownerAddr)))
+	require.NoError(t, err)
+	redirected, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(pooled.ID(), 1, child.Inputs()[0].Signature())},
+		[]*transaction.Output{transaction.NewOutput(40, "thief")},
+	)
+	require.NoError(t, err)
+	// the owner claims the output holds more than it does
+	require.NoError(t, unspent.Add(transaction.NewUnspentOutput("savings", 0, 100, ownerAddr)))
+	inflating, err := transaction.New("receiver", ownerAddr, 150, owner, newUnspent(t, transaction.NewUnspentOutput("savings", 0, 200, ownerAddr)))
+	require.NoError(t, err)
+	// spends the change of the pooled transaction
+	negative, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(pooled.ID(), 1, "")},
+		[]*transaction.Output{transaction.NewOutput(50, "receiver"), transaction.NewOutput(-10, ownerAddr)},
+	)
+	require.NoError(t, err)
+	zero, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(pooled.ID(), 1, "")},
+		[]*transaction.Output{transaction.NewOutput(0, "receiver")},
+	)
+	require.NoError(t, err)
+	coinbase, err := transaction.NewCoinbase("miner", 2)
+	require.NoError(t, err)
+	wrapping, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(pooled.ID(), 1, "")},
+		[]*transaction.Output{transaction.NewOutput(math.MaxInt64, "receiver"), transaction.NewOutput(math.MaxInt64, "receiver")},
+	)
+	require.NoError(t, err)
+	aboveSupply, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(pooled.ID(), 1, "")},
+		[]*transaction.Output{transaction.NewOutput(transaction.MaxAmount()/2+1, "receiver"), transaction.NewOutput(transaction.MaxAmount()/2+1, "receiver")},
+	)
+	require.NoError(t, err)
+
+	tt := []struct {
+		description string
+		tx          *transaction.Transaction
+		reason      error
+	}{
+		{description: "child of a pooled transaction", tx: child},
+		{description: "already in the pool", tx: pooled, reason: transaction.AlreadyInPool},
+		{description: "spends what the pool spends", tx: conflicting, reason: transaction.ConflictsWithPool},
+		{description: "spends a missing output", tx: missing, reason: transaction.OutputNotUnspent},
+		{description: "signature of another transaction", tx: redirected, reason: transaction.SignatureNotValid},
+		{description: "outputs above the inputs", tx: inflating, reason: transaction.InputsBelowOutput},
+		{description: "negative output", tx: negative, reason: transaction.OutputNotPositive},
+		{description: "zero output", tx: zero, reason: transaction.OutputNotPositive},
+		{description: "coinbase", tx: coinbase, reason: transaction.NotSpending},
+		{description: "outputs wrapping around", tx: wrapping, reason: transaction.AmountTooLarge},
+		{description: "outputs above the supply", tx: aboveSupply, reason: transaction.AmountTooLarge},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			pool := transaction.NewPool(inmem.NewPoolRepository(), unspent, transaction.PoolConfig{})
+			require.NoError(t, pool.Add(pooled))
+
+			// when
+			err := pool.Add(tc.tx)
+
+			// then
+			if tc.reason == nil {
+				assert.NoError(t, err)
+				assert.True(t, pool.Exists(tc.tx.ID()))
+				return
+			}
+			var rejection *transaction.Rejection
+			require.ErrorAs(t, err, &rejection)
+			assert.Equal(t, tc.reason, rejection.Reason)
+			assert.ErrorIs(t, err, tc.reason)
+		})
+	}
+}
+
+func TestPool_shouldAdmitTheSpendOfAnOutputOnceItsSpenderIsRemoved(t *testing.T) {
+	// given
+	owner, ownerAddr := newKey(t)
+	unspent := newUnspent(t, transaction.NewUnspentOutput("funding", 0, 100, ownerAddr))
+	repository := inmem.NewPoolRepository()
+	pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{})
+	first, err := transaction.New("receiver", ownerAddr, 100, owner, unspent)
+	require.NoError(t, err)
+	second, err := transaction.New("someone else", ownerAddr, 100, owner, unspent)
+	require.NoError(t, err)
+	require.NoError(t, pool.Add(first))
+
+	// when
+	require.NoError(t, repository.Remove(first.ID()))
+
+	// then
+	assert.NoError(t, pool.Add(second))
+}
+
 func TestUpdatePool(t *testing.T) {
-	t.Skipf("TODO#30")
+	assert := assert.New(t)
+
+	// given a pool holding a transaction, a child spending from it and an unrelated transaction
+	owner, ownerAddr := newKey(t)
+	funding := transaction.NewUnspentOutput("funding", 0, 100, ownerAddr)
+	savings := transaction.NewUnspentOutput("savings", 0, 100, ownerAddr)
+	unspent := newUnspent(t, funding, savings)
+	receiver, receiverAddr := newKey(t)
+	mined, err := transaction.NewWithFee(receiverAddr, ownerAddr, 60, 5, owner, newUnspent(t, funding))
+	require.NoError(t, err)
+	child, err := transaction.NewWithFee("someone", receiverAddr, 50, 10, receiver, newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*mined})...))
+	require.NoError(t, err)
+	unrelated, err := transaction.NewWithFee("someone", ownerAddr, 90, 5, owner, newUnspent(t, savings))
+	require.NoError(t, err)
+	// and a transaction spending an output which the block spends as well, along with its own child
+	lost := transaction.NewUnspentOutput("lost", 0, 100, ownerAddr)
+	conflicting, err := transaction.NewWithFee(receiverAddr, ownerAddr, 90, 10, owner, newUnspent(t, lost))
+	require.NoError(t, err)
+	orphan, err := transaction.NewWithFee("someone", receiverAddr, 80, 10, receiver, newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*conflicting})...))
+	require.NoError(t, err)
+	repository := inmem.NewPoolRepository()
+	for _, tx := range []*transaction.Transaction{mined, child, unrelated, conflicting, orphan} {
+		require.NoError(t, repository.Add(tx))
+	}
+	pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{})
+
+	// when the transaction gets mined, in a block which spends the other output too
+	err = pool.Update(transaction.ApplyTransactions([]transaction.UnspentOutput{funding, savings}, []transaction.Transaction{*mined}))
+
+	// then it leaves the pool, along with the transactions spending what the block spent
+	assert.NoError(err)
+	assert.False(pool.Exists(mined.ID()))
+	assert.False(pool.Exists(conflicting.ID()))
+	assert.False(pool.Exists(orphan.ID()))
+	// and the others stay
+	assert.True(pool.Exists(child.ID()))
+	assert.True(pool.Exists(unrelated.ID()))
+}
+
+func TestPool_Revalidate(t *testing.T) {
+	// given an owner of unspent outputs
+	owner, ownerAddr := newKey(t)
+	funding := transaction.NewUnspentOutput("funding", 0, 100, ownerAddr)
+	savings := transaction.NewUnspentOutput("savings", 0, 100, ownerAddr)
+	unspent := newUnspent(t, funding, savings)
+
+	// and the transactions replayed from the journal
+	receiver, receiverAddr := newKey(t)
+	first, err := transaction.NewWithFee(receiverAddr, ownerAddr, 60, 5, owner, newUnspent(t, funding))
+	require.NoError(t, err)
+	child, err := transaction.NewWithFee("someone", receiverAddr, 50, 10, receiver, newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*first})...))
+	require.NoError(t, err)
+	conflicting, err := transaction.New("someone else", ownerAddr, 100, owner, newUnspent(t, funding))
+	require.NoError(t, err)
+	missing, err := transaction.New("someone", ownerAddr, 100, owner, newUnspent(t, transaction.NewUnspentOutput("missing", 0, 100, ownerAddr)))
+	require.NoError(t, err)
+	forged, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(savings.OutputID(), savings.OutputIndex(), child.Inputs()[0].Signature())},
+		[]*transaction.Output{transaction.NewOutput(100, "thief")},
+	)
+	require.NoError(t, err)
+	cheap, err := transaction.NewWithFee("someone", ownerAddr, 99, 1, owner, newUnspent(t, savings))
+	require.NoError(t, err)
+	replayed := []transaction.Transaction{*first, *child, *conflicting, *missing, *forged, *cheap}
+
+	tt := []struct {
+		description string
+		maxSize     int
+		kept        []transaction.Transaction
+		rejected    []transaction.Transaction
+	}{
+		{
+			description: "pool not capped",
+			kept:        []transaction.Transaction{*first, *child, *cheap},
+			rejected:    []transaction.Transaction{*conflicting, *missing, *forged},
+		},
+		{
+			description: "pool too small for all of them",
+			maxSize:     first.Size() + child.Size(),
+			kept:        []transaction.Transaction{*first, *child},
+			rejected:    []transaction.Transaction{*conflicting, *missing, *forged, *cheap},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			repository := inmem.NewPoolRepository()
+			pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{MaxSize: tc.maxSize})
+
+			// when
+			kept, rejected, err := pool.Revalidate(replayed)
+
+			// then the child of a kept transaction is kept, the double spend, the spend of a missing output,
+			// the forged signature and the transactions the full pool would evict are not
+			assert.NoError(t, err)
+			assert.Equal(t, tc.kept, kept)
+			assert.Equal(t, tc.rejected, rejected)
+			// and nothing is put into the pool
+			assert.Empty(t, repository.GetAll())
+		})
+	}
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return key, hex.EncodeToString(raw)
+}
+
+func newUnspent(t *testing.T, outputs ...transaction.UnspentOutput) *inmem.UnspentOutputRepository {
+	t.Helper()
+	unspent := inmem.NewUnspentOutputRepository()
+	require.NoError(t, unspent.Add(outputs...))
+	return unspent
+}
+
+func TestPool_shouldReplaceByFee(t *testing.T) {
+	// given a transaction in the pool, along with a child spending from it
+	owner, ownerAddr := newKey(t)
+	unspent := newUnspent(t, transaction.NewUnspentOutput("funding", 0, 100, ownerAddr))
+	receiver, receiverAddr := newKey(t)
+	pending, err := transaction.NewReplaceableWithFee(receiverAddr, ownerAddr, 60, 5, owner, unspent)
+	require.NoError(t, err)
+	child, err := transaction.NewWithFee("someone", receiverAddr, 50, 10, receiver, newUnspent(t, transaction.UnspentOutputsFrom([]transaction.Transaction{*pending})...))
+	require.NoError(t, err)
+
+	// and the replacements
+	bumped := func(by int) *transaction.Transaction {
+		tx, err := transaction.BumpFee(*pending, by, ownerAddr, owner, unspent)
+		require.NoError(t, err)
+		return tx
+	}
+	change := transaction.UnspentOutputsFrom([]transaction.Transaction{*pending})[1]
+	spendingReplaced, err := transaction.NewWithFee("someone", ownerAddr, 120, 10, owner, newUnspent(t, transaction.NewUnspentOutput("funding", 0, 100, ownerAddr), change))
+	require.NoError(t, err)
+
+	tt := []struct {
+		description  string
+		replaceByFee bool
+		replacement  *transaction.Transaction
+		reason       error
+	}{
+		{description: "paying more than both", replaceByFee: true, replacement: bumped(20)},
+		{description: "paying more than the transaction only", replaceByFee: true, replacement: bumped(10), reason: transaction.ReplacementTooLow},
+		{description: "spending what it replaces", replaceByFee: true, replacement: spendingReplaced, reason: transaction.ConflictsWithPool},
+		{description: "replacing turned off", replaceByFee: false, replacement: bumped(20), reason: transaction.ConflictsWithPool},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			pool := transaction.NewPool(inmem.NewPoolRepository(), unspent, transaction.PoolConfig{ReplaceByFee: tc.replaceByFee})
+			require.NoError(t, pool.Add(pending))
+			require.NoError(t, pool.Add(child))
+
+			// when
+			err := pool.Add(tc.replacement)
+
+			// then
+			if tc.reason != nil {
+				var rejection *transaction.Rejection
+				require.ErrorAs(t, err, &rejection)
+				assert.Equal(t, tc.reason, rejection.Reason)
+				assert.True(t, pool.Exists(pending.ID()))
+				assert.True(t, pool.Exists(child.ID()))
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, pool.Exists(tc.replacement.ID()))
+			assert.False(t, pool.Exists(pending.ID()))
+			assert.False(t, pool.Exists(child.ID()))
+		})
+	}
+}
+
+func TestPool_shouldNotReplaceTransactionsNotReplaceable(t *testing.T) {
+	// given a transaction in the pool created without letting it be replaced
+	owner, ownerAddr := newKey(t)
+	unspent := newUnspent(t, transaction.NewUnspentOutput("funding", 0, 100, ownerAddr))
+	pending, err := transaction.NewWithFee("receiver", ownerAddr, 60, 5, owner, unspent)
+	require.NoError(t, err)
+	pool := transaction.NewPool(inmem.NewPoolRepository(), unspent, transaction.PoolConfig{ReplaceByFee: true})
+	require.NoError(t, pool.Add(pending))
+	// and a transaction spending the same output, paying much more
+	conflicting, err := transaction.NewReplaceableWithFee("receiver", ownerAddr, 60, 30, owner, unspent)
+	require.NoError(t, err)
+
+	// when
+	err = pool.Add(conflicting)
+
+	// then
+	var rejection *transaction.Rejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, transaction.ConflictsWithPool, rejection.Reason)
+	assert.True(t, pool.Exists(pending.ID()))
+	assert.False(t, pool.Exists(conflicting.ID()))
 }
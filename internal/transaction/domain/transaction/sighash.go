@@ -0,0 +1,27 @@
+package transaction
+
+import (
+	"crypto/sha256"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+)
+
+// sigHashTag keeps the signatures of the inputs apart from anything else signed with the same keys.
+const sigHashTag = "eecoin/sighash"
+
+// sigHash is the digest the owner of the output spent by the input at the given index signs. It commits to the
+// network, to all the inputs and outputs of the transaction and whether it can be replaced, to the index of the input
+// and to the amount and the address of the output it spends, so that a signature cannot be moved to another network,
+// transaction or input, and the signer does not have to trust anyone on how much the input is worth.
+func sigHash(tx *Transaction, index int, referenced UnspentOutput, chainID uint32) []byte {
+	e := canonical.NewEncoder()
+	e.String(sigHashTag)
+	e.Uint32(chainID)
+	marshalUnsigned(e, tx.inputs, tx.outputs, tx.replaceable)
+	e.Int64(int64(index))
+	e.Int64(int64(referenced.amount))
+	e.String(referenced.address)
+
+	hash := sha256.Sum256(e.Encoded())
+	return hash[:]
+}
@@ -0,0 +1,80 @@
+package transaction_test
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/wallet/domain/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigHash_shouldVerifyTransactionsSignedWithWalletKeys(t *testing.T) {
+	// given the main identity of a wallet, read back from where it was saved
+	key, err := wallet.NewEcdsaKey()
+	require.NoError(t, err)
+	dir, passphrase := t.TempDir(), "passphrase"
+	require.NoError(t, wallet.NewEcdsaWallet(&key).ExportWalletEcdsa(dir, &passphrase))
+	wl, err := wallet.ReadWalletFromDirectoryEcdsa(dir, &passphrase)
+	require.NoError(t, err)
+	raw, err := x509.MarshalPKIXPublicKey(wl.MainId.Public)
+	require.NoError(t, err)
+	ownerAddr := hex.EncodeToString(raw)
+	// and the outputs it owns
+	owned := []transaction.UnspentOutput{
+		transaction.NewUnspentOutput("first", 0, 60, ownerAddr),
+		transaction.NewUnspentOutput("second", 3, 50, ownerAddr),
+	}
+
+	// when it pays with both of them
+	tx, err := transaction.NewWithFee("receiver", ownerAddr, 100, 5, wl.MainId.Private(), newUnspent(t, owned...))
+	require.NoError(t, err)
+	require.Len(t, tx.Inputs(), 2)
+	// and the transaction goes through its encoding
+	body, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	var received transaction.Transaction
+	require.NoError(t, received.UnmarshalBinary(body))
+
+	// then its signatures are valid
+	coinbase, err := transaction.NewCoinbaseWithFees("miner", 1, 5)
+	require.NoError(t, err)
+	assert.NoError(t, transaction.ValidateBlockTransactions([]transaction.Transaction{*coinbase, received}, newUnspent(t, owned...), 1))
+
+	// and they do not hold once swapped between the inputs
+	inputs := received.Inputs()
+	swapped, err := transaction.NewFrom(
+		[]*transaction.Input{
+			transaction.NewInput(inputs[0].OutputID(), inputs[0].OutputIndex(), inputs[1].Signature()),
+			transaction.NewInput(inputs[1].OutputID(), inputs[1].OutputIndex(), inputs[0].Signature()),
+		},
+		outputsOf(received),
+	)
+	require.NoError(t, err)
+	assert.Error(t, transaction.ValidateBlockTransactions([]transaction.Transaction{*coinbase, *swapped}, newUnspent(t, owned...), 1))
+
+	// and nor once an output is changed
+	changed := outputsOf(received)
+	changed[0] = transaction.NewOutput(changed[0].Amount(), "thief")
+	redirected, err := transaction.NewFrom(inputsOf(received), changed)
+	require.NoError(t, err)
+	assert.Error(t, transaction.ValidateBlockTransactions([]transaction.Transaction{*coinbase, *redirected}, newUnspent(t, owned...), 1))
+}
+
+func inputsOf(tx transaction.Transaction) []*transaction.Input {
+	inputs := make([]*transaction.Input, 0, len(tx.Inputs()))
+	for _, in := range tx.Inputs() {
+		inputs = append(inputs, transaction.NewInput(in.OutputID(), in.OutputIndex(), in.Signature()))
+	}
+	return inputs
+}
+
+func outputsOf(tx transaction.Transaction) []*transaction.Output {
+	outputs := make([]*transaction.Output, 0, len(tx.Outputs()))
+	for _, out := range tx.Outputs() {
+		outputs = append(outputs, transaction.NewOutput(out.Amount(), out.Address()))
+	}
+	return outputs
+}
@@ -0,0 +1,70 @@
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+)
+
+// SubsidySchedule describes how many new coins the coinbase of a block can mint.
+// The subsidy starts at Initial and halves every HalvingInterval blocks, until it drops to zero.
+type SubsidySchedule struct {
+	Initial         int
+	HalvingInterval int
+}
+
+// ActiveSubsidy is the schedule of the network the node follows, the coinbase transactions are created and validated with it.
+func ActiveSubsidy() SubsidySchedule {
+	params := chaincfg.Active()
+	return SubsidySchedule{
+		Initial:         params.InitialSubsidy,
+		HalvingInterval: params.HalvingInterval,
+	}
+}
+
+// At returns the subsidy of the block at the given height.
+func (s SubsidySchedule) At(blockHeight int) int {
+	if blockHeight <= 0 || s.HalvingInterval <= 0 {
+		return 0
+	}
+	halvings := blockHeight / s.HalvingInterval
+	if halvings >= 63 {
+		return 0
+	}
+	return s.Initial >> halvings
+}
+
+// MaxSupply is the amount of coins there will ever be: the genesis allocation and all the subsidies.
+// The genesis block at height 0 has no coinbase.
+func (s SubsidySchedule) MaxSupply(genesisAmount int) int {
+	supply := genesisAmount
+	if s.HalvingInterval <= 0 {
+		return supply
+	}
+	for era := 0; era < 63 && s.Initial>>era > 0; era++ {
+		blocks := s.HalvingInterval
+		if era == 0 {
+			blocks--
+		}
+		supply += blocks * (s.Initial >> era)
+	}
+	return supply
+}
+
+// MaxAmount is the most an output, or the outputs of a transaction or a block together, can hold: all the coins
+// of the network there will ever be.
+func MaxAmount() int {
+	return ActiveSubsidy().MaxSupply(chaincfg.Active().GenesisAmount)
+}
+
+// addAmount adds the amount to the sum. The amount cannot be negative and the sum cannot go above the max,
+// so that no sum of amounts wraps around.
+func addAmount(sum, amount, max int) (int, error) {
+	if amount < 0 {
+		return 0, fmt.Errorf("amount cannot be negative, got %d", amount)
+	}
+	if amount > max || sum > max-amount {
+		return 0, fmt.Errorf("%w of %d", AmountTooLarge, max)
+	}
+	return sum + amount, nil
+}
@@ -0,0 +1,62 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubsidySchedule_At(t *testing.T) {
+	schedule := SubsidySchedule{Initial: 100, HalvingInterval: 10}
+
+	tt := []struct {
+		height   int
+		expected int
+	}{
+		{height: 0, expected: 0},
+		{height: 1, expected: 100},
+		{height: 9, expected: 100},
+		{height: 10, expected: 50},
+		{height: 25, expected: 25},
+		{height: 69, expected: 1},
+		{height: 70, expected: 0},
+		{height: 10_000, expected: 0},
+	}
+
+	for _, tc := range tt {
+		assert.Equal(t, tc.expected, schedule.At(tc.height), "height %d", tc.height)
+	}
+}
+
+func TestSubsidySchedule_MaxSupply(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	schedule := SubsidySchedule{Initial: 100, HalvingInterval: 10}
+
+	// when
+	issued := 1000
+	for height := 1; schedule.At(height) > 0; height++ {
+		issued += schedule.At(height)
+	}
+
+	// then
+	assert.Equal(issued, schedule.MaxSupply(1000))
+	assert.Equal(1000+9*100+10*(50+25+12+6+3+1), schedule.MaxSupply(1000))
+}
+
+func TestValidateCoinbaseAfterHalving(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	height := ActiveSubsidy().HalvingInterval
+	halved, err := NewCoinbase("miner", height)
+	assert.NoError(err)
+	full, err := NewFrom([]*Input{NewInput("", height, "")}, []*Output{NewOutput(ActiveSubsidy().Initial, "miner")})
+	assert.NoError(err)
+
+	// then
+	assert.Equal(ActiveSubsidy().Initial/2, halved.Outputs()[0].Amount())
+	assert.NoError(validateCoinbase(halved, height, 0))
+	assert.Error(validateCoinbase(full, height, 0))
+}
@@ -4,13 +4,9 @@ import (
 	"crypto"
 	"crypto/sha256"
 	"fmt"
-	"strings"
-)
 
-const (
-	COINBASE_AMOUNT = 100
-	GENESIS_AMOUNT  = 10000
-	GENESIS_ADDRESS = "3059301306072a8648ce3d020106082a8648ce3d03010703420004376119d02e6b95174f1c6af6bdc26c4280036104909fc8025dd3ebf8ed524e5abe265b67c1102edd0204ebdc3ab8556fe979be13a51526cea0d414b133061ec3" // public marshaled x509 PKIX
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 )
 
 // ID is the transaction ID, represented as a base64 string
@@ -24,32 +20,44 @@ func (h ID) MarshalBinary() ([]byte, error) {
 	return []byte(h), nil
 }
 
-func newID(ins []*Input, outs []*Output) (ID, error) {
-	var sb strings.Builder
+// newID hashes the canonical encoding of the transaction without the signatures, as they sign what it is made of.
+func newID(ins []*Input, outs []*Output, replaceable bool) (ID, error) {
+	e := canonical.NewEncoder()
+	marshalUnsigned(e, ins, outs, replaceable)
 
-	for _, in := range ins {
-		sb.WriteString(in.outputID.String())
-		sb.WriteString(fmt.Sprint(in.outputIndex))
-	}
+	hash := sha256.Sum256(e.Encoded())
+	return ID(hash[:]), nil
+}
 
-	for _, out := range outs {
-		sb.WriteString(fmt.Sprint(out.amount))
-		sb.WriteString(fmt.Sprint(out.address))
+// marshalUnsigned writes the outputs the inputs spend, the outputs and whether the transaction can be replaced,
+// which is all of the transaction but the signatures.
+func marshalUnsigned(e *canonical.Encoder, ins []*Input, outs []*Output, replaceable bool) {
+	e.Length(len(ins))
+	for _, in := range ins {
+		e.String(string(in.outputID))
+		e.Int64(int64(in.outputIndex))
 	}
+	marshalOutputs(e, outs)
+	marshalReplaceable(e, ins, replaceable)
+}
 
-	h := sha256.New()
-	_, err := h.Write([]byte(sb.String()))
-	if err != nil {
-		return "", fmt.Errorf("error writing to hash: %w", err)
+// marshalReplaceable writes whether the transaction can be replaced. Only the transactions spending outputs wait
+// in the pool, so the genesis and the coinbase transactions leave it out and keep the encoding they always had.
+func marshalReplaceable(e *canonical.Encoder, ins []*Input, replaceable bool) {
+	if spendsOutputs(ins) {
+		e.Bool(replaceable)
 	}
+}
 
-	return ID(h.Sum(nil)), nil
+func spendsOutputs(ins []*Input) bool {
+	return len(ins) > 0 && !(Transaction{inputs: ins}).IsCoinbase()
 }
 
 type Transaction struct {
-	id      ID
-	inputs  []*Input
-	outputs []*Output
+	id          ID
+	inputs      []*Input
+	outputs     []*Output
+	replaceable bool
 }
 
 // ID() returns the transaction ID
@@ -75,55 +83,128 @@ func (t Transaction) Outputs() []Output {
 	return oo
 }
 
-func (t Transaction) MarshalBinary() ([]byte, error) {
-	var transactionBytes []byte
+// Replaceable() reports whether the sender agreed to the transaction being replaced by one paying a higher fee
+// while it waits in the pool. The ID and the signatures commit to it, so nobody else can change it.
+func (t Transaction) Replaceable() bool {
+	return t.replaceable
+}
 
+// IsCoinbase() reports whether the transaction mints new coins for the miner of a block
+func (t Transaction) IsCoinbase() bool {
+	return len(t.inputs) == 1 && t.inputs[0].outputID == ""
+}
+
+// MarshalCanonical writes the inputs with their signatures, the outputs and whether the transaction can be replaced.
+// The ID is left out, as it is derived from them.
+func (t Transaction) MarshalCanonical(e *canonical.Encoder) {
+	e.Length(len(t.inputs))
 	for _, in := range t.inputs {
-		inBytes, err := in.MarshalBinary()
-		if err != nil {
-			return nil, fmt.Errorf("error marshalling input: %w", err)
-		}
-		transactionBytes = append(transactionBytes, inBytes...)
+		in.MarshalCanonical(e)
 	}
+	marshalOutputs(e, t.outputs)
+	marshalReplaceable(e, t.inputs, t.replaceable)
+}
 
-	for _, out := range t.outputs {
-		outBytes, err := out.MarshalBinary()
-		if err != nil {
-			return nil, fmt.Errorf("error marshalling output: %w", err)
-		}
-		transactionBytes = append(transactionBytes, outBytes...)
+// UnmarshalCanonical reads the transaction and derives its ID.
+func (t *Transaction) UnmarshalCanonical(d *canonical.Decoder) {
+	t.inputs = make([]*Input, d.Length())
+	for i := range t.inputs {
+		t.inputs[i] = &Input{}
+		t.inputs[i].UnmarshalCanonical(d)
 	}
-
-	transactionIDBytes, err := t.id.MarshalBinary()
+	t.outputs = make([]*Output, d.Length())
+	for i := range t.outputs {
+		t.outputs[i] = &Output{}
+		t.outputs[i].UnmarshalCanonical(d)
+	}
+	if spendsOutputs(t.inputs) {
+		t.replaceable = d.Bool()
+	}
+	if d.Err() != nil {
+		return
+	}
+	id, err := newID(t.inputs, t.outputs, t.replaceable)
 	if err != nil {
-		return nil, fmt.Errorf("error marshalling transaction ID: %w", err)
+		d.Fail(err)
+		return
+	}
+	t.id = id
+}
+
+func marshalOutputs(e *canonical.Encoder, outs []*Output) {
+	e.Length(len(outs))
+	for _, out := range outs {
+		out.MarshalCanonical(e)
 	}
-	transactionBytes = append(transactionBytes, transactionIDBytes...)
+}
+
+// Size is how many bytes the canonical encoding of the transaction takes up, which is what the limits of the blocks
+// and of the pool count.
+func (t Transaction) Size() int {
+	return len(canonical.Marshal(t))
+}
+
+func (t Transaction) MarshalBinary() ([]byte, error) {
+	return canonical.Marshal(t), nil
+}
 
-	return transactionBytes, nil
+func (t *Transaction) UnmarshalBinary(data []byte) error {
+	return canonical.Unmarshal(data, t)
 }
 
 func NewFrom(inputs []*Input, outputs []*Output) (*Transaction, error) {
-	id, err := newID(inputs, outputs)
+	return newFrom(inputs, outputs, false)
+}
+
+// NewReplaceableFrom creates a transaction which can be replaced by one paying a higher fee while it waits in the pool.
+func NewReplaceableFrom(inputs []*Input, outputs []*Output) (*Transaction, error) {
+	return newFrom(inputs, outputs, true)
+}
+
+func newFrom(inputs []*Input, outputs []*Output, replaceable bool) (*Transaction, error) {
+	if replaceable && !spendsOutputs(inputs) {
+		return nil, fmt.Errorf("only a transaction spending outputs can be replaceable")
+	}
+	id, err := newID(inputs, outputs, replaceable)
 	if err != nil {
 		return nil, fmt.Errorf("error creating transaction ID: %w", err)
 	}
 
 	return &Transaction{
-		id:      id,
-		inputs:  inputs,
-		outputs: outputs,
+		id:          id,
+		inputs:      inputs,
+		outputs:     outputs,
+		replaceable: replaceable,
 	}, nil
 }
 
 func New(receiverAddr string, senderAddr string, amount int, pk crypto.Signer, unspentOutputRepository UnspentOutputRepository) (*Transaction, error) {
+	return NewWithFee(receiverAddr, senderAddr, amount, 0, pk, unspentOutputRepository)
+}
+
+// NewWithFee creates a transaction which leaves the fee to the miner of the block including it,
+// by sending back as change less than the inputs are worth.
+func NewWithFee(receiverAddr string, senderAddr string, amount int, fee int, pk crypto.Signer, unspentOutputRepository UnspentOutputRepository) (*Transaction, error) {
+	return newWithFee(receiverAddr, senderAddr, amount, fee, false, pk, unspentOutputRepository)
+}
+
+// NewReplaceableWithFee creates a transaction like NewWithFee, which can then be replaced to raise its fee, see BumpFee.
+func NewReplaceableWithFee(receiverAddr string, senderAddr string, amount int, fee int, pk crypto.Signer, unspentOutputRepository UnspentOutputRepository) (*Transaction, error) {
+	return newWithFee(receiverAddr, senderAddr, amount, fee, true, pk, unspentOutputRepository)
+}
+
+func newWithFee(receiverAddr string, senderAddr string, amount int, fee int, replaceable bool, pk crypto.Signer, unspentOutputRepository UnspentOutputRepository) (*Transaction, error) {
+	if fee < 0 {
+		return nil, fmt.Errorf("fee cannot be negative, got %d", fee)
+	}
+
 	unspentOutputs, err := unspentOutputRepository.GetByAddress(senderAddr)
 	if err != nil {
 		return nil, fmt.Errorf("error getting unspent Ou: %w", err)
 	}
 
 	// TODO#38 - filter unspent Ou already present in the pool
-	leftover, included, err := calculateUnspentForAmount(unspentOutputs, amount)
+	leftover, included, err := calculateUnspentForAmount(unspentOutputs, amount+fee)
 	if err != nil {
 		return nil, fmt.Errorf("error calculating unspent Ou: %w", err)
 	}
@@ -134,13 +215,13 @@ func New(receiverAddr string, senderAddr string, amount int, pk crypto.Signer, u
 	}
 
 	outputs := generateOutputsFor(amount, leftover, senderAddr, receiverAddr)
-	tx, err := NewFrom(inputs, outputs)
+	tx, err := newFrom(inputs, outputs, replaceable)
 	if err != nil {
 		return nil, fmt.Errorf("error creating transaction: %w", err)
 	}
 
 	for i, in := range tx.inputs {
-		err := in.sign(pk, tx.id, included[i])
+		err := in.sign(pk, tx, i, included[i])
 		if err != nil {
 			return nil, fmt.Errorf("error signing input: %w", err)
 		}
@@ -149,32 +230,95 @@ func New(receiverAddr string, senderAddr string, amount int, pk crypto.Signer, u
 	return tx, nil
 }
 
+// BumpFee creates the replacement of the transaction which pays the miner more by the given amount, taken out of
+// the change the transaction sends back to the sender. The replacement spends the same outputs, whose amounts and
+// owners are looked up in the unspent outputs, so it conflicts with the transaction and pays a higher fee and fee rate.
+// Only a replaceable transaction can be bumped, and the replacement can be bumped again.
+func BumpFee(tx Transaction, by int, senderAddr string, pk crypto.Signer, unspentOutputRepository UnspentOutputRepository) (*Transaction, error) {
+	if !tx.replaceable {
+		return nil, fmt.Errorf("transaction %x was not created replaceable", tx.id)
+	}
+	if by <= 0 {
+		return nil, fmt.Errorf("fee can only be raised, got %d", by)
+	}
+	change := -1
+	for i, out := range tx.outputs {
+		if out.address == senderAddr {
+			change = i
+		}
+	}
+	if change < 0 {
+		return nil, fmt.Errorf("transaction sends no change back to take the fee from")
+	}
+	if left := tx.outputs[change].amount; left < by {
+		return nil, fmt.Errorf("change of %d does not cover the fee raised by %d", left, by)
+	}
+
+	inputs := make([]*Input, len(tx.inputs))
+	for i, in := range tx.inputs {
+		inputs[i] = NewInput(in.outputID, in.outputIndex, "")
+	}
+	outputs := make([]*Output, 0, len(tx.outputs))
+	for i, out := range tx.outputs {
+		switch {
+		case i != change:
+			outputs = append(outputs, NewOutput(out.amount, out.address))
+		case out.amount > by:
+			outputs = append(outputs, NewOutput(out.amount-by, out.address))
+		}
+	}
+	bumped, err := NewReplaceableFrom(inputs, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	for i, in := range bumped.inputs {
+		referenced, err := unspentOutputRepository.GetByOutputIDAndIndex(in.outputID, in.outputIndex)
+		if err != nil {
+			return nil, fmt.Errorf("error getting referenced output: %w", err)
+		}
+		if referenced == (UnspentOutput{}) {
+			return nil, fmt.Errorf("output %x:%d is not unspent", in.outputID, in.outputIndex)
+		}
+		if err := in.sign(pk, bumped, i, referenced); err != nil {
+			return nil, fmt.Errorf("error signing input: %w", err)
+		}
+	}
+	return bumped, nil
+}
+
 func NewGenesis() (*Transaction, error) {
+	return NewGenesisFor(chaincfg.Active())
+}
+
+// NewGenesisFor creates the transaction of the genesis block of the given network, handing out its genesis amount.
+func NewGenesisFor(params chaincfg.ChainParams) (*Transaction, error) {
 	inputs := []*Input{}
 	outputs := []*Output{
-		NewOutput(GENESIS_AMOUNT, GENESIS_ADDRESS),
+		NewOutput(params.GenesisAmount, params.GenesisAddress),
 	}
 
 	return NewFrom(inputs, outputs)
 }
 
 func NewCoinbase(receiverAddr string, blockHeight int) (*Transaction, error) {
+	return NewCoinbaseWithFees(receiverAddr, blockHeight, 0)
+}
+
+// NewCoinbaseWithFees creates a coinbase claiming the subsidy for the block height together with the fees of the other transactions in the block.
+func NewCoinbaseWithFees(receiverAddr string, blockHeight int, fees int) (*Transaction, error) {
+	return NewCoinbaseWithOutputs(blockHeight, []*Output{
+		NewOutput(ActiveSubsidy().At(blockHeight)+fees, receiverAddr),
+	})
+}
+
+// NewCoinbaseWithOutputs creates a coinbase splitting the reward of the block between the outputs.
+// The outputs have to add up to the subsidy for the block height together with the fees of the block.
+func NewCoinbaseWithOutputs(blockHeight int, outputs []*Output) (*Transaction, error) {
 	in := NewInput("", blockHeight, "")
 	inputs := []*Input{
 		in,
 	}
-	outputs := []*Output{
-		NewOutput(COINBASE_AMOUNT, receiverAddr),
-	}
-
-	id, err := newID(inputs, outputs)
-	if err != nil {
-		return nil, fmt.Errorf("error creating transaction ID: %w", err)
-	}
 
-	return &Transaction{
-		id:      id,
-		inputs:  inputs,
-		outputs: outputs,
-	}, nil
+	return NewFrom(inputs, outputs)
 }
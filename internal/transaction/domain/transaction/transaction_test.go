@@ -55,6 +55,87 @@ func TestCreatingTransaction(t *testing.T) {
 	assert.NotEmpty(tx.Inputs()[0].Signature())
 }
 
+func TestCreatingTransactionWithFee(t *testing.T) {
+	assert := assert.New(t)
+	// given sender with an unspent output
+	privateSender, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+	senderAddrRaw, err := x509.MarshalPKIXPublicKey(privateSender.Public())
+	assert.NoError(err)
+	senderAddr := hex.EncodeToString(senderAddrRaw)
+	someTransaction, err := transactiontest.NewGenesisLike(senderAddr, 100)
+	assert.NoError(err)
+	unspentOutputRepo := &mock.UnspentOutputRepository{
+		UnspentOutputs: map[string][]transaction.UnspentOutput{
+			senderAddr: {transaction.NewUnspentOutput(someTransaction.ID(), 0, 100, senderAddr)},
+		},
+	}
+
+	// when sending 60 with a fee of 15
+	tx, err := transaction.NewWithFee("receiver", senderAddr, 60, 15, privateSender, unspentOutputRepo)
+	assert.NoError(err)
+
+	// then the change is what is left after the fee
+	assert.Equal([]transaction.Output{*transaction.NewOutput(60, "receiver"), *transaction.NewOutput(25, senderAddr)}, tx.Outputs())
+	fee, err := transaction.Fee(*tx, unspentOutputRepo)
+	assert.NoError(err)
+	assert.Equal(15, fee)
+
+	// and when the fee cannot be covered
+	_, err = transaction.NewWithFee("receiver", senderAddr, 60, 50, privateSender, unspentOutputRepo)
+	// then
+	assert.Error(err)
+}
+
+func TestBumpFee(t *testing.T) {
+	assert := assert.New(t)
+	// given a transaction sending 60 with a fee of 15
+	privateSender, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+	senderAddrRaw, err := x509.MarshalPKIXPublicKey(privateSender.Public())
+	assert.NoError(err)
+	senderAddr := hex.EncodeToString(senderAddrRaw)
+	someTransaction, err := transactiontest.NewGenesisLike(senderAddr, 100)
+	assert.NoError(err)
+	unspentOutputRepo := &mock.UnspentOutputRepository{
+		UnspentOutputs: map[string][]transaction.UnspentOutput{
+			senderAddr: {transaction.NewUnspentOutput(someTransaction.ID(), 0, 100, senderAddr)},
+		},
+	}
+	tx, err := transaction.NewReplaceableWithFee("receiver", senderAddr, 60, 15, privateSender, unspentOutputRepo)
+	assert.NoError(err)
+
+	// when the fee is raised by 10
+	bumped, err := transaction.BumpFee(*tx, 10, senderAddr, privateSender, unspentOutputRepo)
+	assert.NoError(err)
+
+	// then the replacement spends the same output and takes the fee out of the change
+	assert.NotEqual(tx.ID(), bumped.ID())
+	assert.True(bumped.Replaceable())
+	assert.Equal(tx.Inputs()[0].OutputID(), bumped.Inputs()[0].OutputID())
+	assert.Equal([]transaction.Output{*transaction.NewOutput(60, "receiver"), *transaction.NewOutput(15, senderAddr)}, bumped.Outputs())
+	fee, err := transaction.Fee(*bumped, unspentOutputRepo)
+	assert.NoError(err)
+	assert.Equal(25, fee)
+
+	// and when the whole change goes to the fee, the change output is dropped
+	bumped, err = transaction.BumpFee(*tx, 25, senderAddr, privateSender, unspentOutputRepo)
+	assert.NoError(err)
+	assert.Equal([]transaction.Output{*transaction.NewOutput(60, "receiver")}, bumped.Outputs())
+
+	// and when the change cannot cover the raise
+	_, err = transaction.BumpFee(*tx, 26, senderAddr, privateSender, unspentOutputRepo)
+	// then
+	assert.Error(err)
+
+	// and when the transaction was not created replaceable
+	final, err := transaction.NewWithFee("receiver", senderAddr, 60, 15, privateSender, unspentOutputRepo)
+	assert.NoError(err)
+	_, err = transaction.BumpFee(*final, 10, senderAddr, privateSender, unspentOutputRepo)
+	// then
+	assert.Error(err)
+}
+
 func TestCreateCoinbase(t *testing.T) {
 	assert := assert.New(t)
 	// given some receiver
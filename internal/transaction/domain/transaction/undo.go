@@ -0,0 +1,53 @@
+package transaction
+
+import "fmt"
+
+// Undo describes how a block changed the unspent outputs. It is kept for every connected block,
+// so that the change can be reverted when the block gets disconnected from the chain.
+type Undo struct {
+	Spent   []UnspentOutput
+	Created []UnspentOutput
+}
+
+// NewUndo works out which of the unspent outputs the transactions spend and which outputs they create.
+// Outputs created and spent by the same transactions appear in neither.
+func NewUndo(transactions []Transaction, unspent UnspentOutputRepository) (Undo, error) {
+	spent := make([]UnspentOutput, 0)
+	created := make(map[outpoint]UnspentOutput)
+	order := make([]outpoint, 0)
+
+	for _, tx := range transactions {
+		if !tx.IsCoinbase() {
+			for _, in := range tx.inputs {
+				key := outpoint{id: in.outputID, index: in.outputIndex}
+				if _, ok := created[key]; ok {
+					delete(created, key)
+					continue
+				}
+
+				output, err := unspent.GetByOutputIDAndIndex(in.outputID, in.outputIndex)
+				if err != nil {
+					return Undo{}, fmt.Errorf("error getting spent output: %w", err)
+				}
+				if output == (UnspentOutput{}) {
+					return Undo{}, fmt.Errorf("output %x:%d is not unspent", in.outputID, in.outputIndex)
+				}
+				spent = append(spent, output)
+			}
+		}
+
+		for i, out := range tx.outputs {
+			key := outpoint{id: tx.id, index: i}
+			order = append(order, key)
+			created[key] = NewUnspentOutput(tx.id, i, out.amount, out.address)
+		}
+	}
+
+	undo := Undo{Spent: spent, Created: make([]UnspentOutput, 0, len(created))}
+	for _, key := range order {
+		if output, ok := created[key]; ok {
+			undo.Created = append(undo.Created, output)
+		}
+	}
+	return undo, nil
+}
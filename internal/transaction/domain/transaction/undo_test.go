@@ -0,0 +1,49 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUndo(t *testing.T) {
+	assert := assert.New(t)
+
+	// given an unspent output
+	funding, err := NewFrom([]*Input{}, []*Output{NewOutput(100, "owner")})
+	assert.NoError(err)
+	unspent := newUnspentSet(UnspentOutputsFrom([]Transaction{*funding}))
+
+	// and a block spending it, with an output spent again within the block
+	coinbase, err := NewCoinbase("miner", 1)
+	assert.NoError(err)
+	spend, err := NewFrom([]*Input{NewInput(funding.ID(), 0, "")}, []*Output{NewOutput(60, "first"), NewOutput(40, "owner")})
+	assert.NoError(err)
+	respend, err := NewFrom([]*Input{NewInput(spend.ID(), 0, "")}, []*Output{NewOutput(60, "second")})
+	assert.NoError(err)
+
+	// when
+	undo, err := NewUndo([]Transaction{*coinbase, *spend, *respend}, unspent)
+
+	// then
+	assert.NoError(err)
+	assert.Equal([]UnspentOutput{NewUnspentOutput(funding.ID(), 0, 100, "owner")}, undo.Spent)
+	assert.Equal([]UnspentOutput{
+		NewUnspentOutput(coinbase.ID(), 0, chaincfg.Active().InitialSubsidy, "miner"),
+		NewUnspentOutput(spend.ID(), 1, 40, "owner"),
+		NewUnspentOutput(respend.ID(), 0, 60, "second"),
+	}, undo.Created)
+}
+
+func TestNewUndo_shouldErrorWhenOutputIsNotUnspent(t *testing.T) {
+	// given
+	spend, err := NewFrom([]*Input{NewInput("unknown", 0, "")}, []*Output{NewOutput(60, "first")})
+	assert.NoError(t, err)
+
+	// when
+	_, err = NewUndo([]Transaction{*spend}, newUnspentSet(nil))
+
+	// then
+	assert.Error(t, err)
+}
@@ -49,6 +49,50 @@ type UnspentOutputRepository interface {
 	Set(unspentOutputs []UnspentOutput) error
 }
 
+// UnspentOutputsFrom replays the transactions in order and returns the outputs which were not spent by any of them.
+func UnspentOutputsFrom(transactions []Transaction) []UnspentOutput {
+	return ApplyTransactions(nil, transactions)
+}
+
+// ApplyTransactions replays the transactions in order on top of the unspent outputs.
+// It returns the outputs which are left unspent afterwards, the new ones after the old ones.
+func ApplyTransactions(unspentOutputs []UnspentOutput, transactions []Transaction) []UnspentOutput {
+	order := make([]outpoint, 0, len(unspentOutputs))
+	unspent := make(map[outpoint]UnspentOutput, len(unspentOutputs))
+	for _, output := range unspentOutputs {
+		key := outpoint{id: output.outputID, index: output.outputIndex}
+		order = append(order, key)
+		unspent[key] = output
+	}
+
+	for _, tx := range transactions {
+		if !tx.IsCoinbase() {
+			for _, in := range tx.inputs {
+				delete(unspent, outpoint{id: in.outputID, index: in.outputIndex})
+			}
+		}
+		for i, out := range tx.outputs {
+			key := outpoint{id: tx.id, index: i}
+			order = append(order, key)
+			unspent[key] = NewUnspentOutput(tx.id, i, out.amount, out.address)
+		}
+	}
+
+	result := make([]UnspentOutput, 0, len(unspent))
+	for _, key := range order {
+		if output, ok := unspent[key]; ok {
+			result = append(result, output)
+			delete(unspent, key)
+		}
+	}
+	return result
+}
+
+type outpoint struct {
+	id    ID
+	index int
+}
+
 func calculateUnspentForAmount(unspentOutputs []UnspentOutput, amount int) (leftover int, included []UnspentOutput, err error) {
 	currentAmount := 0
 	for _, unspentOutput := range unspentOutputs {
@@ -66,3 +110,105 @@ func calculateUnspentForAmount(unspentOutputs []UnspentOutput, amount int) (left
 
 	return currentAmount - amount, included, nil
 }
+
+// UnspentView is the unspent outputs of a repository with blocks connected or disconnected on top of it, without
+// changing the repository. It tells the outputs unspent at any block near the tip without replaying the chain.
+// The outputs not changed by the view are read through to the repository.
+type UnspentView struct {
+	base    UnspentOutputRepository
+	changed map[outpoint]UnspentOutput // the empty output for the ones which are spent
+	order   []outpoint                 // the changed outputs, in the order they were first changed
+}
+
+func NewUnspentView(base UnspentOutputRepository) *UnspentView {
+	return &UnspentView{base: base, changed: make(map[outpoint]UnspentOutput)}
+}
+
+func (v *UnspentView) change(key outpoint, output UnspentOutput) {
+	if _, ok := v.changed[key]; !ok {
+		v.order = append(v.order, key)
+	}
+	v.changed[key] = output
+}
+
+// Connect spends the outputs the transactions spend and adds the ones they create, in order.
+func (v *UnspentView) Connect(transactions []Transaction) {
+	for _, tx := range transactions {
+		v.apply(tx)
+	}
+}
+
+func (v *UnspentView) apply(tx Transaction) {
+	if !tx.IsCoinbase() {
+		for _, in := range tx.inputs {
+			v.change(outpoint{id: in.outputID, index: in.outputIndex}, UnspentOutput{})
+		}
+	}
+	for i, out := range tx.outputs {
+		v.change(outpoint{id: tx.id, index: i}, NewUnspentOutput(tx.id, i, out.amount, out.address))
+	}
+}
+
+// Disconnect reverts the change a block made to the outputs, as described by its undo data.
+func (v *UnspentView) Disconnect(undo Undo) {
+	for _, output := range undo.Created {
+		v.change(outpoint{id: output.outputID, index: output.outputIndex}, UnspentOutput{})
+	}
+	for _, output := range undo.Spent {
+		v.change(outpoint{id: output.outputID, index: output.outputIndex}, output)
+	}
+}
+
+func (v *UnspentView) GetAll() ([]UnspentOutput, error) {
+	all, err := v.base.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return v.merge(all, func(UnspentOutput) bool { return true }), nil
+}
+
+func (v *UnspentView) GetByAddress(address string) ([]UnspentOutput, error) {
+	owned, err := v.base.GetByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return v.merge(owned, func(output UnspentOutput) bool { return output.address == address }), nil
+}
+
+// merge drops the outputs of the base spent in the view and adds the ones the view created after them, in the order
+// they were created, so that the same outputs are always listed in the same order.
+func (v *UnspentView) merge(base []UnspentOutput, keep func(UnspentOutput) bool) []UnspentOutput {
+	merged := make([]UnspentOutput, 0, len(base))
+	inBase := make(map[outpoint]struct{}, len(base))
+	for _, output := range base {
+		key := outpoint{id: output.outputID, index: output.outputIndex}
+		inBase[key] = struct{}{}
+		if changed, ok := v.changed[key]; !ok {
+			merged = append(merged, output)
+		} else if changed != (UnspentOutput{}) {
+			merged = append(merged, changed)
+		}
+	}
+	for _, key := range v.order {
+		if _, ok := inBase[key]; ok {
+			continue
+		}
+		if output := v.changed[key]; output != (UnspentOutput{}) && keep(output) {
+			merged = append(merged, output)
+		}
+	}
+	return merged
+}
+
+func (v *UnspentView) GetByOutputIDAndIndex(outputID ID, outputIndex int) (UnspentOutput, error) {
+	if output, ok := v.changed[outpoint{id: outputID, index: outputIndex}]; ok {
+		return output, nil
+	}
+	return v.base.GetByOutputIDAndIndex(outputID, outputIndex)
+}
+
+// Set replaces the outputs of the view, leaving the repository as it is.
+func (v *UnspentView) Set(unspentOutputs []UnspentOutput) error {
+	v.base, v.changed, v.order = newUnspentSet(unspentOutputs), make(map[outpoint]UnspentOutput), nil
+	return nil
+}
@@ -3,6 +3,7 @@ package transaction
 import (
 	"testing"
 
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,3 +34,102 @@ func TestUnspentWhenNotEnough(t *testing.T) {
 	// then
 	assert.Error(err)
 }
+
+func TestUnspentOutputsFrom(t *testing.T) {
+	assert := assert.New(t)
+	// given a genesis-like transaction
+	genesis, err := NewFrom([]*Input{}, []*Output{NewOutput(100, "someAddress-1")})
+	assert.NoError(err)
+	// and a coinbase
+	coinbase, err := NewCoinbase("someAddress-2", 1)
+	assert.NoError(err)
+	// and a transaction spending the genesis output
+	spending, err := NewFrom(
+		[]*Input{NewInput(genesis.ID(), 0, "signature")},
+		[]*Output{NewOutput(60, "someAddress-2"), NewOutput(40, "someAddress-1")},
+	)
+	assert.NoError(err)
+
+	// when
+	unspent := UnspentOutputsFrom([]Transaction{*genesis, *coinbase, *spending})
+
+	// then
+	assert.Equal([]UnspentOutput{
+		NewUnspentOutput(coinbase.ID(), 0, chaincfg.Active().InitialSubsidy, "someAddress-2"),
+		NewUnspentOutput(spending.ID(), 0, 60, "someAddress-2"),
+		NewUnspentOutput(spending.ID(), 1, 40, "someAddress-1"),
+	}, unspent)
+}
+
+func TestUnspentView(t *testing.T) {
+	assert := assert.New(t)
+	// given the outputs unspent at the tip
+	genesis, err := NewFrom([]*Input{}, []*Output{NewOutput(100, "someAddress-1")})
+	assert.NoError(err)
+	spending, err := NewFrom(
+		[]*Input{NewInput(genesis.ID(), 0, "signature")},
+		[]*Output{NewOutput(60, "someAddress-2"), NewOutput(40, "someAddress-1")},
+	)
+	assert.NoError(err)
+	atParent := UnspentOutputsFrom([]Transaction{*genesis})
+	undo, err := NewUndo([]Transaction{*spending}, newUnspentSet(atParent))
+	assert.NoError(err)
+	tip := newUnspentSet(ApplyTransactions(atParent, []Transaction{*spending}))
+
+	// when the tip is disconnected on top of them
+	view := NewUnspentView(tip)
+	view.Disconnect(undo)
+
+	// then the view holds the outputs unspent at the parent
+	all, err := view.GetAll()
+	assert.NoError(err)
+	assert.ElementsMatch(atParent, all)
+	owned, err := view.GetByAddress("someAddress-2")
+	assert.NoError(err)
+	assert.Empty(owned)
+	// and the outputs at the tip are left as they are
+	output, err := tip.GetByOutputIDAndIndex(spending.ID(), 0)
+	assert.NoError(err)
+	assert.Equal(NewUnspentOutput(spending.ID(), 0, 60, "someAddress-2"), output)
+
+	// when the tip is connected again
+	view.Connect([]Transaction{*spending})
+
+	// then the view holds the outputs unspent at the tip
+	all, err = view.GetAll()
+	assert.NoError(err)
+	assert.ElementsMatch(ApplyTransactions(atParent, []Transaction{*spending}), all)
+	output, err = view.GetByOutputIDAndIndex(genesis.ID(), 0)
+	assert.NoError(err)
+	assert.Equal(UnspentOutput{}, output)
+}
+
+func TestUnspentView_shouldListTheOutputsInOrder(t *testing.T) {
+	assert := assert.New(t)
+	// given the outputs of an address, one of which gets spent
+	base := []UnspentOutput{
+		NewUnspentOutput("first", 0, 10, "someAddress-1"),
+		NewUnspentOutput("second", 0, 20, "someAddress-1"),
+		NewUnspentOutput("third", 0, 30, "someAddress-1"),
+	}
+	paying, err := NewFrom(
+		[]*Input{NewInput("second", 0, "signature")},
+		[]*Output{NewOutput(1, "someAddress-1"), NewOutput(2, "someAddress-1"), NewOutput(3, "someAddress-1"), NewOutput(4, "someAddress-1"), NewOutput(5, "someAddress-1")},
+	)
+	assert.NoError(err)
+	view := NewUnspentView(newUnspentSet(base))
+
+	// when
+	view.Connect([]Transaction{*paying})
+
+	// then the outputs left are listed first, then the ones created, in the order they were created
+	expected := []UnspentOutput{base[0], base[2]}
+	for i, out := range paying.Outputs() {
+		expected = append(expected, NewUnspentOutput(paying.ID(), i, out.Amount(), "someAddress-1"))
+	}
+	for range 10 {
+		owned, err := view.GetByAddress("someAddress-1")
+		assert.NoError(err)
+		assert.Equal(expected, owned)
+	}
+}
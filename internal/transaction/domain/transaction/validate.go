@@ -2,35 +2,51 @@ package transaction
 
 import (
 	"crypto/ecdsa"
-	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"math/big"
+
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 )
 
-func validateCoinbase(tx *Transaction, blockHeight int) error {
+func validateCoinbase(tx *Transaction, blockHeight int, fees int) error {
 	if tx == nil {
 		return fmt.Errorf("transaction is nil, the first transaction in a block must be a coinbase transaction")
 	}
 	if ins := len(tx.inputs); ins != 1 {
 		return fmt.Errorf("coinbase transaction must have one input, got %d", ins)
 	}
+	if !tx.IsCoinbase() {
+		return fmt.Errorf("coinbase transaction must not spend an output, got %x:%d", tx.inputs[0].outputID, tx.inputs[0].outputIndex)
+	}
 	if tx.inputs[0].OutputIndex() != blockHeight {
 		return fmt.Errorf("coinbase transaction input must have output index equal to block height, got %d", tx.inputs[0].OutputIndex())
 	}
-	if outs := len(tx.outputs); outs != 1 {
-		return fmt.Errorf("coinbase transaction must have one output, got %d", outs)
+	if len(tx.outputs) == 0 {
+		return fmt.Errorf("coinbase transaction must have at least one output")
+	}
+	total, max := 0, MaxAmount()
+	for _, output := range tx.outputs {
+		if output.amount <= 0 {
+			return fmt.Errorf("coinbase transaction output amount must be positive, got %d", output.amount)
+		}
+		var err error
+		if total, err = addAmount(total, output.amount, max); err != nil {
+			return fmt.Errorf("coinbase transaction outputs: %w", err)
+		}
 	}
-	if tx.outputs[0].amount != COINBASE_AMOUNT {
-		return fmt.Errorf("coinbase transaction output amount must be %d, got %d", COINBASE_AMOUNT, tx.outputs[0].amount)
+	if expected := ActiveSubsidy().At(blockHeight) + fees; total != expected {
+		return fmt.Errorf("coinbase transaction outputs must add up to %d, got %d", expected, total)
 	}
 
 	return nil
 }
 
-func validateTransactionIn(inputTx *Input, tx *Transaction, unspent UnspentOutputRepository) error {
+// validateTransactionIn checks that the input at the given index of the transaction is signed by the owner of the output
+// it spends, see sigHash.
+func validateTransactionIn(tx *Transaction, index int, unspent UnspentOutputRepository) error {
+	inputTx := tx.inputs[index]
 	referencedOutput, err := unspent.GetByOutputIDAndIndex(
 		inputTx.outputID,
 		inputTx.outputIndex,
@@ -42,52 +58,51 @@ func validateTransactionIn(inputTx *Input, tx *Transaction, unspent UnspentOutpu
 		return fmt.Errorf("referenced output not found")
 	}
 
-	// Decode the address (public key) from the referenced output
-	pubKeyBytes, err := hex.DecodeString(referencedOutput.Address())
+	publicKey, err := publicKeyOf(referencedOutput.Address())
 	if err != nil {
-		return fmt.Errorf("failed to decode public key: %w", err)
+		return err
+	}
+
+	// Verify the signature over the sighash of the input
+	return inputTx.verify(publicKey, sigHash(tx, index, referencedOutput, chaincfg.Active().ChainID))
+}
+
+// ValidateAddress tells whether the outputs paid to the address could ever be spent, that is whether the address is
+// an ECDSA public key.
+func ValidateAddress(address string) error {
+	_, err := publicKeyOf(address)
+	return err
+}
+
+// publicKeyOf the address, which is the hex of the PKIX encoding of the public key.
+func publicKeyOf(address string) (*ecdsa.PublicKey, error) {
+	pubKeyBytes, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
 	}
 
-	// Parse the public key
 	publicKeyRaw, err := x509.ParsePKIXPublicKey(pubKeyBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse public key: %w", err)
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
 	publicKey, ok := publicKeyRaw.(*ecdsa.PublicKey)
 	if !ok {
-		return errors.New("failed to parse public key: not an ECDSA public key")
+		return nil, errors.New("failed to parse public key: not an ECDSA public key")
 	}
 
-	// Verify that the referenced output's address matches the public key
+	// Verify that the address is the very encoding of the public key
 	marshaledPubKey, err := x509.MarshalPKIXPublicKey(publicKey)
 	if err != nil {
-		return fmt.Errorf("failed to marshal public key: %w", err)
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
 	if string(pubKeyBytes) != string(marshaledPubKey) {
-		return errors.New("address does not match the public key")
-	}
-
-	// Hash the transaction ID
-	txIDHash := sha256.Sum256([]byte(tx.ID().String()))
-
-	// Decode the signature
-	sigBytes, err := hex.DecodeString(inputTx.signature)
-	if err != nil {
-		return fmt.Errorf("failed to decode signature: %w", err)
-	}
-	r := new(big.Int).SetBytes(sigBytes[:len(sigBytes)/2])
-	s := new(big.Int).SetBytes(sigBytes[len(sigBytes)/2:])
-
-	// Verify the signature
-	if !ecdsa.Verify(publicKey, txIDHash[:], r, s) {
-		return fmt.Errorf("invalid signature for transaction input")
+		return nil, errors.New("address does not match the public key")
 	}
-
-	return nil
+	return publicKey, nil
 }
 
 func ValidateTransaction(tx *Transaction, unspent UnspentOutputRepository, blockHeight int) error {
-	if err := validateCoinbase(tx, blockHeight); err != nil {
+	if err := validateCoinbase(tx, blockHeight, 0); err != nil {
 		return err
 	}
 
@@ -96,7 +111,7 @@ func ValidateTransaction(tx *Transaction, unspent UnspentOutputRepository, block
 	}
 
 	for i := 1; i < len(tx.inputs); i++ {
-		if err := validateTransactionIn(tx.inputs[i], tx, unspent); err != nil {
+		if err := validateTransactionIn(tx, i, unspent); err != nil {
 			return err
 		}
 	}
@@ -115,3 +130,159 @@ func validateDuplicates(inputs []*Input) error {
 	}
 	return nil
 }
+
+// ValidateBlockTransactions checks the transactions of a block at the given height. The first transaction has to be
+// the coinbase for that height, claiming the subsidy and the fees of the other transactions. Every other one has to be
+// signed by the owners of the outputs it spends, which have to be unspent at the parent block or created earlier
+// in the same block, and its inputs have to cover its outputs. The transactions have to fit in the limits of the network.
+func ValidateBlockTransactions(transactions []Transaction, unspentAtParent UnspentOutputRepository, blockHeight int) error {
+	if len(transactions) == 0 {
+		return errors.New("block must start with a coinbase transaction")
+	}
+	if err := validateBlockLimits(transactions, chaincfg.Active()); err != nil {
+		return err
+	}
+
+	available := NewUnspentView(unspentAtParent)
+	available.apply(transactions[0])
+	fees, max := 0, MaxAmount()
+	for _, tx := range transactions[1:] {
+		fee, err := validateSpending(&tx, available)
+		if err != nil {
+			return fmt.Errorf("transaction %x: %w", tx.ID(), err)
+		}
+		if fees, err = addAmount(fees, fee, max); err != nil {
+			return fmt.Errorf("fees of the block: %w", err)
+		}
+		available.apply(tx)
+	}
+
+	return validateCoinbase(&transactions[0], blockHeight, fees)
+}
+
+func validateBlockLimits(transactions []Transaction, params chaincfg.ChainParams) error {
+	if len(transactions) > params.MaxBlockTransactions {
+		return fmt.Errorf("block holds %d transactions, at most %d allowed", len(transactions), params.MaxBlockTransactions)
+	}
+	size := 0
+	for _, tx := range transactions {
+		size += tx.Size()
+	}
+	if size > params.MaxBlockSize {
+		return fmt.Errorf("block transactions take up %d bytes, at most %d allowed", size, params.MaxBlockSize)
+	}
+	return nil
+}
+
+// Fee is what the inputs of the transaction are worth above its outputs. Neither the inputs nor the outputs
+// can add up to more than MaxAmount.
+func Fee(tx Transaction, unspent UnspentOutputRepository) (int, error) {
+	in, max := 0, MaxAmount()
+	for _, input := range tx.inputs {
+		referenced, err := unspent.GetByOutputIDAndIndex(input.outputID, input.outputIndex)
+		if err != nil {
+			return 0, fmt.Errorf("error getting referenced output: %w", err)
+		}
+		if referenced == (UnspentOutput{}) {
+			return 0, fmt.Errorf("output %x:%d is not unspent", input.outputID, input.outputIndex)
+		}
+		if in, err = addAmount(in, referenced.amount, max); err != nil {
+			return 0, fmt.Errorf("inputs: %w", err)
+		}
+	}
+
+	out, err := sumOutputs(tx.outputs, max)
+	if err != nil {
+		return 0, err
+	}
+	return in - out, nil
+}
+
+func sumOutputs(outputs []*Output, max int) (int, error) {
+	out := 0
+	for _, output := range outputs {
+		var err error
+		if out, err = addAmount(out, output.amount, max); err != nil {
+			return 0, fmt.Errorf("outputs: %w", err)
+		}
+	}
+	return out, nil
+}
+
+func validateSpending(tx *Transaction, available UnspentOutputRepository) (int, error) {
+	if len(tx.inputs) == 0 || tx.IsCoinbase() {
+		return 0, errors.New("only the first transaction in a block can mint coins")
+	}
+	if err := validateDuplicates(tx.inputs); err != nil {
+		return 0, err
+	}
+
+	for i := range tx.inputs {
+		if err := validateTransactionIn(tx, i, available); err != nil {
+			return 0, err
+		}
+	}
+	for _, output := range tx.outputs {
+		if output.amount <= 0 {
+			return 0, fmt.Errorf("output amount must be positive, got %d", output.amount)
+		}
+	}
+
+	fee, err := Fee(*tx, available)
+	if err != nil {
+		return 0, err
+	}
+	if fee < 0 {
+		return 0, fmt.Errorf("inputs do not cover outputs, short by %d", -fee)
+	}
+	return fee, nil
+}
+
+// unspentSet is an UnspentOutputRepository over the given outputs, which lists them in the order given.
+type unspentSet struct {
+	outputs map[outpoint]UnspentOutput
+	order   []outpoint
+}
+
+func newUnspentSet(outputs []UnspentOutput) *unspentSet {
+	set := &unspentSet{outputs: make(map[outpoint]UnspentOutput, len(outputs)), order: make([]outpoint, 0, len(outputs))}
+	for _, output := range outputs {
+		key := outpoint{id: output.outputID, index: output.outputIndex}
+		if _, ok := set.outputs[key]; !ok {
+			set.order = append(set.order, key)
+		}
+		set.outputs[key] = output
+	}
+	return set
+}
+
+func (s *unspentSet) GetAll() ([]UnspentOutput, error) {
+	all := make([]UnspentOutput, 0, len(s.order))
+	for _, key := range s.order {
+		all = append(all, s.outputs[key])
+	}
+	return all, nil
+}
+
+func (s *unspentSet) GetByAddress(address string) ([]UnspentOutput, error) {
+	owned := make([]UnspentOutput, 0)
+	for _, key := range s.order {
+		if output := s.outputs[key]; output.address == address {
+			owned = append(owned, output)
+		}
+	}
+	return owned, nil
+}
+
+func (s *unspentSet) GetByOutputIDAndIndex(outputID ID, outputIndex int) (UnspentOutput, error) {
+	output, ok := s.outputs[outpoint{id: outputID, index: outputIndex}]
+	if !ok {
+		return UnspentOutput{}, fmt.Errorf("output %x:%d is not unspent", outputID, outputIndex)
+	}
+	return output, nil
+}
+
+func (s *unspentSet) Set(unspentOutputs []UnspentOutput) error {
+	*s = *newUnspentSet(unspentOutputs)
+	return nil
+}
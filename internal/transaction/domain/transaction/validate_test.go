@@ -8,9 +8,12 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"testing"
 
+	"github.com/patrykferenc/eecoin/internal/common/chaincfg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateCoinbaseInvalid(t *testing.T) {
@@ -19,12 +22,12 @@ func TestValidateCoinbaseInvalid(t *testing.T) {
 	in := NewInput(ID("some-output-id"), 0, "sign")
 	tx := &Transaction{
 		inputs:  []*Input{in},
-		outputs: []*Output{NewOutput(COINBASE_AMOUNT, "some-address")},
+		outputs: []*Output{NewOutput(chaincfg.Active().InitialSubsidy, "some-address")},
 	}
 	blockHeight := 1
 
 	// when validating the coinbase transaction
-	err := validateCoinbase(tx, blockHeight)
+	err := validateCoinbase(tx, blockHeight, 0)
 
 	// then no error should be returned
 	assert.Error(err)
@@ -37,12 +40,29 @@ func TestValidateCoinbaseValid(t *testing.T) {
 	assert.NoError(err)
 
 	// when validating the coinbase transaction
-	err = validateCoinbase(tx, 1)
+	err = validateCoinbase(tx, 1, 0)
 
 	// then no error should be returned
 	assert.NoError(err)
 }
 
+func TestValidateCoinbase_shouldNotSpendAnOutput(t *testing.T) {
+	// given an unsigned first transaction spending the output of someone else at the index of the block height
+	victim, err := NewFrom([]*Input{}, []*Output{NewOutput(1, "a"), NewOutput(5000, "victim")})
+	assert.NoError(t, err)
+	tx, err := NewFrom(
+		[]*Input{NewInput(victim.ID(), 1, "")},
+		[]*Output{NewOutput(ActiveSubsidy().At(1), "thief")},
+	)
+	assert.NoError(t, err)
+
+	// when
+	err = ValidateBlockTransactions([]Transaction{*tx}, newUnspentSet(UnspentOutputsFrom([]Transaction{*victim})), 1)
+
+	// then
+	assert.ErrorContains(t, err, "coinbase transaction must not spend an output")
+}
+
 func TestValidateDuplicateInputs(t *testing.T) {
 	assert := assert.New(t)
 
@@ -99,69 +119,70 @@ func TestValidateDuplicateInputs(t *testing.T) {
 }
 
 func TestValidateTransactionIn(t *testing.T) {
-	assert := assert.New(t)
-
-	// --- Setup for both cases ---
-
-	// Generate key pair for the sender
+	// given a transaction spending an output of the sender
 	privateSender, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	assert.NoError(err)
+	require.NoError(t, err)
 	senderAddrRaw, err := x509.MarshalPKIXPublicKey(privateSender.Public())
-	assert.NoError(err)
+	require.NoError(t, err)
 	senderAddr := hex.EncodeToString(senderAddrRaw)
-
-	// Create a valid unspent output
 	unspentOutput := NewUnspentOutput("some-tx-id", 0, 100, senderAddr)
-
-	// Mock UnspentOutputRepository
 	mockUnspentRepo := &mockUnspentOutputRepository{
 		UnspentOutputs: map[string][]UnspentOutput{
-			string(senderAddr): {unspentOutput},
+			senderAddr: {unspentOutput},
 		},
 	}
-
-	// Create a transaction referencing the unspent output
-	tx := &Transaction{
-		id: "some-tx-id",
+	tx, err := NewFrom([]*Input{NewInput("some-tx-id", 0, "")}, []*Output{NewOutput(90, "receiver")})
+	require.NoError(t, err)
+
+	// and signatures of it, as r and s of 32 bytes each
+	signed := func(digest []byte) string {
+		r, s, err := ecdsa.Sign(rand.Reader, privateSender, digest)
+		require.NoError(t, err)
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return hex.EncodeToString(signature)
 	}
-
-	// Valid signature
-	txIDHash := sha256.Sum256([]byte(tx.ID().String()))
-	r, s, err := ecdsa.Sign(rand.Reader, privateSender, txIDHash[:])
-	assert.NoError(err)
-
-	signature := append(r.Bytes(), s.Bytes()...)
-
-	validInput := &Input{
-		outputID:    "some-tx-id",
-		outputIndex: 0,
-		signature:   hex.EncodeToString(signature),
+	chainID := chaincfg.Active().ChainID
+	digest := sigHash(tx, 0, unspentOutput, chainID)
+	tampered, err := hex.DecodeString(signed(digest))
+	require.NoError(t, err)
+	tampered[len(tampered)-1] ^= 0xFF
+	asn1Signature, err := ecdsa.SignASN1(rand.Reader, privateSender, digest)
+	require.NoError(t, err)
+	txIDHash := sha256.Sum256([]byte(tx.ID()))
+
+	tt := []struct {
+		description string
+		signature   string
+		valid       bool
+	}{
+		{description: "signs the sighash", signature: signed(digest), valid: true},
+		{description: "tampered", signature: hex.EncodeToString(tampered)},
+		{description: "signs the ID", signature: signed(txIDHash[:])},
+		{description: "signs for another network", signature: signed(sigHash(tx, 0, unspentOutput, chainID+1))},
+		{description: "signs for another input", signature: signed(sigHash(tx, 1, unspentOutput, chainID))},
+		{description: "signs for another amount", signature: signed(sigHash(tx, 0, NewUnspentOutput("some-tx-id", 0, 1000, senderAddr), chainID))},
+		{description: "encoded in ASN.1", signature: hex.EncodeToString(asn1Signature)},
+		{description: "not hex", signature: "signature"},
 	}
 
-	// --- Valid Case ---
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given
+			tx.inputs[0].signature = tc.signature
 
-	t.Run("ValidTransactionInput", func(t *testing.T) {
-		err := validateTransactionIn(validInput, tx, mockUnspentRepo)
-		assert.NoError(err, "expected no error for valid input")
-	})
-
-	// --- Invalid Case (tampered signature) ---
+			// when
+			err := validateTransactionIn(tx, 0, mockUnspentRepo)
 
-	t.Run("InvalidTransactionInput", func(t *testing.T) {
-		// Create a tampered signature (by modifying one byte)
-		tamperedSignature := append(r.Bytes(), s.Bytes()...)
-		tamperedSignature[len(tamperedSignature)-1] ^= 0xFF // Flip the last byte
-
-		invalidInput := &Input{
-			outputID:    "some-tx-id",
-			outputIndex: 0,
-			signature:   hex.EncodeToString(tamperedSignature),
-		}
-
-		err := validateTransactionIn(invalidInput, tx, mockUnspentRepo)
-		assert.Error(err, "expected error for invalid input")
-		assert.Contains(err.Error(), "invalid signature", "error should indicate invalid signature")
-	})
+			// then
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
 }
 
 type mockUnspentOutputRepository struct {
@@ -198,3 +219,149 @@ func (r *mockUnspentOutputRepository) GetByOutputIDAndIndex(outputID ID, outputI
 	}
 	return UnspentOutput{}, fmt.Errorf("output with ID %s and index %d not found", outputID, outputIndex)
 }
+
+func TestValidateBlockTransactions(t *testing.T) {
+	// given an owner of an unspent output
+	owner, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ownerAddrRaw, err := x509.MarshalPKIXPublicKey(owner.Public())
+	assert.NoError(t, err)
+	ownerAddr := hex.EncodeToString(ownerAddrRaw)
+	funding, err := NewFrom([]*Input{}, []*Output{NewOutput(100, ownerAddr)})
+	assert.NoError(t, err)
+	unspentAtParent := UnspentOutputsFrom([]Transaction{*funding})
+
+	// and the transactions a block could contain
+	coinbase, err := NewCoinbase("miner", 2)
+	assert.NoError(t, err)
+	otherCoinbase, err := NewCoinbase("miner", 3)
+	assert.NoError(t, err)
+	spend, err := New("receiver", ownerAddr, 60, owner, newUnspentSet(unspentAtParent))
+	assert.NoError(t, err)
+	respend, err := New("someone else", ownerAddr, 100, owner, newUnspentSet(unspentAtParent))
+	assert.NoError(t, err)
+	paying, err := NewWithFee("receiver", ownerAddr, 60, 5, owner, newUnspentSet(unspentAtParent))
+	assert.NoError(t, err)
+	claiming, err := NewCoinbaseWithFees("miner", 2, 5)
+	assert.NoError(t, err)
+	split, err := NewCoinbaseWithOutputs(2, []*Output{NewOutput(60, "miner"), NewOutput(ActiveSubsidy().At(2)-60, "other miner")})
+	assert.NoError(t, err)
+	overpaid, err := NewCoinbaseWithOutputs(2, []*Output{NewOutput(60, "miner"), NewOutput(ActiveSubsidy().At(2), "other miner")})
+	assert.NoError(t, err)
+	unbalanced, err := NewCoinbaseWithOutputs(2, []*Output{NewOutput(ActiveSubsidy().At(2)+10, "miner"), NewOutput(-10, "other miner")})
+	assert.NoError(t, err)
+	unsigned, err := NewFrom([]*Input{NewInput(funding.ID(), 0, "")}, []*Output{NewOutput(100, "thief")})
+	assert.NoError(t, err)
+	inflating := signedSpend(t, owner, unspentAtParent[0], NewOutput(150, "receiver"))
+	negative := signedSpend(t, owner, unspentAtParent[0], NewOutput(200, "receiver"), NewOutput(-100, ownerAddr))
+	wrapping := signedSpend(t, owner, unspentAtParent[0], NewOutput(math.MaxInt64, "receiver"), NewOutput(math.MaxInt64, "receiver"))
+	wrappingCoinbase, err := NewCoinbaseWithOutputs(2, []*Output{NewOutput(math.MaxInt64, "miner"), NewOutput(math.MaxInt64, "other miner")})
+	assert.NoError(t, err)
+
+	tt := []struct {
+		description  string
+		transactions []Transaction
+		valid        bool
+	}{
+		{description: "coinbase only", transactions: []Transaction{*coinbase}, valid: true},
+		{description: "coinbase and a signed spend", transactions: []Transaction{*coinbase, *spend}, valid: true},
+		{description: "coinbase claiming the fee", transactions: []Transaction{*claiming, *paying}, valid: true},
+		{description: "coinbase not claiming the fee", transactions: []Transaction{*coinbase, *paying}},
+		{description: "coinbase claiming a fee nobody paid", transactions: []Transaction{*claiming, *spend}},
+		{description: "no transactions", transactions: []Transaction{}},
+		{description: "no coinbase", transactions: []Transaction{*spend}},
+		{description: "coinbase split between miners", transactions: []Transaction{*split}, valid: true},
+		{description: "coinbase split above the reward", transactions: []Transaction{*overpaid}},
+		{description: "coinbase split with a negative output", transactions: []Transaction{*unbalanced}},
+		{description: "coinbase for another height", transactions: []Transaction{*otherCoinbase}},
+		{description: "second coinbase", transactions: []Transaction{*coinbase, *coinbase}},
+		{description: "double spend", transactions: []Transaction{*coinbase, *spend, *respend}},
+		{description: "missing signature", transactions: []Transaction{*coinbase, *unsigned}},
+		{description: "outputs above inputs", transactions: []Transaction{*coinbase, *inflating}},
+		{description: "negative output", transactions: []Transaction{*coinbase, *negative}},
+		{description: "outputs wrapping around", transactions: []Transaction{*coinbase, *wrapping}},
+		{description: "coinbase outputs wrapping around", transactions: []Transaction{*wrappingCoinbase}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			err := ValidateBlockTransactions(tc.transactions, newUnspentSet(unspentAtParent), 2)
+
+			// then
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateBlockLimits(t *testing.T) {
+	// given
+	coinbase, err := NewCoinbase("miner", 2)
+	assert.NoError(t, err)
+	other, err := NewCoinbase("other miner", 2)
+	assert.NoError(t, err)
+	transactions := []Transaction{*coinbase, *other}
+	size := coinbase.Size() + other.Size()
+
+	tt := []struct {
+		description string
+		params      chaincfg.ChainParams
+		valid       bool
+	}{
+		{description: "within the limits", params: chaincfg.ChainParams{MaxBlockSize: size, MaxBlockTransactions: 2}, valid: true},
+		{description: "too many transactions", params: chaincfg.ChainParams{MaxBlockSize: size, MaxBlockTransactions: 1}},
+		{description: "too big", params: chaincfg.ChainParams{MaxBlockSize: size - 1, MaxBlockTransactions: 2}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			err := validateBlockLimits(transactions, tc.params)
+
+			// then
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func signedSpend(t *testing.T, owner *ecdsa.PrivateKey, referenced UnspentOutput, outputs ...*Output) *Transaction {
+	t.Helper()
+	tx, err := NewFrom([]*Input{NewInput(referenced.outputID, referenced.outputIndex, "")}, outputs)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.inputs[0].sign(owner, tx, 0, referenced))
+	return tx
+}
+
+func TestFee(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	funding, err := NewFrom([]*Input{}, []*Output{NewOutput(100, "owner")})
+	assert.NoError(err)
+	unspent := newUnspentSet(UnspentOutputsFrom([]Transaction{*funding}))
+	tx, err := NewFrom([]*Input{NewInput(funding.ID(), 0, "")}, []*Output{NewOutput(60, "receiver"), NewOutput(30, "owner")})
+	assert.NoError(err)
+	unknown, err := NewFrom([]*Input{NewInput("unknown", 0, "")}, []*Output{NewOutput(60, "receiver")})
+	assert.NoError(err)
+
+	// when
+	fee, err := Fee(*tx, unspent)
+
+	// then
+	assert.NoError(err)
+	assert.Equal(10, fee)
+
+	// when
+	_, err = Fee(*unknown, unspent)
+
+	// then
+	assert.Error(err)
+}
@@ -8,12 +8,19 @@ import (
 
 type UnspentOutputRepository struct {
 	outputs map[string][]transaction.UnspentOutput
+	owners  map[outpoint]string
 	rw      sync.RWMutex
 }
 
+type outpoint struct {
+	id    transaction.ID
+	index int
+}
+
 func NewUnspentOutputRepository() *UnspentOutputRepository {
 	return &UnspentOutputRepository{
 		outputs: make(map[string][]transaction.UnspentOutput),
+		owners:  make(map[outpoint]string),
 	}
 }
 
@@ -40,24 +47,67 @@ func (r *UnspentOutputRepository) GetByOutputIDAndIndex(outputID transaction.ID,
 	r.rw.RLock()
 	defer r.rw.RUnlock()
 
-	for _, outputs := range r.outputs {
-		for _, output := range outputs {
-			if output.OutputID() == outputID && output.OutputIndex() == outputIndex {
-				return output, nil
-			}
+	owner, ok := r.owners[outpoint{id: outputID, index: outputIndex}]
+	if !ok {
+		return transaction.UnspentOutput{}, nil
+	}
+	for _, output := range r.outputs[owner] {
+		if output.OutputID() == outputID && output.OutputIndex() == outputIndex {
+			return output, nil
 		}
 	}
 
 	return transaction.UnspentOutput{}, nil
 }
 
+// Set replaces all the unspent outputs with the given ones
 func (r *UnspentOutputRepository) Set(outputs []transaction.UnspentOutput) error {
 	r.rw.Lock()
 	defer r.rw.Unlock()
 
+	r.outputs = make(map[string][]transaction.UnspentOutput)
+	r.owners = make(map[outpoint]string)
+	r.add(outputs)
+
+	return nil
+}
+
+func (r *UnspentOutputRepository) Add(outputs ...transaction.UnspentOutput) error {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	r.add(outputs)
+	return nil
+}
+
+func (r *UnspentOutputRepository) add(outputs []transaction.UnspentOutput) {
 	for _, output := range outputs {
+		key := outpoint{id: output.OutputID(), index: output.OutputIndex()}
+		if _, ok := r.owners[key]; ok {
+			continue
+		}
+		r.owners[key] = output.Address()
 		r.outputs[output.Address()] = append(r.outputs[output.Address()], output)
 	}
+}
+
+func (r *UnspentOutputRepository) Remove(outputs ...transaction.UnspentOutput) error {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	for _, output := range outputs {
+		addressOutputs := r.outputs[output.Address()]
+		for i, o := range addressOutputs {
+			if o.OutputID() == output.OutputID() && o.OutputIndex() == output.OutputIndex() {
+				r.outputs[output.Address()] = append(addressOutputs[:i:i], addressOutputs[i+1:]...)
+				delete(r.owners, outpoint{id: o.OutputID(), index: o.OutputIndex()})
+				break
+			}
+		}
+		if len(r.outputs[output.Address()]) == 0 {
+			delete(r.outputs, output.Address())
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,236 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+const (
+	recordAdded   uint8 = 1
+	recordRemoved uint8 = 2
+
+	// recordHeaderSize is the length and the checksum in front of every record.
+	recordHeaderSize = 8
+	// maxRecordSize guards against reading a length which got corrupted.
+	maxRecordSize = 1 << 24
+)
+
+var UnknownRecord = errors.New("unknown journal record")
+
+// Entry is a transaction of the pool, along with the time it was added.
+type Entry struct {
+	Transaction transaction.Transaction
+	Added       time.Time
+}
+
+// Journal appends the changes to the pool to a file, every record synced to the disk before the change is made.
+// Each record carries its length and checksum, so that a record cut short by a crash is told apart and dropped.
+type Journal struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// OpenJournal replays the journal at the given path and returns the transactions still in the pool, in the order
+// they were added. The ones added longer than maxAge ago are expired. The journal is then compacted to hold only
+// the returned transactions and opened for appending.
+func OpenJournal(path string, maxAge time.Duration) (*Journal, []Entry, error) {
+	entries, err := replay(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	kept := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if maxAge > 0 && entry.Added.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if expired := len(entries) - len(kept); expired > 0 {
+		slog.Info("expired transactions from the pool journal", "expired", expired)
+	}
+
+	if err := compact(path, kept); err != nil {
+		return nil, nil, fmt.Errorf("error compacting pool journal: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Journal{file: file}, kept, nil
+}
+
+func (j *Journal) Added(tx transaction.Transaction, at time.Time) error {
+	return j.append(addedRecord(tx, at))
+}
+
+func (j *Journal) Removed(ids ...transaction.ID) error {
+	e := canonical.NewEncoder()
+	e.Uint8(recordRemoved)
+	e.Length(len(ids))
+	for _, id := range ids {
+		e.String(string(id))
+	}
+	return j.append(e.Encoded())
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+func (j *Journal) append(record []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(frame(record)); err != nil {
+		return fmt.Errorf("error writing pool journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+func addedRecord(tx transaction.Transaction, at time.Time) []byte {
+	e := canonical.NewEncoder()
+	e.Uint8(recordAdded)
+	e.Int64(at.UnixMilli())
+	tx.MarshalCanonical(e)
+	return e.Encoded()
+}
+
+func frame(record []byte) []byte {
+	framed := make([]byte, recordHeaderSize, recordHeaderSize+len(record))
+	binary.BigEndian.PutUint32(framed, uint32(len(record)))
+	binary.BigEndian.PutUint32(framed[4:], crc32.ChecksumIEEE(record))
+	return append(framed, record...)
+}
+
+// replay reads the records up to the end of the journal, or up to the first record which is not complete or does not
+// match its checksum, which is what a crash in the middle of a write leaves behind.
+func replay(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var order []transaction.ID
+	entries := make(map[transaction.ID]Entry)
+	r := bufio.NewReader(file)
+	for {
+		record, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.Warn("pool journal ends with a broken record, dropping it", "path", path, "error", err)
+			break
+		}
+
+		d, err := canonical.NewDecoder(record)
+		if err != nil {
+			return nil, err
+		}
+		switch kind := d.Uint8(); kind {
+		case recordAdded:
+			added := time.UnixMilli(d.Int64())
+			var tx transaction.Transaction
+			tx.UnmarshalCanonical(d)
+			if err := d.Finish(); err != nil {
+				return nil, fmt.Errorf("error reading pool journal: %w", err)
+			}
+			if _, ok := entries[tx.ID()]; !ok {
+				order = append(order, tx.ID())
+				entries[tx.ID()] = Entry{Transaction: tx, Added: added}
+			}
+		case recordRemoved:
+			ids := make([]transaction.ID, d.Length())
+			for i := range ids {
+				ids[i] = transaction.ID(d.String())
+			}
+			if err := d.Finish(); err != nil {
+				return nil, fmt.Errorf("error reading pool journal: %w", err)
+			}
+			for _, id := range ids {
+				delete(entries, id)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %d", UnknownRecord, kind)
+		}
+	}
+
+	kept := make([]Entry, 0, len(entries))
+	for _, id := range order {
+		if entry, ok := entries[id]; ok {
+			kept = append(kept, entry)
+			// a transaction removed and added again is kept once, where it was first added
+			delete(entries, id)
+		}
+	}
+	return kept, nil
+}
+
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("record header truncated: %w", err)
+		}
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes is too long", size)
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, fmt.Errorf("record truncated: %w", err)
+	}
+	if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("record checksum does not match")
+	}
+	return record, nil
+}
+
+// compact writes the entries to a new journal, which replaces the old one once it is synced to the disk.
+func compact(path string, entries []Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, entry := range entries {
+		if _, err := w.Write(frame(addedRecord(entry.Transaction, entry.Added))); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,177 @@
+package persistence_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_shouldReplayThePool(t *testing.T) {
+	assert := assert.New(t)
+
+	// given a journaled pool
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	journal, pending, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(pending)
+	pool := inmem.NewJournaledPoolRepository(journal)
+
+	// when transactions are added and one of them is removed
+	first, second, third := spending(t, "a"), spending(t, "b"), spending(t, "c")
+	require.NoError(t, pool.Add(first))
+	require.NoError(t, pool.Add(second))
+	require.NoError(t, pool.Add(third))
+	require.NoError(t, pool.Remove(second.ID()))
+	require.NoError(t, journal.Close())
+
+	// then the rest is replayed in order
+	journal, pending, err = persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	defer journal.Close()
+	assert.Equal([]transaction.ID{first.ID(), third.ID()}, idsOf(pending))
+	assert.Equal(*first, pending[0].Transaction)
+	assert.WithinDuration(time.Now(), pending[0].Added, time.Minute)
+}
+
+func TestJournal_shouldExpireOldTransactions(t *testing.T) {
+	// given a transaction added long ago
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	journal, _, err := persistence.OpenJournal(path, 0)
+	require.NoError(t, err)
+	old, recent := spending(t, "a"), spending(t, "b")
+	require.NoError(t, journal.Added(*old, time.Now().Add(-2*time.Hour)))
+	require.NoError(t, journal.Added(*recent, time.Now()))
+	require.NoError(t, journal.Close())
+
+	// when
+	journal, pending, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	// then
+	assert.Equal(t, []transaction.ID{recent.ID()}, idsOf(pending))
+
+	// and the expired transaction is gone from the compacted journal
+	journal, pending, err = persistence.OpenJournal(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+	assert.Equal(t, []transaction.ID{recent.ID()}, idsOf(pending))
+}
+
+func TestJournal_shouldDropRecordCutShort(t *testing.T) {
+	// given a journal whose last write was cut short
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	journal, _, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	kept, lost := spending(t, "a"), spending(t, "b")
+	require.NoError(t, journal.Added(*kept, time.Now()))
+	require.NoError(t, journal.Added(*lost, time.Now()))
+	require.NoError(t, journal.Close())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	// when
+	journal, pending, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	defer journal.Close()
+
+	// then
+	assert.Equal(t, []transaction.ID{kept.ID()}, idsOf(pending))
+}
+
+func TestPoolRepository_shouldDiscardTransactionsNotRestored(t *testing.T) {
+	// given the transactions replayed from the journal
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	journal, _, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	restored, discarded := spending(t, "a"), spending(t, "b")
+	require.NoError(t, journal.Added(*restored, time.Now()))
+	require.NoError(t, journal.Added(*discarded, time.Now()))
+	require.NoError(t, journal.Close())
+	journal, pending, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	pool := inmem.NewJournaledPoolRepository(journal)
+
+	// when only one of them is restored
+	require.NoError(t, pool.Restore(pending[0].Transaction, pending[0].Added))
+	require.NoError(t, pool.Discard(discarded.ID()))
+	require.NoError(t, journal.Close())
+
+	// then the other one is not replayed again
+	journal, pending, err = persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	defer journal.Close()
+	assert.Equal(t, []transaction.ID{restored.ID()}, idsOf(pending))
+}
+
+func TestPoolRepository_shouldRefuseTwoSpendersOfAnOutput(t *testing.T) {
+	// given
+	pool := inmem.NewPoolRepository()
+	first := spending(t, "a")
+	second, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("a", 0, "other signature")},
+		[]*transaction.Output{transaction.NewOutput(5, "someone else")},
+	)
+	require.NoError(t, err)
+	require.NoError(t, pool.Add(first))
+
+	// when
+	err = pool.Add(second)
+
+	// then
+	assert.ErrorIs(t, err, transaction.ConflictsWithPool)
+	assert.False(t, pool.Exists(second.ID()))
+	spender, ok := pool.SpentBy("a", 0)
+	assert.True(t, ok)
+	assert.Equal(t, first.ID(), spender)
+	assert.ErrorIs(t, pool.Restore(*second, time.Now()), transaction.ConflictsWithPool)
+}
+
+func TestPoolRepository_shouldExpireTransactions(t *testing.T) {
+	assert := assert.New(t)
+
+	// given
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	journal, _, err := persistence.OpenJournal(path, time.Hour)
+	require.NoError(t, err)
+	pool := inmem.NewJournaledPoolRepository(journal)
+	old, recent := spending(t, "a"), spending(t, "b")
+	require.NoError(t, pool.Restore(*old, time.Now().Add(-2*time.Hour)))
+	require.NoError(t, pool.Add(recent))
+
+	// when
+	expired, err := pool.Expire(time.Now().Add(-time.Hour))
+
+	// then
+	assert.NoError(err)
+	assert.Equal([]transaction.ID{old.ID()}, expired)
+	assert.False(pool.Exists(old.ID()))
+	assert.True(pool.Exists(recent.ID()))
+}
+
+func spending(t *testing.T, outputID transaction.ID) *transaction.Transaction {
+	t.Helper()
+	tx, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(outputID, 0, "signature")},
+		[]*transaction.Output{transaction.NewOutput(10, "receiver")},
+	)
+	require.NoError(t, err)
+	return tx
+}
+
+func idsOf(entries []persistence.Entry) []transaction.ID {
+	ids := make([]transaction.ID, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.Transaction.ID()
+	}
+	return ids
+}
@@ -1,30 +1,115 @@
 package inmem
 
 import (
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
+// Journal records the changes to the pool, so that the pool can be restored after a restart.
+type Journal interface {
+	Added(tx transaction.Transaction, at time.Time) error
+	Removed(ids ...transaction.ID) error
+}
+
 type PoolRepository struct {
 	transactions map[transaction.ID]*transaction.Transaction
+	added        map[transaction.ID]time.Time
+	spenders     map[outpoint]transaction.ID
+	size         int
+	journal      Journal // nil when the pool is not journaled
 	rw           sync.RWMutex
 }
 
 func NewPoolRepository() *PoolRepository {
 	return &PoolRepository{
 		transactions: make(map[transaction.ID]*transaction.Transaction),
+		added:        make(map[transaction.ID]time.Time),
+		spenders:     make(map[outpoint]transaction.ID),
 	}
 }
 
+// NewJournaledPoolRepository returns a pool which writes every change to the journal.
+func NewJournaledPoolRepository(journal Journal) *PoolRepository {
+	p := NewPoolRepository()
+	p.journal = journal
+	return p
+}
+
 func (p *PoolRepository) Add(tx *transaction.Transaction) error {
 	p.rw.Lock()
 	defer p.rw.Unlock()
 
+	return p.add(tx, time.Now())
+}
+
+func (p *PoolRepository) add(tx *transaction.Transaction, at time.Time) error {
+	if _, ok := p.transactions[tx.ID()]; ok {
+		return nil
+	}
+	if err := p.conflicts(tx); err != nil {
+		return err
+	}
+	if p.journal != nil {
+		if err := p.journal.Added(*tx, at); err != nil {
+			return err
+		}
+	}
+	p.put(tx, at)
+	return nil
+}
+
+// conflicts tells whether the transaction spends an output another transaction of the pool spends, as only one of
+// them can ever be mined. The pool checks this before adding a transaction, replacing the other one if its policy
+// allows, so this only guards the repository from being left with two spenders of an output.
+func (p *PoolRepository) conflicts(tx *transaction.Transaction) error {
+	for _, in := range tx.Inputs() {
+		if spender, ok := p.spenders[outpoint{id: in.OutputID(), index: in.OutputIndex()}]; ok {
+			return fmt.Errorf("%w: output %x:%d is spent by %x", transaction.ConflictsWithPool, in.OutputID(), in.OutputIndex(), spender)
+		}
+	}
+	return nil
+}
+
+func (p *PoolRepository) put(tx *transaction.Transaction, at time.Time) {
 	p.transactions[tx.ID()] = tx
+	p.added[tx.ID()] = at
+	p.size += tx.Size()
+	for _, in := range tx.Inputs() {
+		p.spenders[outpoint{id: in.OutputID(), index: in.OutputIndex()}] = tx.ID()
+	}
+}
+
+// Restore puts back a transaction replayed from the journal, along with the time it was first added.
+// It is not written to the journal again.
+func (p *PoolRepository) Restore(tx transaction.Transaction, added time.Time) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if _, ok := p.transactions[tx.ID()]; ok {
+		return nil
+	}
+	if err := p.conflicts(&tx); err != nil {
+		return err
+	}
+	p.put(&tx, added)
 	return nil
 }
 
+// Discard writes to the journal that the transactions replayed from it are not restored, so that they are not
+// replayed again.
+func (p *PoolRepository) Discard(ids ...transaction.ID) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if p.journal == nil || len(ids) == 0 {
+		return nil
+	}
+	return p.journal.Removed(ids...)
+}
+
 func (p *PoolRepository) Exists(tx transaction.ID) bool {
 	p.rw.RLock()
 	defer p.rw.RUnlock()
@@ -44,16 +129,68 @@ func (p *PoolRepository) Get(tx transaction.ID) (*transaction.Transaction, error
 	return t, nil
 }
 
+func (p *PoolRepository) SpentBy(outputID transaction.ID, outputIndex int) (transaction.ID, bool) {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	id, ok := p.spenders[outpoint{id: outputID, index: outputIndex}]
+	return id, ok
+}
+
+func (p *PoolRepository) Size() int {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	return p.size
+}
+
 func (p *PoolRepository) Remove(txs ...transaction.ID) error {
 	p.rw.Lock()
 	defer p.rw.Unlock()
 
+	return p.remove(txs)
+}
+
+func (p *PoolRepository) remove(txs []transaction.ID) error {
+	present := make([]transaction.ID, 0, len(txs))
 	for _, tx := range txs {
-		delete(p.transactions, tx)
+		if _, ok := p.transactions[tx]; ok {
+			present = append(present, tx)
+		}
+	}
+	if p.journal != nil && len(present) > 0 {
+		if err := p.journal.Removed(present...); err != nil {
+			return err
+		}
+	}
+	for _, id := range present {
+		for _, in := range p.transactions[id].Inputs() {
+			key := outpoint{id: in.OutputID(), index: in.OutputIndex()}
+			if p.spenders[key] == id {
+				delete(p.spenders, key)
+			}
+		}
+		p.size -= p.transactions[id].Size()
+		delete(p.transactions, id)
+		delete(p.added, id)
 	}
 	return nil
 }
 
+// Expire removes the transactions added before the cutoff and returns their IDs.
+func (p *PoolRepository) Expire(cutoff time.Time) ([]transaction.ID, error) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	var expired []transaction.ID
+	for id, added := range p.added {
+		if added.Before(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	return expired, p.remove(expired)
+}
+
 func (p *PoolRepository) GetAll() []transaction.Transaction {
 	p.rw.RLock()
 	defer p.rw.RUnlock()
@@ -64,13 +201,3 @@ func (p *PoolRepository) GetAll() []transaction.Transaction {
 	}
 	return txs
 }
-
-func (p *PoolRepository) Set(txs []transaction.Transaction) error {
-	p.rw.Lock()
-	defer p.rw.Unlock()
-
-	for _, tx := range txs {
-		p.transactions[tx.ID()] = &tx
-	}
-	return nil
-}
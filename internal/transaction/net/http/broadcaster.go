@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	"github.com/patrykferenc/eecoin/internal/peer/query"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
@@ -32,15 +33,14 @@ func (b *Broadcaster) Broadcast(tx transaction.Transaction) error {
 	}
 	errors := make(chan error, len(peers))
 
-	dto := AsDTO(tx)
-	body, err := json.Marshal(dto)
+	body, err := tx.MarshalBinary()
 	if err != nil {
-		return fmt.Errorf("could not marshal block: %w", err)
+		return fmt.Errorf("could not marshal transaction: %w", err)
 	}
 
 	for _, peer := range peers {
 		go func(peer string) {
-			errors <- SendTransaction(body, peer)
+			errors <- send(body, canonical.MediaType, peer)
 		}(peer)
 	}
 
@@ -60,16 +60,38 @@ func (b *Broadcaster) Broadcast(tx transaction.Transaction) error {
 	return nil
 }
 
+// SendTransaction posts the transaction as JSON, the way the wallet does.
 func SendTransaction(body []byte, peer string) error {
+	return send(body, "application/json", peer)
+}
+
+// SendCanonicalTransaction posts the transaction in the canonical encoding, the way the nodes relay it.
+func SendCanonicalTransaction(tx transaction.Transaction, peer string) error {
+	return send(canonical.Marshal(tx), canonical.MediaType, peer)
+}
+
+func send(body []byte, contentType string, peer string) error {
 	req, err := http.NewRequest(http.MethodPost, peer+transactionURL, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
+	req.Header.Set("Content-Type", contentType)
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusConflict {
+		var rejection rejectionDTO
+		if err := json.NewDecoder(res.Body).Decode(&rejection); err == nil && rejection.Reason != "" {
+			if rejection.Reason == transaction.AlreadyInPool.Error() {
+				// the peer got it from someone else already
+				return nil
+			}
+			return fmt.Errorf("transaction rejected (%d): %s", res.StatusCode, rejection.Message)
+		}
+	}
 	if res.StatusCode != http.StatusOK {
 		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
 	}
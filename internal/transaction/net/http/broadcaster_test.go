@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/command"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction/transactiontest"
 	"github.com/stretchr/testify/assert"
 )
@@ -106,3 +108,38 @@ func TestBroadcastNoPeers(t *testing.T) {
 	// then
 	assert.Error(err)
 }
+
+type recordingAddTransaction struct {
+	added chan command.AddTransaction
+}
+
+func (r recordingAddTransaction) Handle(cmd command.AddTransaction) error {
+	r.added <- cmd
+	return nil
+}
+
+func TestBroadcastSendsTheCanonicalTransaction(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// given a peer adding what it receives to its pool
+	handler := recordingAddTransaction{added: make(chan command.AddTransaction, 1)}
+	mockServer := httptest.NewServer(postTransaction(handler))
+	defer mockServer.Close()
+	broadcaster := NewBroadcaster(mock.NewPeers([]string{mockServer.URL}))
+	// and
+	tx, err := transactiontest.NewTransaction()
+	assert.NoError(err)
+
+	// when
+	err = broadcaster.Broadcast(*tx)
+
+	// then the transaction arrives unchanged, including its ID
+	assert.NoError(err)
+	added := <-handler.added
+	assert.Equal(tx.ID().String(), added.ProvidedID)
+	received, err := transaction.NewFrom(added.Inputs, added.Outputs)
+	assert.NoError(err)
+	assert.Equal(tx.ID(), received.ID())
+	assert.Equal(tx.Inputs(), received.Inputs())
+}
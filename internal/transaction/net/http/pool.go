@@ -1,10 +1,14 @@
 package http
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 	"github.com/patrykferenc/eecoin/internal/transaction/query"
 )
 
@@ -31,6 +35,33 @@ func getTransactionPool(q query.GetTransactionPool) http.HandlerFunc {
 	}
 }
 
+// getPooledTransaction answers with the transaction of the pool in the canonical encoding, the ID is given in hex.
+func getPooledTransaction(q query.GetTransactionPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := hex.DecodeString(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "transaction ID must be hex", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := q.Get(transaction.ID(id))
+		if err != nil {
+			slog.Error("failed to get pooled transaction", "id", chi.URLParam(r, "id"), "error", err)
+			http.Error(w, "failed to get pooled transaction", http.StatusInternalServerError)
+			return
+		}
+		if tx == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", canonical.MediaType)
+		if _, err := w.Write(canonical.Marshal(*tx)); err != nil {
+			slog.Warn("failed to write pooled transaction", "error", err)
+		}
+	}
+}
+
 type transactionPoolDTO struct {
 	Transactions []transactionDTO `json:"transactions"`
 	Count        int              `json:"count"`
@@ -1,7 +1,10 @@
 package http
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
@@ -47,3 +50,25 @@ func (t *TransactionPoolClient) Get(peers []string) ([]transaction.Transaction,
 
 	return nil, errs[0] // TODO: abomination
 }
+
+// GetTransaction downloads the transaction from the pool of the peer.
+func (t *TransactionPoolClient) GetTransaction(peer string, id transaction.ID) (transaction.Transaction, error) {
+	var tx transaction.Transaction
+	resp, err := t.client.Get(peer + transactionURL + "/" + hex.EncodeToString([]byte(id)))
+	if err != nil {
+		return tx, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return tx, fmt.Errorf("transaction %x is not in the pool of %s", id, peer)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tx, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tx, err
+	}
+	return tx, tx.UnmarshalBinary(data)
+}
@@ -15,4 +15,5 @@ func Route(
 	r.Post(transactionURL, postTransaction(addTransaction))
 	r.Get(unspentURL, getUnspent(unspent))
 	r.Get(poolURL, getTransactionPool(pool))
+	r.Get(transactionURL+"/{id}", getPooledTransaction(pool))
 }
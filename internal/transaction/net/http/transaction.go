@@ -2,37 +2,59 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
 	"github.com/patrykferenc/eecoin/internal/transaction/command"
 	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 )
 
 func postTransaction(addTransactionHandler command.AddTransactionHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var dto transactionDTO
+		var cmd command.AddTransaction
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == canonical.MediaType {
+			tx, err := readTransaction(r.Body)
+			if err != nil {
+				slog.Warn("failed to decode canonical transaction", "error", err)
+				http.Error(w, "invalid canonical body", http.StatusBadRequest)
+				return
+			}
+			cmd = asCommand(tx)
+		} else {
+			var dto transactionDTO
+			if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+				slog.Warn("failed to decode transaction JSON", "error", err)
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
 
-		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-			slog.Warn("failed to decode transaction JSON", "error", err)
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
-			return
-		}
-
-		inputs := make([]*transaction.Input, len(dto.Inputs))
-		for i, in := range dto.Inputs {
-			inputs[i] = in.asInput()
-		}
-		outputs := make([]*transaction.Output, len(dto.Outputs))
-		for i, out := range dto.Outputs {
-			outputs[i] = out.asOutput()
+			inputs := make([]*transaction.Input, len(dto.Inputs))
+			for i, in := range dto.Inputs {
+				inputs[i] = in.asInput()
+			}
+			outputs := make([]*transaction.Output, len(dto.Outputs))
+			for i, out := range dto.Outputs {
+				outputs[i] = out.asOutput()
+			}
+			cmd = command.AddTransaction{
+				ProvidedID:  dto.ID,
+				Inputs:      inputs,
+				Outputs:     outputs,
+				Replaceable: dto.Replaceable,
+			}
 		}
 
-		if err := addTransactionHandler.Handle(command.AddTransaction{
-			ProvidedID: dto.ID,
-			Inputs:     inputs,
-			Outputs:    outputs,
-		}); err != nil {
+		if err := addTransactionHandler.Handle(cmd); err != nil {
+			var rejection *transaction.Rejection
+			if errors.As(err, &rejection) {
+				slog.Info("transaction rejected by the pool", "error", err)
+				writeRejection(w, rejection)
+				return
+			}
 			slog.Warn("failed to add transaction to pool", "error", err)
 			http.Error(w, "failed to add transaction to pool", http.StatusInternalServerError)
 			return
@@ -42,3 +64,45 @@ func postTransaction(addTransactionHandler command.AddTransactionHandler) http.H
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// writeRejection tells the sender why the pool did not accept the transaction. A transaction which is in the pool
+// already, conflicts with one in it or pays too little for the full pool or for a replacement is a conflict with
+// the state of the pool, any other one is a bad request.
+func writeRejection(w http.ResponseWriter, rejection *transaction.Rejection) {
+	status := http.StatusBadRequest
+	switch rejection.Reason {
+	case transaction.AlreadyInPool, transaction.ConflictsWithPool, transaction.PoolFull, transaction.ReplacementTooLow:
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(rejectionDTO{Reason: rejection.Reason.Error(), Message: rejection.Error()}); err != nil {
+		slog.Warn("failed to write rejection", "error", err)
+	}
+}
+
+func readTransaction(body io.Reader) (transaction.Transaction, error) {
+	var tx transaction.Transaction
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return tx, err
+	}
+	return tx, tx.UnmarshalBinary(data)
+}
+
+func asCommand(tx transaction.Transaction) command.AddTransaction {
+	inputs := make([]*transaction.Input, len(tx.Inputs()))
+	for i, in := range tx.Inputs() {
+		inputs[i] = transaction.NewInput(in.OutputID(), in.OutputIndex(), in.Signature())
+	}
+	outputs := make([]*transaction.Output, len(tx.Outputs()))
+	for i, out := range tx.Outputs() {
+		outputs[i] = transaction.NewOutput(out.Amount(), out.Address())
+	}
+	return command.AddTransaction{
+		ProvidedID:  tx.ID().String(),
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Replaceable: tx.Replaceable(),
+	}
+}
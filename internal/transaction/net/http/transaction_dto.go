@@ -24,9 +24,10 @@ func (o outputDTO) asOutput() *transaction.Output {
 }
 
 type transactionDTO struct {
-	ID      string      `json:"id"`
-	Inputs  []inputDTO  `json:"inputs"`
-	Outputs []outputDTO `json:"outputs"`
+	ID          string      `json:"id"`
+	Inputs      []inputDTO  `json:"inputs"`
+	Outputs     []outputDTO `json:"outputs"`
+	Replaceable bool        `json:"replaceable,omitempty"`
 }
 
 func AsDTO(tx transaction.Transaction) transactionDTO {
@@ -48,9 +49,10 @@ func AsDTO(tx transaction.Transaction) transactionDTO {
 	}
 
 	return transactionDTO{
-		ID:      tx.ID().String(),
-		Inputs:  inputs,
-		Outputs: outputs,
+		ID:          tx.ID().String(),
+		Inputs:      inputs,
+		Outputs:     outputs,
+		Replaceable: tx.Replaceable(),
 	}
 }
 
@@ -65,5 +67,13 @@ func asModel(dto transactionDTO) (*transaction.Transaction, error) {
 		outputs[i] = out.asOutput()
 	}
 
+	if dto.Replaceable {
+		return transaction.NewReplaceableFrom(inputs, outputs)
+	}
 	return transaction.NewFrom(inputs, outputs)
 }
+
+type rejectionDTO struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/patrykferenc/eecoin/internal/common/canonical"
+	"github.com/patrykferenc/eecoin/internal/common/mock"
+	"github.com/patrykferenc/eecoin/internal/transaction/command"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction/transactiontest"
+	"github.com/patrykferenc/eecoin/internal/transaction/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostTransaction_shouldTellWhyTheTransactionIsRejected(t *testing.T) {
+	t.Parallel()
+
+	// given a transaction already in the pool of the peer
+	pooled, err := transactiontest.NewTransaction()
+	require.NoError(t, err)
+	repository := mock.NewPoolRepository()
+	repository.Transactions[pooled.ID()] = pooled
+	// and a transaction spending an output the peer does not know
+	unknown, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput("unknown", 0, "signature")},
+		[]*transaction.Output{transaction.NewOutput(10, "receiver")},
+	)
+	require.NoError(t, err)
+	// and a transaction spending what the pooled one spends
+	spent := pooled.Inputs()[0]
+	conflicting, err := transaction.NewFrom(
+		[]*transaction.Input{transaction.NewInput(spent.OutputID(), spent.OutputIndex(), "signature")},
+		[]*transaction.Output{transaction.NewOutput(10, "someone else")},
+	)
+	require.NoError(t, err)
+
+	pool := transaction.NewPool(repository, inmem.NewUnspentOutputRepository(), transaction.PoolConfig{})
+	mockServer := httptest.NewServer(postTransaction(command.NewAddTransactionHandler(&mock.Publisher{}, pool)))
+	defer mockServer.Close()
+
+	tt := []struct {
+		description string
+		tx          *transaction.Transaction
+		expected    string
+	}{
+		{description: "already in the pool is not an error", tx: pooled},
+		{description: "conflicts with the pool", tx: conflicting, expected: "transaction rejected (409): output already spent by a transaction in the pool"},
+		{description: "spends an unknown output", tx: unknown, expected: "transaction rejected (400): spent output is not unspent"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// when
+			body, err := tc.tx.MarshalBinary()
+			require.NoError(t, err)
+			err = send(body, canonical.MediaType, mockServer.URL)
+
+			// then
+			if tc.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestPostTransaction_shouldReplaceByFee(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		description string
+		encode      func(tx transaction.Transaction) ([]byte, string)
+	}{
+		{
+			description: "canonical",
+			encode: func(tx transaction.Transaction) ([]byte, string) {
+				return canonical.Marshal(tx), canonical.MediaType
+			},
+		},
+		{
+			description: "JSON",
+			encode: func(tx transaction.Transaction) ([]byte, string) {
+				body, err := json.Marshal(AsDTO(tx))
+				require.NoError(t, err)
+				return body, "application/json"
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.description, func(t *testing.T) {
+			// given a peer replacing by fee
+			owner, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(t, err)
+			raw, err := x509.MarshalPKIXPublicKey(owner.Public())
+			require.NoError(t, err)
+			ownerAddr := hex.EncodeToString(raw)
+			unspent := inmem.NewUnspentOutputRepository()
+			require.NoError(t, unspent.Add(transaction.NewUnspentOutput("funding", 0, 100, ownerAddr)))
+			repository := inmem.NewPoolRepository()
+			pool := transaction.NewPool(repository, unspent, transaction.PoolConfig{ReplaceByFee: true})
+			mockServer := httptest.NewServer(postTransaction(command.NewAddTransactionHandler(&mock.Publisher{}, pool)))
+			defer mockServer.Close()
+			// and a transaction created replaceable, along with its replacement paying more
+			tx, err := transaction.NewReplaceableWithFee("receiver", ownerAddr, 60, 5, owner, unspent)
+			require.NoError(t, err)
+			bumped, err := transaction.BumpFee(*tx, 10, ownerAddr, owner, unspent)
+			require.NoError(t, err)
+
+			// when both are posted
+			body, contentType := tc.encode(*tx)
+			require.NoError(t, send(body, contentType, mockServer.URL))
+			body, contentType = tc.encode(*bumped)
+			require.NoError(t, send(body, contentType, mockServer.URL))
+
+			// then the replacement took the place of the transaction
+			assert.True(t, repository.Exists(bumped.ID()))
+			assert.False(t, repository.Exists(tx.ID()))
+		})
+	}
+}
+
+func TestTransactionPoolClient_shouldGetThePooledTransaction(t *testing.T) {
+	t.Parallel()
+
+	// given a transaction in the pool of the peer
+	pooled, err := transactiontest.NewTransaction()
+	require.NoError(t, err)
+	pool := inmem.NewPoolRepository()
+	require.NoError(t, pool.Add(pooled))
+	r := chi.NewRouter()
+	r.Get(transactionURL+"/{id}", getPooledTransaction(pool))
+	mockServer := httptest.NewServer(r)
+	defer mockServer.Close()
+	client := &TransactionPoolClient{}
+
+	// when
+	got, err := client.GetTransaction(mockServer.URL, pooled.ID())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, *pooled, got)
+
+	// and when the transaction is not in the pool
+	_, err = client.GetTransaction(mockServer.URL, "missing")
+
+	// then
+	assert.ErrorContains(t, err, "is not in the pool")
+}
@@ -4,4 +4,6 @@ import "github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
 
 type GetTransactionPool interface {
 	GetAll() []transaction.Transaction
+	// Get the transaction in the pool, nil when it is not there.
+	Get(transaction.ID) (*transaction.Transaction, error)
 }
@@ -0,0 +1,207 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+)
+
+const (
+	// DefaultConfirmations is how many blocks have to be on top of an output, counting its own, before it is spendable.
+	DefaultConfirmations = 6
+
+	// headersBatch is how many headers are asked for at once, the most a node sends back.
+	headersBatch = 2000
+)
+
+var (
+	NoValidHeaders   = errors.New("no node sent valid headers")
+	HeadersNotSynced = errors.New("headers not synced")
+)
+
+// Node serves the headers and the proofs a light client needs.
+type Node interface {
+	GetHeaders(peer string, from int, count int) ([]blockchain.Header, error)
+	GetUnspentProofs(peer string, address string) ([]query.UnspentProof, error)
+}
+
+// Balance of an address, as far as the headers back it.
+type Balance struct {
+	// Confirmed is in the outputs with enough confirmations
+	Confirmed int
+	// Pending is in the outputs proven to be in a block which does not have enough confirmations yet
+	Pending int
+}
+
+// LightClient follows the chain by its headers only. It downloads the headers from several nodes, verifies them by
+// the rules the full nodes verify the blocks with and keeps the valid chain with the most work. The outputs the nodes
+// report for an address are only counted when they come with a proof leading to one of those headers.
+// A node can still hide that an output was spent, which only the full nodes can tell.
+type LightClient struct {
+	node          Node
+	peers         []string
+	confirmations int
+
+	headers []blockchain.Header
+}
+
+func NewLightClient(node Node, peers []string, confirmations int) *LightClient {
+	return &LightClient{
+		node:          node,
+		peers:         peers,
+		confirmations: max(confirmations, 1),
+	}
+}
+
+// SyncHeaders downloads the headers from every peer. The peers sending headers which are not valid are skipped.
+func (c *LightClient) SyncHeaders() error {
+	genesis := blockchain.GenerateGenesisBlock().Header
+
+	var best []blockchain.Header
+	for _, peer := range c.peers {
+		headers, err := c.downloadHeaders(peer)
+		if err != nil {
+			slog.Warn("could not download headers", "peer", peer, "error", err)
+			continue
+		}
+		if len(headers) == 0 || headers[0] != genesis {
+			slog.Warn("peer follows another genesis", "peer", peer)
+			continue
+		}
+		if err := blockchain.ValidateHeaders(headers[:1], headers[1:]); err != nil {
+			slog.Warn("peer sent headers which are not valid", "peer", peer, "error", err)
+			continue
+		}
+		if best == nil || blockchain.GetCumulativeDifficulty(headers).Cmp(blockchain.GetCumulativeDifficulty(best)) > 0 {
+			best = headers
+		}
+	}
+
+	if best == nil {
+		return NoValidHeaders
+	}
+	c.headers = best
+	slog.Info("headers synced", "height", len(best)-1, "tip", best[len(best)-1].ContentHash)
+	return nil
+}
+
+func (c *LightClient) downloadHeaders(peer string) ([]blockchain.Header, error) {
+	var headers []blockchain.Header
+	for {
+		batch, err := c.node.GetHeaders(peer, len(headers), headersBatch)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, batch...)
+		if len(batch) < headersBatch {
+			return headers, nil
+		}
+	}
+}
+
+// Balance of the address, made of the outputs any of the peers proves to be in the synced headers.
+func (c *LightClient) Balance(address string) (Balance, error) {
+	outputs, err := c.proven(address)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	var balance Balance
+	for _, output := range outputs {
+		if output.confirmations >= c.confirmations {
+			balance.Confirmed += output.Amount()
+		} else {
+			balance.Pending += output.Amount()
+		}
+	}
+	return balance, nil
+}
+
+// Unspent outputs of the address any of the peers proves to be in the synced headers, however many confirmations
+// they have.
+func (c *LightClient) Unspent(address string) ([]transaction.UnspentOutput, error) {
+	outputs, err := c.proven(address)
+	if err != nil {
+		return nil, err
+	}
+
+	unspent := make([]transaction.UnspentOutput, len(outputs))
+	for i, output := range outputs {
+		unspent[i] = output.UnspentOutput
+	}
+	return unspent, nil
+}
+
+type provenOutput struct {
+	transaction.UnspentOutput
+	confirmations int
+}
+
+func (c *LightClient) proven(address string) ([]provenOutput, error) {
+	if c.headers == nil {
+		return nil, HeadersNotSynced
+	}
+
+	type outpoint struct {
+		id    transaction.ID
+		index int
+	}
+	counted := make(map[outpoint]struct{})
+
+	var outputs []provenOutput
+	for _, peer := range c.peers {
+		proofs, err := c.node.GetUnspentProofs(peer, address)
+		if err != nil {
+			slog.Warn("could not get unspent proofs", "peer", peer, "error", err)
+			continue
+		}
+
+		for _, proof := range proofs {
+			key := outpoint{id: proof.Transaction.ID(), index: proof.OutputIndex}
+			if _, ok := counted[key]; ok {
+				continue
+			}
+			amount, confirmations, err := c.verify(address, proof)
+			if err != nil {
+				slog.Warn("peer sent a proof which is not valid", "peer", peer, "error", err)
+				continue
+			}
+
+			counted[key] = struct{}{}
+			outputs = append(outputs, provenOutput{
+				UnspentOutput: transaction.NewUnspentOutput(key.id, key.index, amount, address),
+				confirmations: confirmations,
+			})
+		}
+	}
+	return outputs, nil
+}
+
+// verify that the output belongs to the address and that its transaction is in a block of the synced headers.
+// It returns the amount of the output and the number of its confirmations.
+func (c *LightClient) verify(address string, proof query.UnspentProof) (int, int, error) {
+	if proof.Transaction.ID() != proof.Proof.TransactionID {
+		return 0, 0, errors.New("proof is for another transaction")
+	}
+	outputs := proof.Transaction.Outputs()
+	if proof.OutputIndex < 0 || proof.OutputIndex >= len(outputs) {
+		return 0, 0, fmt.Errorf("transaction has no output %d", proof.OutputIndex)
+	}
+	output := outputs[proof.OutputIndex]
+	if output.Address() != address {
+		return 0, 0, errors.New("output belongs to another address")
+	}
+
+	index := proof.Header.Index
+	if index < 0 || index >= len(c.headers) || c.headers[index] != proof.Header {
+		return 0, 0, fmt.Errorf("block %d is not in the synced headers", index)
+	}
+	if !blockchain.VerifyMerkleProof(c.headers[index], proof.Proof) {
+		return 0, 0, errors.New("transaction is not in the block")
+	}
+	return output.Amount(), len(c.headers) - index, nil
+}
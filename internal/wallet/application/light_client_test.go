@@ -0,0 +1,175 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/patrykferenc/eecoin/internal/blockchain/domain/blockchain"
+	"github.com/patrykferenc/eecoin/internal/blockchain/query"
+	"github.com/patrykferenc/eecoin/internal/transaction/domain/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNode struct {
+	headers map[string][]blockchain.Header
+	proofs  map[string][]query.UnspentProof
+}
+
+func (f fakeNode) GetHeaders(peer string, from int, count int) ([]blockchain.Header, error) {
+	headers, ok := f.headers[peer]
+	if !ok {
+		return nil, errors.New("peer unreachable")
+	}
+	if from >= len(headers) {
+		return []blockchain.Header{}, nil
+	}
+	return headers[from:min(from+count, len(headers))], nil
+}
+
+func (f fakeNode) GetUnspentProofs(peer string, _ string) ([]query.UnspentProof, error) {
+	proofs, ok := f.proofs[peer]
+	if !ok {
+		return nil, errors.New("peer unreachable")
+	}
+	return proofs, nil
+}
+
+// mine a block on top of the chain, paying the amount to the address
+func mine(t *testing.T, chain *blockchain.BlockChain, address string, amount int) blockchain.Block {
+	t.Helper()
+	tx, err := transaction.NewFrom(nil, []*transaction.Output{transaction.NewOutput(amount, address)})
+	require.NoError(t, err)
+	transactions := []transaction.Transaction{*tx}
+
+	difficulty, err := blockchain.GetDifficulty(*chain)
+	require.NoError(t, err)
+	challenge, err := blockchain.NewChallenge(difficulty, 1)
+	require.NoError(t, err)
+	timestamp := chain.GetLast().TimestampMilis + 1000
+	solved, err := challenge.Solve(context.Background(), 1, chain.GetLast(), transactions, timestamp, new(atomic.Uint64))
+	require.NoError(t, err)
+	require.True(t, solved)
+
+	block, err := chain.NewBlock(timestamp, transactions, challenge)
+	require.NoError(t, err)
+	require.NoError(t, chain.AddBlock(block))
+	return block
+}
+
+func proofOf(t *testing.T, block blockchain.Block) query.UnspentProof {
+	t.Helper()
+	tx := block.Transactions[0]
+	proof, err := blockchain.NewMerkleProof(block.Transactions, tx.ID())
+	require.NoError(t, err)
+	return query.UnspentProof{
+		OutputIndex:      0,
+		Transaction:      tx,
+		TransactionProof: query.TransactionProof{Header: block.Header, Proof: proof},
+	}
+}
+
+func newChain(t *testing.T) *blockchain.BlockChain {
+	t.Helper()
+	chain, err := blockchain.ImportBlockchain([]blockchain.Block{blockchain.GenerateGenesisBlock()})
+	require.NoError(t, err)
+	return chain
+}
+
+func TestLightClient_Balance(t *testing.T) {
+	assertThat := assert.New(t)
+
+	// given a chain in which the address got paid in the first and in the last block
+	chain := newChain(t)
+	first := mine(t, chain, "address", 10)
+	mine(t, chain, "other", 1)
+	mine(t, chain, "other", 1)
+	last := mine(t, chain, "address", 5)
+	headers := chain.GetHeaders(0, len(chain.Blocks))
+
+	// and given proofs which are not valid
+	forkChain := newChain(t)
+	forked := mine(t, forkChain, "address", 1000)
+	otherAddress := proofOf(t, chain.Blocks[2])
+	otherTransaction := proofOf(t, first)
+	otherTransaction.Transaction = last.Transactions[0]
+	missingOutput := proofOf(t, first)
+	missingOutput.OutputIndex = 1
+
+	node := fakeNode{
+		headers: map[string][]blockchain.Header{"honest": headers, "also honest": headers},
+		proofs: map[string][]query.UnspentProof{
+			"honest":      {proofOf(t, first), proofOf(t, last)},
+			"also honest": {proofOf(t, first), proofOf(t, forked), otherAddress, otherTransaction, missingOutput},
+		},
+	}
+	client := NewLightClient(node, []string{"honest", "also honest"}, 3)
+	require.NoError(t, client.SyncHeaders())
+
+	// when
+	balance, err := client.Balance("address")
+
+	// then the first output is confirmed, the last is pending and the rest is not counted
+	require.NoError(t, err)
+	assertThat.Equal(Balance{Confirmed: 10, Pending: 5}, balance)
+
+	// and the same outputs are the unspent ones, pending or not
+	unspent, err := client.Unspent("address")
+	require.NoError(t, err)
+	assertThat.ElementsMatch([]transaction.UnspentOutput{
+		transaction.NewUnspentOutput(first.Transactions[0].ID(), 0, 10, "address"),
+		transaction.NewUnspentOutput(last.Transactions[0].ID(), 0, 5, "address"),
+	}, unspent)
+}
+
+func TestLightClient_SyncHeaders_shouldKeepTheValidChainWithMostWork(t *testing.T) {
+	assertThat := assert.New(t)
+
+	// given
+	chain := newChain(t)
+	mine(t, chain, "address", 10)
+	shorter := chain.GetHeaders(0, len(chain.Blocks))
+	mine(t, chain, "address", 10)
+	longer := chain.GetHeaders(0, len(chain.Blocks))
+
+	// and given a peer which forged the last header, claiming more work
+	forged := append([]blockchain.Header{}, longer...)
+	forged = append(forged, longer[len(longer)-1])
+	forged[len(forged)-1].Index++
+	forged[len(forged)-1].PrevHash = longer[len(longer)-1].ContentHash
+	forged[len(forged)-1].TimestampMilis += 1000
+
+	node := fakeNode{headers: map[string][]blockchain.Header{
+		"shorter": shorter,
+		"longer":  longer,
+		"forged":  forged,
+	}}
+	client := NewLightClient(node, []string{"shorter", "forged", "unreachable", "longer"}, 1)
+
+	// when
+	err := client.SyncHeaders()
+
+	// then
+	require.NoError(t, err)
+	assertThat.Equal(longer, client.headers)
+}
+
+func TestLightClient_shouldErrorWithoutValidHeaders(t *testing.T) {
+	assertThat := assert.New(t)
+
+	// given
+	otherGenesis := newChain(t).GetHeaders(0, 1)
+	otherGenesis[0].TimestampMilis++
+	node := fakeNode{headers: map[string][]blockchain.Header{"other network": otherGenesis}}
+	client := NewLightClient(node, []string{"other network", "unreachable"}, 1)
+
+	// when
+	_, balanceErr := client.Balance("address")
+	syncErr := client.SyncHeaders()
+
+	// then
+	assertThat.ErrorIs(balanceErr, HeadersNotSynced)
+	assertThat.ErrorIs(syncErr, NoValidHeaders)
+}
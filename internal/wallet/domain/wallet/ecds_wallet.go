@@ -25,7 +25,7 @@ type Ecdsa struct {
 
 func (w *Ecdsa) SetMainIdentity(key *EcdsaKey) error {
 	if key.private != nil {
-		w.MainId = &EcdsaKey{private: key.private, Public: key.private.PublicKey, algType: ECDSA}
+		w.MainId = &EcdsaKey{private: key.private, Public: key.private.Public(), algType: ECDSA}
 		return nil
 	}
 	return ErrPrivateKeyNotFound
@@ -130,5 +130,6 @@ func TestSavingMainIdentityEcdsa(t *testing.T) {
 	assertThat.NotNil(wallet.Keys)
 	assertThat.NotNil(wallet.MainId)
 	assertThat.Equal(mainId.private, wallet.MainId.private)
+	assertThat.Equal(mainId.Public, wallet.MainId.Public)
 	assertThat.Len(wallet.Keys, 3)
 }